@@ -1,7 +1,9 @@
 package common
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"math"
 	"net/mail"
 	"net/url"
@@ -12,6 +14,7 @@ import (
 
 	"github.com/bufbuild/connect-go"
 	"github.com/docker/distribution/reference"
+	"github.com/pmezard/go-difflib/difflib"
 	"github.com/rigdev/rig-go-api/api/v1/database"
 	"github.com/rigdev/rig-go-api/api/v1/group"
 	"github.com/rigdev/rig-go-api/api/v1/storage"
@@ -265,6 +268,43 @@ func ProtoToPrettyJson(m protoreflect.ProtoMessage) string {
 	return protojson.Format(m)
 }
 
+// ProtoDiff returns a unified diff of the JSON representations of the two
+// messages. The diff is empty if the messages are equal.
+func ProtoDiff(from, to protoreflect.ProtoMessage, fromName, toName string) (string, error) {
+	a, err := indentedJSON(from)
+	if err != nil {
+		return "", err
+	}
+
+	b, err := indentedJSON(to)
+	if err != nil {
+		return "", err
+	}
+
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(a),
+		B:        difflib.SplitLines(b),
+		FromFile: fromName,
+		ToFile:   toName,
+		Context:  3,
+	})
+}
+
+// indentedJSON formats the message with encoding/json, as the output of
+// protojson is not stable.
+func indentedJSON(m protoreflect.ProtoMessage) (string, error) {
+	bs, err := protojson.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bs, "", "  "); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func FormatIntToSI(n uint64, decimals int) string {
 	scale := uint64(math.Pow10(decimals))
 	n = (n * scale) / scale
@@ -3,6 +3,7 @@ package common
 import (
 	"testing"
 
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -147,3 +148,21 @@ func Test_ToStringWithSignificantDigits(t *testing.T) {
 		})
 	}
 }
+
+func Test_ProtoDiff(t *testing.T) {
+	diff, err := ProtoDiff(&capsule.RolloutConfig{Replicas: 1}, &capsule.RolloutConfig{Replicas: 1}, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "", diff)
+
+	diff, err = ProtoDiff(&capsule.RolloutConfig{Replicas: 1, BuildId: "foo"}, &capsule.RolloutConfig{Replicas: 2, BuildId: "foo"}, "a", "b")
+	assert.NoError(t, err)
+	assert.Equal(t, `--- a
++++ b
+@@ -1,4 +1,4 @@
+ {
+-  "replicas": 1,
++  "replicas": 2,
+   "buildId": "foo"
+ }
+`, diff)
+}
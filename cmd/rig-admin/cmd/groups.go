@@ -39,6 +39,14 @@ func init() {
 	populate.PersistentFlags().StringVar(&groupName, "name", "", "name for the group")
 	groups.AddCommand(populate)
 
+	create := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a group, e.g. named as a permission to grant it to the members",
+		Args:  cobra.ExactArgs(1),
+		RunE:  register(GroupsCreate),
+	}
+	groups.AddCommand(create)
+
 	update := &cobra.Command{
 		Use:  "update <group-id>",
 		Args: cobra.MinimumNArgs(1),
@@ -124,6 +132,20 @@ func GroupsPopulate(ctx context.Context, cmd *cobra.Command, gp *group_service.S
 	return nil
 }
 
+func GroupsCreate(ctx context.Context, cmd *cobra.Command, args []string, gp *group_service.Service, logger *zap.Logger) error {
+	ctx = auth.WithClaims(ctx, service_auth.ProjectClaims{
+		UseProjectID: auth.RigProjectID,
+	})
+
+	g, err := gp.Create(ctx, []*group.Update{{Field: &group.Update_Name{Name: args[0]}}})
+	if err != nil {
+		return err
+	}
+
+	logger.Info("created group", zap.String("group_id", g.GetGroupId()), zap.String("name", g.GetName()))
+	return nil
+}
+
 func GroupsList(ctx context.Context, cmd *cobra.Command, gp *group_service.Service, logger *zap.Logger) error {
 	ctx = auth.WithClaims(ctx, service_auth.ProjectClaims{
 		UseProjectID: auth.RigProjectID,
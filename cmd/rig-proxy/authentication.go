@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/service/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/proxy"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.uber.org/zap"
+)
+
+type subjectKey struct{}
+
+// subjectFromContext returns the subject of the verified token of the request,
+// if any.
+func subjectFromContext(ctx context.Context) (string, bool) {
+	s, ok := ctx.Value(subjectKey{}).(string)
+	return s, ok
+}
+
+type authenticationMiddleware struct {
+	a         *capsule.Authentication
+	projectID uuid.UUID
+	publicKey interface{}
+	issuer    string
+	next      http.Handler
+	logger    *zap.Logger
+}
+
+func (m *authenticationMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.a.GetEnabled() {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	removeRigHeaders(r.Header)
+
+	a := m.resolveAuth(r)
+	switch v := a.GetMethod().(type) {
+	case *capsule.Auth_AllowAuthorized_:
+		h, err := m.handleJWTAuth(r.Header, v.AllowAuthorized.GetClaims())
+		if err != nil {
+			w.WriteHeader(errors.ToHTTP(err))
+			w.Write([]byte(errors.MessageOf(err)))
+			w.Write([]byte("\n"))
+			return
+		}
+		r.Header = h
+		setAccessLogUserID(r.Context(), h.Get("X-Rig-User-ID"))
+		r = r.WithContext(context.WithValue(r.Context(), subjectKey{}, h.Get("X-Rig-User-ID")))
+	case *capsule.Auth_AllowAny_:
+		break
+	default:
+		m.logger.Warn("invalid auth method for path", zap.Any("method", v), zap.String("path", r.URL.Path))
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("invalid auth configuration"))
+		w.Write([]byte("\n"))
+		return
+	}
+
+	m.next.ServeHTTP(w, r)
+}
+
+// resolveAuth returns the auth of the request. gRPC requests are matched by the
+// gRPC rules, if any, and other requests by the HTTP rules. Requests not
+// matched by any rule use the default of the interface.
+func (m *authenticationMiddleware) resolveAuth(r *http.Request) *capsule.Auth {
+	if g := m.a.GetGrpc(); g != nil && proxy.IsGRPC(r) {
+		if a := m.resolveGRPCAuth(g, r.URL.Path); a != nil {
+			return a
+		}
+		return m.a.GetDefault()
+	}
+
+	return m.resolveHTTPAuth("/", r.URL.Path)
+}
+
+// grpcMethod returns the service and the method of a gRPC request path under
+// the prefix, or empty strings if the path isn't a gRPC method under the prefix.
+func grpcMethod(prefix, p string) (string, string) {
+	rp := path.Clean(p)
+	if prefix := path.Join("/", prefix); prefix != "/" {
+		if !strings.HasPrefix(rp, prefix+"/") {
+			return "", ""
+		}
+		rp = strings.TrimPrefix(rp, prefix)
+	}
+
+	return splitGRPCPath(rp)
+}
+
+func (m *authenticationMiddleware) resolveHTTPAuth(prefix, p string) *capsule.Auth {
+	for _, h := range m.a.GetHttp() {
+		if matchHTTPPath(prefix, h.GetPath(), h.GetExact(), p) {
+			return h.GetAuth()
+		}
+	}
+
+	return m.a.GetDefault()
+}
+
+// matchHTTPPath returns true if the request path p matches the path of a rule,
+// under the prefix. Unless exact, the paths below the path of the rule match
+// as well.
+func matchHTTPPath(prefix, rulePath string, exact bool, p string) bool {
+	rp := path.Clean(p)
+	pp := path.Join(prefix, rulePath)
+	return rp == pp || (!exact && strings.HasPrefix(rp, pp))
+}
+
+// resolveGRPCAuth returns the auth of the method of a gRPC request, falling back
+// to the auth of its service and then to the auth of gRPC. Nil is returned if
+// none of them is set, or if the path isn't a gRPC method under the prefix.
+func (m *authenticationMiddleware) resolveGRPCAuth(g *capsule.GRPC, p string) *capsule.Auth {
+	service, method := grpcMethod(g.GetPathPrefix(), p)
+	if service == "" || method == "" {
+		m.logger.Debug("request is not a gRPC method under the path prefix", zap.String("prefix", g.GetPathPrefix()), zap.String("path", p))
+		return nil
+	}
+
+	s := g.GetServices()[service]
+	if a := s.GetMethods()[method].GetAuth(); a != nil {
+		return a
+	}
+	if a := s.GetAuth(); a != nil {
+		return a
+	}
+
+	return g.GetAuth()
+}
+
+// handleJWTAuth verifies the bearer token of the request and checks that it has
+// the required claims. The user ID and the required claims are forwarded as
+// X-Rig-* headers.
+func (m *authenticationMiddleware) handleJWTAuth(h http.Header, claims map[string]string) (http.Header, error) {
+	ah := h.Get("Authorization")
+	if !strings.HasPrefix(ah, "Bearer ") {
+		m.logger.Debug("request is missing authorization bearer")
+		return h, errors.UnauthenticatedErrorf("missing authorization bearer")
+	}
+
+	jwtToken := strings.TrimPrefix(ah, "Bearer ")
+
+	c := &auth.RigClaims{}
+	token, err := jwt.ParseWithClaims(
+		jwtToken,
+		c,
+		func(token *jwt.Token) (interface{}, error) {
+			return m.publicKey, nil
+		},
+	)
+	if err != nil {
+		return h, errors.UnauthenticatedErrorf("%v", err)
+	}
+
+	if !token.Valid {
+		return h, errors.InvalidArgumentErrorf("invalid JWT token format")
+	}
+
+	if c.GetIssuer() != m.issuer {
+		return h, errors.InvalidArgumentErrorf("invalid JWT issuer")
+	}
+
+	if c.GetProjectID() != m.projectID {
+		m.logger.Info("invalid project ID", zap.Stringer("claims_project_id", c.GetProjectID()), zap.Stringer("service_project_id", m.projectID))
+		return h, errors.UnauthenticatedErrorf("invalid JWT token")
+	}
+
+	// The token is verified above, so the claims can be read without verifying
+	// it again.
+	mc := jwt.MapClaims{}
+	if _, _, err := new(jwt.Parser).ParseUnverified(jwtToken, mc); err != nil {
+		return h, errors.UnauthenticatedErrorf("%v", err)
+	}
+
+	ch, err := matchClaims(mc, claims)
+	if err != nil {
+		m.logger.Debug("claims of JWT token do not match", zap.Stringer("subject", c.GetSubject()), zap.Error(err))
+		return h, err
+	}
+
+	h.Del("Authorization")
+	for k, v := range ch {
+		h[k] = v
+	}
+	h.Set("X-Rig-User-ID", c.GetSubject().String())
+	return h, nil
+}
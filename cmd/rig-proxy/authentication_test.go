@@ -0,0 +1,153 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func grpcRequest(p string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, p, nil)
+	r.ProtoMajor = 2
+	r.Header.Set("Content-Type", "application/grpc")
+	return r
+}
+
+func Test_ResolveAuth(t *testing.T) {
+	var (
+		defaultAuth = allowAny()
+		httpAuth    = allowAuthorized()
+		grpcAuth    = allowAny()
+		serviceAuth = allowAuthorized()
+		methodAuth  = allowAny()
+	)
+
+	grpc := &capsule.GRPC{
+		Auth: grpcAuth,
+		Services: map[string]*capsule.GRPCService{
+			"api.v1.user.Service": {
+				Auth: serviceAuth,
+				Methods: map[string]*capsule.GRPCMethod{
+					"Get":    {Auth: methodAuth},
+					"Create": {},
+				},
+			},
+			"api.v1.group.Service": {
+				Methods: map[string]*capsule.GRPCMethod{
+					"Get": {Auth: methodAuth},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		name string
+		a    *capsule.Authentication
+		r    *http.Request
+		auth *capsule.Auth
+	}{
+		{
+			name: "method",
+			a:    &capsule.Authentication{Default: defaultAuth, Grpc: grpc},
+			r:    grpcRequest("/api.v1.user.Service/Get"),
+			auth: methodAuth,
+		},
+		{
+			name: "method without auth uses service",
+			a:    &capsule.Authentication{Default: defaultAuth, Grpc: grpc},
+			r:    grpcRequest("/api.v1.user.Service/Create"),
+			auth: serviceAuth,
+		},
+		{
+			name: "unknown method uses service",
+			a:    &capsule.Authentication{Default: defaultAuth, Grpc: grpc},
+			r:    grpcRequest("/api.v1.user.Service/Delete"),
+			auth: serviceAuth,
+		},
+		{
+			name: "service without auth uses grpc",
+			a:    &capsule.Authentication{Default: defaultAuth, Grpc: grpc},
+			r:    grpcRequest("/api.v1.group.Service/List"),
+			auth: grpcAuth,
+		},
+		{
+			name: "unknown service uses grpc",
+			a:    &capsule.Authentication{Default: defaultAuth, Grpc: grpc},
+			r:    grpcRequest("/api.v1.project.Service/Get"),
+			auth: grpcAuth,
+		},
+		{
+			name: "grpc without auth uses default",
+			a: &capsule.Authentication{Default: defaultAuth, Grpc: &capsule.GRPC{
+				Services: grpc.GetServices(),
+			}},
+			r:    grpcRequest("/api.v1.project.Service/Get"),
+			auth: defaultAuth,
+		},
+		{
+			name: "invalid grpc path uses default",
+			a:    &capsule.Authentication{Default: defaultAuth, Grpc: grpc},
+			r:    grpcRequest("/health"),
+			auth: defaultAuth,
+		},
+		{
+			name: "path prefix",
+			a: &capsule.Authentication{Default: defaultAuth, Grpc: &capsule.GRPC{
+				PathPrefix: "/grpc",
+				Services:   grpc.GetServices(),
+			}},
+			r:    grpcRequest("/grpc/api.v1.user.Service/Get"),
+			auth: methodAuth,
+		},
+		{
+			name: "outside path prefix uses default",
+			a: &capsule.Authentication{Default: defaultAuth, Grpc: &capsule.GRPC{
+				PathPrefix: "/grpc",
+				Services:   grpc.GetServices(),
+			}},
+			r:    grpcRequest("/api.v1.user.Service/Get"),
+			auth: defaultAuth,
+		},
+		{
+			name: "http request uses http rules",
+			a: &capsule.Authentication{
+				Default: defaultAuth,
+				Http:    []*capsule.HttpAuth{{Path: "/api.v1.user.Service", Auth: httpAuth}},
+				Grpc:    grpc,
+			},
+			r:    httptest.NewRequest(http.MethodPost, "/api.v1.user.Service/Get", nil),
+			auth: httpAuth,
+		},
+		{
+			name: "grpc request without grpc rules uses http rules",
+			a: &capsule.Authentication{
+				Default: defaultAuth,
+				Http:    []*capsule.HttpAuth{{Path: "/api.v1.user.Service", Auth: httpAuth}},
+			},
+			r:    grpcRequest("/api.v1.user.Service/Get"),
+			auth: httpAuth,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &authenticationMiddleware{
+				a:      tt.a,
+				logger: zap.NewNop(),
+			}
+			assert.Same(t, tt.auth, m.resolveAuth(tt.r))
+		})
+	}
+}
+
+func allowAny() *capsule.Auth {
+	return &capsule.Auth{Method: &capsule.Auth_AllowAny_{AllowAny: &capsule.Auth_AllowAny{}}}
+}
+
+func allowAuthorized() *capsule.Auth {
+	return &capsule.Auth{Method: &capsule.Auth_AllowAuthorized_{AllowAuthorized: &capsule.Auth_AllowAuthorized{}}}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/rigdev/rig/pkg/errors"
+)
+
+// rigHeaderPrefix is the prefix of the headers set by the proxy, which are
+// removed from the incoming requests so they can't be forged.
+const rigHeaderPrefix = "X-Rig-"
+
+// claimAliases are the friendly names of the claims of the rig tokens.
+var claimAliases = map[string]string{
+	"groups":       "gps",
+	"project_id":   "pid",
+	"user_id":      "sub",
+	"subject_type": "sty",
+}
+
+// matchClaims checks that the claims of the token match the required claims,
+// and returns the header values of the required claims. A required value of
+// the form /expr/ is matched as a regular expression, a value containing any
+// of *?[ as a glob, and any other value exactly. A list claim, like the groups
+// of the user, matches if any of its elements match.
+func matchClaims(claims jwt.MapClaims, required map[string]string) (http.Header, error) {
+	keys := make([]string, 0, len(required))
+	for k := range required {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := http.Header{}
+	for _, k := range keys {
+		values, ok := lookupClaim(claims, k)
+		if !ok {
+			return nil, errors.PermissionDeniedErrorf("missing claim '%s'", k)
+		}
+
+		match := false
+		for _, v := range values {
+			m, err := matchClaim(required[k], v)
+			if err != nil {
+				return nil, err
+			}
+			if m {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return nil, errors.PermissionDeniedErrorf("claim '%s' does not match", k)
+		}
+
+		h.Set(claimHeader(k), strings.Join(values, ","))
+	}
+
+	return h, nil
+}
+
+// lookupClaim returns the values of the claim. Nested claims, like the
+// metadata of the user, are looked up by their path, e.g. meta_data.role.
+func lookupClaim(claims jwt.MapClaims, key string) ([]string, bool) {
+	if alias, ok := claimAliases[key]; ok {
+		key = alias
+	}
+
+	var v interface{} = map[string]interface{}(claims)
+	for _, k := range strings.Split(key, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[k]; !ok || v == nil {
+			return nil, false
+		}
+	}
+
+	switch v := v.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, e := range v {
+			values = append(values, fmt.Sprint(e))
+		}
+		return values, true
+	case map[string]interface{}:
+		return nil, false
+	default:
+		return []string{fmt.Sprint(v)}, true
+	}
+}
+
+func matchClaim(expected, value string) (bool, error) {
+	if len(expected) > 1 && strings.HasPrefix(expected, "/") && strings.HasSuffix(expected, "/") {
+		re, err := regexp.Compile("^(?:" + expected[1:len(expected)-1] + ")$")
+		if err != nil {
+			return false, errors.InternalErrorf("invalid claim expression '%s': %v", expected, err)
+		}
+		return re.MatchString(value), nil
+	}
+
+	if strings.ContainsAny(expected, "*?[") {
+		m, err := path.Match(expected, value)
+		if err != nil {
+			return false, errors.InternalErrorf("invalid claim pattern '%s': %v", expected, err)
+		}
+		return m, nil
+	}
+
+	return expected == value, nil
+}
+
+// claimHeader returns the header a claim is forwarded in, e.g. X-Rig-Meta-Data-Role
+// for meta_data.role.
+func claimHeader(key string) string {
+	return http.CanonicalHeaderKey(rigHeaderPrefix + strings.NewReplacer(".", "-", "_", "-").Replace(key))
+}
+
+// removeRigHeaders removes the headers set by the proxy from the request.
+func removeRigHeaders(h http.Header) {
+	for k := range h {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), rigHeaderPrefix) {
+			h.Del(k)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_MatchClaims(t *testing.T) {
+	claims := jwt.MapClaims{
+		"sub": "6a8f7d5e-0a3c-4e1e-9a57-3f0d1f0b7d11",
+		"sty": float64(1),
+		"gps": []interface{}{"admins", "developers"},
+		"meta_data": map[string]interface{}{
+			"email": "jane@example.com",
+			"role":  "owner",
+		},
+	}
+
+	tests := []struct {
+		name     string
+		required map[string]string
+		headers  http.Header
+		err      error
+	}{
+		{
+			name: "no required claims",
+		},
+		{
+			name:     "exact",
+			required: map[string]string{"sub": "6a8f7d5e-0a3c-4e1e-9a57-3f0d1f0b7d11"},
+			headers:  http.Header{"X-Rig-Sub": {"6a8f7d5e-0a3c-4e1e-9a57-3f0d1f0b7d11"}},
+		},
+		{
+			name:     "number",
+			required: map[string]string{"subject_type": "1"},
+			headers:  http.Header{"X-Rig-Subject-Type": {"1"}},
+		},
+		{
+			name:     "exact mismatch",
+			required: map[string]string{"meta_data.role": "admin"},
+			err:      errors.PermissionDeniedErrorf("claim 'meta_data.role' does not match"),
+		},
+		{
+			name:     "missing",
+			required: map[string]string{"meta_data.team": "core"},
+			err:      errors.PermissionDeniedErrorf("missing claim 'meta_data.team'"),
+		},
+		{
+			name:     "group membership",
+			required: map[string]string{"groups": "developers"},
+			headers:  http.Header{"X-Rig-Groups": {"admins,developers"}},
+		},
+		{
+			name:     "not a member",
+			required: map[string]string{"groups": "owners"},
+			err:      errors.PermissionDeniedErrorf("claim 'groups' does not match"),
+		},
+		{
+			name:     "glob",
+			required: map[string]string{"meta_data.email": "*@example.com"},
+			headers:  http.Header{"X-Rig-Meta-Data-Email": {"jane@example.com"}},
+		},
+		{
+			name:     "regex",
+			required: map[string]string{"meta_data.role": "/owner|admin/"},
+			headers:  http.Header{"X-Rig-Meta-Data-Role": {"owner"}},
+		},
+		{
+			name:     "regex is anchored",
+			required: map[string]string{"meta_data.role": "/own/"},
+			err:      errors.PermissionDeniedErrorf("claim 'meta_data.role' does not match"),
+		},
+		{
+			name:     "all must match",
+			required: map[string]string{"groups": "admins", "meta_data.role": "admin"},
+			err:      errors.PermissionDeniedErrorf("claim 'meta_data.role' does not match"),
+		},
+		{
+			name:     "object",
+			required: map[string]string{"meta_data": "*"},
+			err:      errors.PermissionDeniedErrorf("missing claim 'meta_data'"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := matchClaims(claims, tt.required)
+			if tt.err != nil {
+				assert.Equal(t, tt.err.Error(), err.Error())
+				assert.True(t, errors.IsPermissionDenied(err))
+				return
+			}
+
+			require.NoError(t, err)
+			if tt.headers == nil {
+				tt.headers = http.Header{}
+			}
+			assert.Equal(t, tt.headers, h)
+		})
+	}
+}
+
+func Test_AuthenticationMiddleware_Claims(t *testing.T) {
+	secret := []byte("secret")
+	projectID := uuid.New()
+	subject := uuid.New()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"pid": projectID.String(),
+		"sub": subject.String(),
+		"gps": []string{"developers"},
+	}).SignedString(secret)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		claims  map[string]string
+		status  int
+		headers http.Header
+	}{
+		{
+			name:   "missing token",
+			status: http.StatusUnauthorized,
+		},
+		{
+			name:   "claims match",
+			token:  token,
+			claims: map[string]string{"groups": "developers"},
+			status: http.StatusOK,
+			headers: http.Header{
+				"X-Rig-User-Id": {subject.String()},
+				"X-Rig-Groups":  {"developers"},
+			},
+		},
+		{
+			name:   "claims mismatch",
+			token:  token,
+			claims: map[string]string{"groups": "admins"},
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers http.Header
+			m := &authenticationMiddleware{
+				a: &capsule.Authentication{
+					Enabled: true,
+					Default: &capsule.Auth{Method: &capsule.Auth_AllowAuthorized_{
+						AllowAuthorized: &capsule.Auth_AllowAuthorized{Claims: tt.claims},
+					}},
+				},
+				projectID: projectID,
+				publicKey: secret,
+				next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					headers = r.Header
+				}),
+				logger: zap.NewNop(),
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			// Forged headers are removed.
+			r.Header.Set("X-Rig-Groups", "admins")
+			if tt.token != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.headers, headers)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+var defaultCorsMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost}
+
+// corsMiddleware handles Cross-Origin Resource Sharing. Preflight requests are
+// answered without reaching the capsule, and the CORS headers of other
+// responses are replaced, so the capsule doesn't need to handle CORS itself.
+type corsMiddleware struct {
+	c    *capsule.Cors
+	next http.Handler
+}
+
+func (m *corsMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if !m.c.GetEnabled() || origin == "" {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+		m.handlePreflight(w, r, origin)
+		return
+	}
+
+	w.Header().Add("Vary", "Origin")
+	if !m.allowOrigin(origin) {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	w = &rewriteResponseWriter{
+		ResponseWriter: w,
+		rewrite: func(h http.Header) {
+			removeCorsHeaders(h)
+			m.setOriginHeaders(h, origin)
+			if len(m.c.GetExposedHeaders()) > 0 {
+				h.Set("Access-Control-Expose-Headers", strings.Join(m.c.GetExposedHeaders(), ", "))
+			}
+		},
+	}
+
+	m.next.ServeHTTP(w, r)
+}
+
+func (m *corsMiddleware) handlePreflight(w http.ResponseWriter, r *http.Request, origin string) {
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+
+	method := r.Header.Get("Access-Control-Request-Method")
+	headers := requestedHeaders(r)
+	if !m.allowOrigin(origin) || !m.allowMethod(method) || !m.allowHeaders(headers) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	m.setOriginHeaders(h, origin)
+	h.Set("Access-Control-Allow-Methods", method)
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if d := m.c.GetMaxAge().AsDuration(); d > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(d.Seconds())))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (m *corsMiddleware) setOriginHeaders(h http.Header, origin string) {
+	// Credentials can't be used with a wildcard origin, so the origin is
+	// returned as is instead.
+	if m.allowAnyOrigin() && !m.c.GetAllowCredentials() {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+
+	if m.c.GetAllowCredentials() {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (m *corsMiddleware) allowAnyOrigin() bool {
+	for _, o := range m.c.GetAllowedOrigins() {
+		if o == "*" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *corsMiddleware) allowOrigin(origin string) bool {
+	for _, o := range m.c.GetAllowedOrigins() {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+
+		prefix, suffix, ok := strings.Cut(strings.ToLower(o), "*")
+		lo := strings.ToLower(origin)
+		if ok && len(lo) > len(prefix)+len(suffix) &&
+			strings.HasPrefix(lo, prefix) && strings.HasSuffix(lo, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *corsMiddleware) allowMethod(method string) bool {
+	methods := m.c.GetAllowedMethods()
+	if len(methods) == 0 {
+		methods = defaultCorsMethods
+	}
+
+	for _, am := range methods {
+		if am == "*" || strings.EqualFold(am, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *corsMiddleware) allowHeaders(headers []string) bool {
+	allowed := map[string]struct{}{}
+	for _, h := range m.c.GetAllowedHeaders() {
+		if h == "*" {
+			return true
+		}
+		allowed[http.CanonicalHeaderKey(h)] = struct{}{}
+	}
+
+	for _, h := range headers {
+		if _, ok := allowed[http.CanonicalHeaderKey(h)]; !ok && !corsSafelistedHeader(h) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func requestedHeaders(r *http.Request) []string {
+	var headers []string
+	for _, v := range r.Header.Values("Access-Control-Request-Headers") {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				headers = append(headers, h)
+			}
+		}
+	}
+
+	return headers
+}
+
+func corsSafelistedHeader(h string) bool {
+	switch http.CanonicalHeaderKey(h) {
+	case "Accept", "Accept-Language", "Content-Language", "Content-Type":
+		return true
+	default:
+		return false
+	}
+}
+
+func removeCorsHeaders(h http.Header) {
+	for k := range h {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), "Access-Control-") {
+			h.Del(k)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func Test_CorsMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       *capsule.Cors
+		r       func() *http.Request
+		status  int
+		headers map[string]string
+		next    bool
+	}{
+		{
+			name: "no origin",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"https://example.com"}},
+			r: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+			next: true,
+		},
+		{
+			name: "allowed origin",
+			c: &capsule.Cors{
+				Enabled:        true,
+				AllowedOrigins: []string{"https://example.com"},
+				ExposedHeaders: []string{"X-Request-Id"},
+			},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":   "https://example.com",
+				"Access-Control-Expose-Headers": "X-Request-Id",
+				"Vary":                          "Origin",
+			},
+			next: true,
+		},
+		{
+			name: "wildcard origin",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"https://*.example.com"}},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Origin", "https://app.example.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "https://app.example.com",
+			},
+			next: true,
+		},
+		{
+			name: "any origin",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"*"}},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+			next: true,
+		},
+		{
+			name: "any origin with credentials",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"*"}, AllowCredentials: true},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				return r
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":      "https://example.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+			next: true,
+		},
+		{
+			name: "disallowed origin",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"https://*.example.com"}},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				r.Header.Set("Origin", "https://example.org")
+				return r
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+			next: true,
+		},
+		{
+			name: "preflight",
+			c: &capsule.Cors{
+				Enabled:        true,
+				AllowedOrigins: []string{"https://example.com"},
+				AllowedMethods: []string{http.MethodPut},
+				AllowedHeaders: []string{"Authorization"},
+				MaxAge:         durationpb.New(time.Hour),
+			},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodOptions, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				r.Header.Set("Access-Control-Request-Method", http.MethodPut)
+				r.Header.Set("Access-Control-Request-Headers", "authorization, content-type")
+				return r
+			},
+			status: http.StatusNoContent,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin":  "https://example.com",
+				"Access-Control-Allow-Methods": http.MethodPut,
+				"Access-Control-Allow-Headers": "authorization, content-type",
+				"Access-Control-Max-Age":       "3600",
+			},
+		},
+		{
+			name: "preflight disallowed method",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"https://example.com"}},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodOptions, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				r.Header.Set("Access-Control-Request-Method", http.MethodDelete)
+				return r
+			},
+			status: http.StatusForbidden,
+			headers: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name: "preflight disallowed header",
+			c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"https://example.com"}},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodOptions, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				r.Header.Set("Access-Control-Request-Headers", "X-Custom")
+				return r
+			},
+			status: http.StatusForbidden,
+		},
+		{
+			name: "disabled",
+			c:    &capsule.Cors{AllowedOrigins: []string{"https://example.com"}},
+			r: func() *http.Request {
+				r := httptest.NewRequest(http.MethodOptions, "/", nil)
+				r.Header.Set("Origin", "https://example.com")
+				r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+				return r
+			},
+			status: http.StatusOK,
+			next:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := false
+			m := &corsMiddleware{
+				c: tt.c,
+				next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					next = true
+					// The CORS headers of the capsule are replaced.
+					w.Header().Set("Access-Control-Allow-Origin", "*")
+					w.WriteHeader(http.StatusOK)
+				}),
+			}
+
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, tt.r())
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.Equal(t, tt.next, next)
+			for k, v := range tt.headers {
+				assert.Equal(t, v, w.Header().Get(k), k)
+			}
+		})
+	}
+}
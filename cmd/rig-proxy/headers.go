@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+// headersMiddleware rewrites the headers of the requests and the responses.
+type headersMiddleware struct {
+	h    *capsule.Headers
+	next http.Handler
+}
+
+func (m *headersMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.h.GetEnabled() {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	rewriteHeaders(r.Header, m.h.GetRequest())
+	if res := m.h.GetResponse(); res != nil {
+		w = &rewriteResponseWriter{
+			ResponseWriter: w,
+			rewrite: func(h http.Header) {
+				rewriteHeaders(h, res)
+			},
+		}
+	}
+
+	m.next.ServeHTTP(w, r)
+}
+
+func rewriteHeaders(h http.Header, hr *capsule.HeaderRewrite) {
+	for _, k := range hr.GetRemove() {
+		h.Del(k)
+	}
+	for k, v := range hr.GetSet() {
+		h.Set(k, v)
+	}
+	for k, v := range hr.GetAppend() {
+		h.Add(k, v)
+	}
+}
+
+// rewriteResponseWriter rewrites the headers of the response right before they
+// are written, after the capsule has set its own headers.
+type rewriteResponseWriter struct {
+	http.ResponseWriter
+	rewrite func(http.Header)
+	wrote   bool
+}
+
+func (w *rewriteResponseWriter) WriteHeader(status int) {
+	if !w.wrote {
+		w.wrote = true
+		w.rewrite(w.Header())
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *rewriteResponseWriter) Write(p []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush is needed for streaming responses, like gRPC streams.
+func (w *rewriteResponseWriter) Flush() {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter, to
+// hijack the connection when switching protocols, like upgrading to
+// WebSockets.
+func (w *rewriteResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
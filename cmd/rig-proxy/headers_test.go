@@ -0,0 +1,109 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_HeadersMiddleware(t *testing.T) {
+	tests := []struct {
+		name     string
+		h        *capsule.Headers
+		request  http.Header
+		response http.Header
+	}{
+		{
+			name: "disabled",
+			h: &capsule.Headers{
+				Request: &capsule.HeaderRewrite{Set: map[string]string{"X-Env": "prod"}},
+			},
+			request:  http.Header{"X-Remove": {"1"}, "X-Append": {"a"}},
+			response: http.Header{"Server": {"capsule"}, "Cache-Control": {"no-cache"}},
+		},
+		{
+			name: "request",
+			h: &capsule.Headers{
+				Enabled: true,
+				Request: &capsule.HeaderRewrite{
+					Set:    map[string]string{"X-Env": "prod"},
+					Append: map[string]string{"X-Append": "b"},
+					Remove: []string{"x-remove"},
+				},
+			},
+			request:  http.Header{"X-Env": {"prod"}, "X-Append": {"a", "b"}},
+			response: http.Header{"Server": {"capsule"}, "Cache-Control": {"no-cache"}},
+		},
+		{
+			name: "response",
+			h: &capsule.Headers{
+				Enabled: true,
+				Response: &capsule.HeaderRewrite{
+					Set:    map[string]string{"Cache-Control": "no-store"},
+					Append: map[string]string{"Vary": "Accept"},
+					Remove: []string{"Server"},
+				},
+			},
+			request:  http.Header{"X-Remove": {"1"}, "X-Append": {"a"}},
+			response: http.Header{"Cache-Control": {"no-store"}, "Vary": {"Accept"}},
+		},
+		{
+			name: "remove then set",
+			h: &capsule.Headers{
+				Enabled: true,
+				Response: &capsule.HeaderRewrite{
+					Set:    map[string]string{"Server": "rig"},
+					Remove: []string{"Server"},
+				},
+			},
+			request:  http.Header{"X-Remove": {"1"}, "X-Append": {"a"}},
+			response: http.Header{"Server": {"rig"}, "Cache-Control": {"no-cache"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var request http.Header
+			m := &headersMiddleware{
+				h: tt.h,
+				next: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					request = r.Header
+					w.Header().Set("Server", "capsule")
+					w.Header().Set("Cache-Control", "no-cache")
+					w.Write([]byte("ok"))
+				}),
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("X-Remove", "1")
+			r.Header.Set("X-Append", "a")
+
+			w := httptest.NewRecorder()
+			m.ServeHTTP(w, r)
+
+			assert.Equal(t, tt.request, request)
+			w.Header().Del("Content-Type")
+			assert.Equal(t, tt.response, w.Header())
+		})
+	}
+}
+
+func Test_HeadersMiddleware_Upgrade(t *testing.T) {
+	var h http.Handler = &headersMiddleware{
+		h: &capsule.Headers{
+			Enabled:  true,
+			Response: &capsule.HeaderRewrite{Set: map[string]string{"X-Env": "prod"}},
+		},
+		next: upgradeProxy(t),
+	}
+	h = &corsMiddleware{
+		c:    &capsule.Cors{Enabled: true, AllowedOrigins: []string{"https://example.com"}},
+		next: h,
+	}
+
+	_, res := upgrade(t, h, http.Header{"Origin": {"https://example.com"}})
+	assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rigdev/rig/pkg/proxy"
+	"go.uber.org/zap"
+)
+
+type accessLogKey struct{}
+
+// setAccessLogUserID sets the user ID of the access log line of the request,
+// if the request is logged.
+func setAccessLogUserID(ctx context.Context, userID string) {
+	if l, ok := ctx.Value(accessLogKey{}).(*proxy.AccessLog); ok {
+		l.UserID = userID
+	}
+}
+
+// loggingMiddleware writes an access log line for every request. It must be
+// the outermost middleware, so requests rejected by the other middlewares are
+// logged as well.
+type loggingMiddleware struct {
+	next   http.Handler
+	logger *zap.Logger
+
+	lock sync.Mutex
+	enc  *json.Encoder
+}
+
+func newLoggingMiddleware(next http.Handler, out io.Writer, logger *zap.Logger) *loggingMiddleware {
+	return &loggingMiddleware{
+		next:   next,
+		logger: logger,
+		enc:    json.NewEncoder(out),
+	}
+}
+
+func (m *loggingMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	l := &proxy.AccessLog{
+		Kind:   proxy.AccessLogKind,
+		Time:   start,
+		Method: r.Method,
+		Path:   r.URL.Path,
+	}
+	if proxy.IsGRPC(r) {
+		l.GRPCService, l.GRPCMethod = splitGRPCPath(r.URL.Path)
+	}
+
+	body := &countingReader{ReadCloser: r.Body}
+	if r.Body != nil {
+		r.Body = body
+	}
+	rw := &loggingResponseWriter{ResponseWriter: w}
+
+	m.next.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), accessLogKey{}, l)))
+
+	l.Status = rw.status
+	if l.Status == 0 {
+		l.Status = http.StatusOK
+	}
+	l.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	l.BytesIn = body.n
+	l.BytesOut = rw.n
+	if l.GRPCService != "" {
+		l.GRPCStatus = grpcStatus(w.Header())
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if err := m.enc.Encode(l); err != nil {
+		m.logger.Warn("could not write access log", zap.Error(err))
+	}
+}
+
+// splitGRPCPath splits a gRPC path of the form /package.Service/Method into
+// the service and the method.
+func splitGRPCPath(p string) (string, string) {
+	service, method, ok := strings.Cut(strings.TrimPrefix(p, "/"), "/")
+	if !ok {
+		return "", ""
+	}
+
+	return service, method
+}
+
+// grpcStatus returns the gRPC status code of the response, which is sent as a
+// trailer, either declared up front or by the trailer prefix.
+func grpcStatus(h http.Header) string {
+	if s := h.Get("Grpc-Status"); s != "" {
+		return s
+	}
+
+	return h.Get(http.TrailerPrefix + "Grpc-Status")
+}
+
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+type loggingResponseWriter struct {
+	http.ResponseWriter
+	status int
+	n      int64
+}
+
+func (w *loggingResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush is needed for streaming responses, like gRPC streams.
+func (w *loggingResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack is used to switch protocols, like upgrading to WebSockets. The
+// response is written to the hijacked connection, so the status is recorded
+// here.
+func (w *loggingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, brw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+	if err == nil && w.status == 0 {
+		w.status = http.StatusSwitchingProtocols
+	}
+	return conn, brw, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter.
+func (w *loggingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig/pkg/proxy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_LoggingMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     func() *http.Request
+		handler http.HandlerFunc
+		log     proxy.AccessLog
+	}{
+		{
+			name: "http",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/api/users", strings.NewReader("body"))
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.ReadAll(r.Body)
+				setAccessLogUserID(r.Context(), "user")
+				w.WriteHeader(http.StatusCreated)
+				w.Write([]byte("created"))
+			},
+			log: proxy.AccessLog{
+				Kind:     proxy.AccessLogKind,
+				Method:   http.MethodPost,
+				Path:     "/api/users",
+				Status:   http.StatusCreated,
+				BytesIn:  4,
+				BytesOut: 7,
+				UserID:   "user",
+			},
+		},
+		{
+			name: "implicit status",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/", nil)
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			log: proxy.AccessLog{
+				Kind:   proxy.AccessLogKind,
+				Method: http.MethodGet,
+				Path:   "/",
+				Status: http.StatusOK,
+			},
+		},
+		{
+			name: "grpc",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodPost, "/api.v1.user.Service/Get", nil)
+				r.ProtoMajor = 2
+				r.Header.Set("Content-Type", "application/grpc+proto")
+				return r
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				w.Header().Set(http.TrailerPrefix+"Grpc-Status", "5")
+			},
+			log: proxy.AccessLog{
+				Kind:        proxy.AccessLogKind,
+				Method:      http.MethodPost,
+				Path:        "/api.v1.user.Service/Get",
+				Status:      http.StatusOK,
+				GRPCService: "api.v1.user.Service",
+				GRPCMethod:  "Get",
+				GRPCStatus:  "5",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			m := newLoggingMiddleware(tt.handler, out, zap.NewNop())
+			m.ServeHTTP(httptest.NewRecorder(), tt.req())
+
+			assert.True(t, proxy.IsAccessLog(bytes.TrimSpace(out.Bytes())))
+
+			var l proxy.AccessLog
+			require.NoError(t, json.Unmarshal(out.Bytes(), &l))
+			assert.False(t, l.Time.IsZero())
+			assert.GreaterOrEqual(t, l.LatencyMS, float64(0))
+			l.Time = tt.log.Time
+			l.LatencyMS = 0
+			assert.Equal(t, tt.log, l)
+		})
+	}
+}
+
+func Test_LoggingMiddleware_Upgrade(t *testing.T) {
+	pr, pw := io.Pipe()
+	logs := make(chan proxy.AccessLog, 1)
+	go func() {
+		var l proxy.AccessLog
+		json.NewDecoder(pr).Decode(&l)
+		logs <- l
+	}()
+
+	m := newLoggingMiddleware(upgradeProxy(t), pw, zap.NewNop())
+	conn, res := upgrade(t, m, nil)
+	require.Equal(t, http.StatusSwitchingProtocols, res.StatusCode)
+	conn.Close()
+
+	// The line is logged once the connection is closed.
+	select {
+	case l := <-logs:
+		assert.Equal(t, http.StatusSwitchingProtocols, l.Status)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no access log")
+	}
+}
+
+// upgradeProxy returns a reverse proxy to a backend echoing lines after
+// switching to the "echo" protocol.
+func upgradeProxy(t *testing.T) http.Handler {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, brw, err := http.NewResponseController(w).Hijack()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprint(brw, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n\r\n")
+		brw.Flush()
+		for {
+			line, err := brw.ReadString('\n')
+			if err != nil {
+				return
+			}
+			brw.WriteString(line)
+			brw.Flush()
+		}
+	}))
+	t.Cleanup(backend.Close)
+
+	u, err := url.Parse(backend.URL)
+	require.NoError(t, err)
+	return httputil.NewSingleHostReverseProxy(u)
+}
+
+// upgrade switches a connection to h to the "echo" protocol, and checks that
+// lines are echoed if the upgrade succeeded.
+func upgrade(t *testing.T, h http.Handler, header http.Header) (net.Conn, *http.Response) {
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+
+	conn, err := net.Dial("tcp", s.Listener.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	r := httptest.NewRequest(http.MethodGet, "http://test/", nil)
+	r.Header = header.Clone()
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "echo")
+	require.NoError(t, r.Write(conn))
+
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	if res.StatusCode != http.StatusSwitchingProtocols {
+		return conn, res
+	}
+
+	_, err = fmt.Fprint(conn, "ping\n")
+	require.NoError(t, err)
+	line, err := br.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "ping\n", line)
+
+	return conn, res
+}
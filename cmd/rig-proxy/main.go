@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"crypto/x509"
 	"encoding/pem"
 	"flag"
@@ -8,18 +9,14 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"path"
 	"reflect"
 	"strconv"
-	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	proto_proxy "github.com/rigdev/rig/gen/go/proxy"
 	"github.com/rigdev/rig/internal/build"
-	"github.com/rigdev/rig/internal/service/auth"
-	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/proxy"
 	"github.com/rigdev/rig/pkg/uuid"
 	"go.uber.org/zap"
@@ -99,8 +96,25 @@ func main() {
 				logger.Fatal("invalid project ID", zap.String("project_id", pc.GetProjectId()), zap.Error(err))
 			}
 
+			var logging bool
 			for _, m := range e.GetMiddlewares() {
 				switch v := m.Kind.(type) {
+				case *capsule.Middleware_Logging:
+					logging = v.Logging.GetEnabled()
+				case *capsule.Middleware_RateLimit:
+					rl := newRateLimitMiddleware(v.RateLimit, e.GetSourcePort(), e.GetTrustForwardedFor(), h, logger)
+					go rl.run(context.Background())
+					h = rl
+				case *capsule.Middleware_Headers:
+					h = &headersMiddleware{
+						h:    v.Headers,
+						next: h,
+					}
+				case *capsule.Middleware_Cors:
+					h = &corsMiddleware{
+						c:    v.Cors,
+						next: h,
+					}
 				case *capsule.Middleware_Authentication:
 					h = &authenticationMiddleware{
 						a:         v.Authentication,
@@ -115,6 +129,13 @@ func main() {
 				}
 			}
 
+			// Logging wraps the other middlewares, so rejected requests are
+			// logged too. The access log is written to stdout, apart from the
+			// logs of the proxy itself.
+			if logging {
+				h = newLoggingMiddleware(h, os.Stdout, logger)
+			}
+
 			s := &http.Server{
 				Addr:    fmt.Sprint(":", e.GetSourcePort()),
 				Handler: h2c.NewHandler(h, &http2.Server{}),
@@ -130,97 +151,21 @@ func main() {
 		}
 	}
 
-	for {
-		time.Sleep(time.Second)
-	}
-}
-
-type authenticationMiddleware struct {
-	a         *capsule.Authentication
-	projectID uuid.UUID
-	publicKey interface{}
-	issuer    string
-	next      http.Handler
-	logger    *zap.Logger
-}
-
-func (m *authenticationMiddleware) handlePrefix(prefix string, a *capsule.Auth, w http.ResponseWriter, r *http.Request) {
-	m.logger.Debug("using path prefix", zap.String("prefix", prefix))
-	rp := path.Clean(r.URL.Path)
-	for _, h := range m.a.GetHttp() {
-		pp := path.Join(prefix, h.GetPath())
-		if rp == pp || (!h.GetExact() && strings.HasPrefix(rp, pp)) {
-			a = h.GetAuth()
-			break
-		}
-	}
-
-	switch v := a.GetMethod().(type) {
-	case *capsule.Auth_AllowAuthorized_:
-		h, err := m.handleJWTAuth(r.Header)
-		if err != nil {
-			w.WriteHeader(errors.ToHTTP(err))
-			w.Write([]byte(errors.MessageOf(err)))
-			w.Write([]byte("\n"))
-			return
+	if pc.GetMetricsPort() != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		s := &http.Server{
+			Addr:    fmt.Sprint(":", pc.GetMetricsPort()),
+			Handler: mux,
 		}
-		r.Header = h
-	case *capsule.Auth_AllowAny_:
-		break
-	default:
-		m.logger.Warn("invalid auth method for path prefix", zap.Any("method", v), zap.String("prefix", prefix))
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte("invalid auth configuration"))
-		w.Write([]byte("\n"))
-		return
-	}
 
-	m.next.ServeHTTP(w, r)
-}
-
-func (m *authenticationMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if m.a.GetEnabled() {
-		m.handlePrefix("/", m.a.GetDefault(), w, r)
-	} else {
-		m.next.ServeHTTP(w, r)
+		logger.Info("serving metrics", zap.Uint32("port", pc.GetMetricsPort()))
+		go func() {
+			logger.Fatal("error serving metrics", zap.Error(s.ListenAndServe()))
+		}()
 	}
-}
 
-func (m *authenticationMiddleware) handleJWTAuth(h http.Header) (http.Header, error) {
-	ah := h.Get("Authorization")
-	if !strings.HasPrefix(ah, "Bearer ") {
-		m.logger.Debug("request is missing authorization bearer")
-		return h, errors.UnauthenticatedErrorf("missing authorization bearer")
-	}
-
-	jwtToken := strings.TrimPrefix(ah, "Bearer ")
-
-	c := &auth.RigClaims{}
-	token, err := jwt.ParseWithClaims(
-		jwtToken,
-		c,
-		func(token *jwt.Token) (interface{}, error) {
-			return m.publicKey, nil
-		},
-	)
-	if err != nil {
-		return h, errors.UnauthenticatedErrorf("%v", err)
-	}
-
-	if !token.Valid {
-		return h, errors.InvalidArgumentErrorf("invalid JWT token format")
-	}
-
-	if c.GetIssuer() != m.issuer {
-		return h, errors.InvalidArgumentErrorf("invalid JWT issuer")
-	}
-
-	if c.GetProjectID() != m.projectID {
-		m.logger.Info("invalid project ID", zap.Stringer("claims_project_id", c.GetProjectID()), zap.Stringer("service_project_id", m.projectID))
-		return h, errors.UnauthenticatedErrorf("invalid JWT token")
+	for {
+		time.Sleep(time.Second)
 	}
-
-	h.Del("Authorization")
-	h.Set("X-Rig-User-ID", c.GetSubject().String())
-	return h, nil
 }
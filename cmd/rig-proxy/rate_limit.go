@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/proxy"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// rateLimitIdleTimeout is how long the bucket of a client is kept after its
+	// last request.
+	rateLimitIdleTimeout = 10 * time.Minute
+	// rateLimitCleanupInterval is how often idle buckets are removed.
+	rateLimitCleanupInterval = time.Minute
+)
+
+var rateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "rig_proxy_rate_limited_requests_total",
+	Help: "The number of requests rejected by the rate limit.",
+}, []string{"port", "limit"})
+
+func init() {
+	prometheus.MustRegister(rateLimitedRequests)
+}
+
+type bucket struct {
+	l        *rate.Limiter
+	lastSeen time.Time
+}
+
+// rateLimitMiddleware limits the rate of requests of each client with a token
+// bucket per client and limit.
+type rateLimitMiddleware struct {
+	rl     *capsule.RateLimit
+	port   string
+	next   http.Handler
+	logger *zap.Logger
+	now    func() time.Time
+	// trustForwardedFor is set if the interface is only reached through an
+	// ingress, which sets X-Forwarded-For.
+	trustForwardedFor bool
+
+	lock    sync.Mutex
+	buckets map[string]*bucket
+}
+
+func newRateLimitMiddleware(rl *capsule.RateLimit, port uint32, trustForwardedFor bool, next http.Handler, logger *zap.Logger) *rateLimitMiddleware {
+	return &rateLimitMiddleware{
+		rl:                rl,
+		port:              fmt.Sprint(port),
+		next:              next,
+		logger:            logger,
+		now:               time.Now,
+		trustForwardedFor: trustForwardedFor,
+		buckets:           map[string]*bucket{},
+	}
+}
+
+func (m *rateLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !m.rl.GetEnabled() {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	name, l := m.resolveLimit(r)
+	if l.GetRequestsPerSecond() <= 0 {
+		m.next.ServeHTTP(w, r)
+		return
+	}
+
+	if delay := m.reserve(name, m.clientKey(r), l); delay > 0 {
+		rateLimitedRequests.WithLabelValues(m.port, name).Inc()
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte("too many requests"))
+		w.Write([]byte("\n"))
+		return
+	}
+
+	m.next.ServeHTTP(w, r)
+}
+
+// resolveLimit returns the limit of the request and its name. gRPC requests
+// are matched by the gRPC limits, if any, and other requests by the HTTP
+// limits. Requests not matched by any limit use the default limit.
+func (m *rateLimitMiddleware) resolveLimit(r *http.Request) (string, *capsule.Limit) {
+	if g := m.rl.GetGrpc(); g != nil && proxy.IsGRPC(r) {
+		if service, method := grpcMethod(g.GetPathPrefix(), r.URL.Path); service != "" {
+			if l, ok := g.GetMethods()[service+"/"+method]; ok {
+				return service + "/" + method, l
+			}
+			if l, ok := g.GetMethods()[service]; ok {
+				return service, l
+			}
+		}
+		return "default", m.rl.GetDefault()
+	}
+
+	for _, h := range m.rl.GetHttp() {
+		if matchHTTPPath("/", h.GetPath(), h.GetExact(), r.URL.Path) {
+			return h.GetPath(), h.GetLimit()
+		}
+	}
+
+	return "default", m.rl.GetDefault()
+}
+
+// clientKey returns the key the client of the request is told apart by.
+func (m *rateLimitMiddleware) clientKey(r *http.Request) string {
+	switch m.rl.GetKey() {
+	case capsule.RateLimit_KEY_SUBJECT:
+		if s, ok := subjectFromContext(r.Context()); ok {
+			return "subject:" + s
+		}
+	case capsule.RateLimit_KEY_HEADER:
+		if v := r.Header.Get(m.rl.GetHeader()); v != "" {
+			return "header:" + v
+		}
+	}
+
+	return "ip:" + m.clientIP(r)
+}
+
+// clientIP returns the IP of the client of the request. Behind an ingress, the
+// client is the last address of X-Forwarded-For, as added by the ingress.
+// Other interfaces are reached by clients directly, which could set any
+// X-Forwarded-For to get a new bucket, so it is ignored.
+func (m *rateLimitMiddleware) clientIP(r *http.Request) string {
+	if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ","); m.trustForwardedFor && xff != "" {
+		addrs := strings.Split(xff, ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// reserve takes a token from the bucket of the client for the limit, and
+// returns how long to wait before retrying if the bucket is empty.
+func (m *rateLimitMiddleware) reserve(name, client string, l *capsule.Limit) time.Duration {
+	now := m.now()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	key := name + "\x00" + client
+	b, ok := m.buckets[key]
+	if !ok {
+		burst := int(l.GetBurst())
+		if burst == 0 {
+			burst = int(math.Ceil(l.GetRequestsPerSecond()))
+		}
+		b = &bucket{l: rate.NewLimiter(rate.Limit(l.GetRequestsPerSecond()), burst)}
+		m.buckets[key] = b
+	}
+	b.lastSeen = now
+
+	res := b.l.ReserveN(now, 1)
+	if delay := res.DelayFrom(now); delay > 0 {
+		res.CancelAt(now)
+		return delay
+	}
+
+	return 0
+}
+
+// run removes the buckets of idle clients until the context is done.
+func (m *rateLimitMiddleware) run(ctx context.Context) {
+	t := time.NewTicker(rateLimitCleanupInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			m.cleanup()
+		}
+	}
+}
+
+func (m *rateLimitMiddleware) cleanup() {
+	now := m.now()
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for k, b := range m.buckets {
+		if now.Sub(b.lastSeen) > rateLimitIdleTimeout {
+			delete(m.buckets, k)
+		}
+	}
+}
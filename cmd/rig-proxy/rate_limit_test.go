@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func Test_ResolveLimit(t *testing.T) {
+	var (
+		defaultLimit = &capsule.Limit{RequestsPerSecond: 10}
+		httpLimit    = &capsule.Limit{RequestsPerSecond: 5}
+		serviceLimit = &capsule.Limit{RequestsPerSecond: 2}
+		methodLimit  = &capsule.Limit{RequestsPerSecond: 1}
+	)
+
+	rl := &capsule.RateLimit{
+		Enabled: true,
+		Default: defaultLimit,
+		Http:    []*capsule.HttpRateLimit{{Path: "/login", Limit: httpLimit}},
+		Grpc: &capsule.GRPCRateLimit{
+			Methods: map[string]*capsule.Limit{
+				"api.v1.user.Service":       serviceLimit,
+				"api.v1.user.Service/Login": methodLimit,
+			},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		r     *http.Request
+		limit *capsule.Limit
+		key   string
+	}{
+		{
+			name:  "http path",
+			r:     httptest.NewRequest(http.MethodPost, "/login/password", nil),
+			limit: httpLimit,
+			key:   "/login",
+		},
+		{
+			name:  "http default",
+			r:     httptest.NewRequest(http.MethodGet, "/users", nil),
+			limit: defaultLimit,
+			key:   "default",
+		},
+		{
+			name:  "grpc method",
+			r:     grpcRequest("/api.v1.user.Service/Login"),
+			limit: methodLimit,
+			key:   "api.v1.user.Service/Login",
+		},
+		{
+			name:  "grpc service",
+			r:     grpcRequest("/api.v1.user.Service/Get"),
+			limit: serviceLimit,
+			key:   "api.v1.user.Service",
+		},
+		{
+			name:  "grpc default",
+			r:     grpcRequest("/api.v1.group.Service/Get"),
+			limit: defaultLimit,
+			key:   "default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newRateLimitMiddleware(rl, 8080, false, nil, zap.NewNop())
+			key, limit := m.resolveLimit(tt.r)
+			assert.Equal(t, tt.key, key)
+			assert.Same(t, tt.limit, limit)
+		})
+	}
+}
+
+func Test_RateLimitMiddleware(t *testing.T) {
+	tests := []struct {
+		name              string
+		rl                *capsule.RateLimit
+		trustForwardedFor bool
+		requests          func() []*http.Request
+		statuses          []int
+	}{
+		{
+			name: "burst then limited",
+			rl: &capsule.RateLimit{
+				Enabled: true,
+				Default: &capsule.Limit{RequestsPerSecond: 1, Burst: 2},
+			},
+			requests: func() []*http.Request {
+				return []*http.Request{
+					httptest.NewRequest(http.MethodGet, "/", nil),
+					httptest.NewRequest(http.MethodGet, "/", nil),
+					httptest.NewRequest(http.MethodGet, "/", nil),
+				}
+			},
+			statuses: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name: "no limit",
+			rl: &capsule.RateLimit{
+				Enabled: true,
+				Http:    []*capsule.HttpRateLimit{{Path: "/login", Limit: &capsule.Limit{RequestsPerSecond: 1}}},
+			},
+			requests: func() []*http.Request {
+				return []*http.Request{
+					httptest.NewRequest(http.MethodGet, "/", nil),
+					httptest.NewRequest(http.MethodGet, "/", nil),
+				}
+			},
+			statuses: []int{http.StatusOK, http.StatusOK},
+		},
+		{
+			name: "client ip",
+			rl: &capsule.RateLimit{
+				Enabled: true,
+				Default: &capsule.Limit{RequestsPerSecond: 1},
+			},
+			trustForwardedFor: true,
+			requests: func() []*http.Request {
+				r1 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r1.RemoteAddr = "10.0.0.1:1234"
+				r2 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r2.RemoteAddr = "10.0.0.2:1234"
+				r3 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r3.RemoteAddr = "10.0.0.3:1234"
+				r3.Header.Set("X-Forwarded-For", "10.0.0.9, 10.0.0.1")
+				return []*http.Request{r1, r2, r3}
+			},
+			statuses: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name: "forwarded for not trusted",
+			rl: &capsule.RateLimit{
+				Enabled: true,
+				Default: &capsule.Limit{RequestsPerSecond: 1},
+			},
+			requests: func() []*http.Request {
+				r1 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r1.RemoteAddr = "10.0.0.1:1234"
+				r1.Header.Set("X-Forwarded-For", "10.0.0.8")
+				r2 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r2.RemoteAddr = "10.0.0.1:1234"
+				r2.Header.Set("X-Forwarded-For", "10.0.0.9")
+				return []*http.Request{r1, r2}
+			},
+			statuses: []int{http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name: "subject",
+			rl: &capsule.RateLimit{
+				Enabled: true,
+				Key:     capsule.RateLimit_KEY_SUBJECT,
+				Default: &capsule.Limit{RequestsPerSecond: 1},
+			},
+			requests: func() []*http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/", nil)
+				ctx := context.WithValue(r.Context(), subjectKey{}, "user-1")
+				return []*http.Request{
+					r.WithContext(ctx),
+					httptest.NewRequest(http.MethodGet, "/", nil),
+					r.WithContext(ctx),
+				}
+			},
+			statuses: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+		{
+			name: "header",
+			rl: &capsule.RateLimit{
+				Enabled: true,
+				Key:     capsule.RateLimit_KEY_HEADER,
+				Header:  "X-Api-Key",
+				Default: &capsule.Limit{RequestsPerSecond: 1},
+			},
+			requests: func() []*http.Request {
+				r1 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r1.Header.Set("X-Api-Key", "a")
+				r2 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r2.Header.Set("X-Api-Key", "b")
+				r3 := httptest.NewRequest(http.MethodGet, "/", nil)
+				r3.Header.Set("X-Api-Key", "a")
+				return []*http.Request{r1, r2, r3}
+			},
+			statuses: []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			m := newRateLimitMiddleware(tt.rl, 8080, tt.trustForwardedFor, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), zap.NewNop())
+			m.now = func() time.Time { return now }
+
+			var statuses []int
+			for _, r := range tt.requests() {
+				w := httptest.NewRecorder()
+				m.ServeHTTP(w, r)
+				statuses = append(statuses, w.Code)
+			}
+			assert.Equal(t, tt.statuses, statuses)
+		})
+	}
+}
+
+func Test_RateLimitMiddleware_RetryAfter(t *testing.T) {
+	now := time.Now()
+	m := newRateLimitMiddleware(&capsule.RateLimit{
+		Enabled: true,
+		Default: &capsule.Limit{RequestsPerSecond: 0.25},
+	}, 8081, false, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), zap.NewNop())
+	m.now = func() time.Time { return now }
+
+	w := httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "4", w.Header().Get("Retry-After"))
+	assert.Equal(t, float64(1), testutil.ToFloat64(rateLimitedRequests.WithLabelValues("8081", "default")))
+
+	// The rejected request doesn't use a token.
+	now = now.Add(4 * time.Second)
+	w = httptest.NewRecorder()
+	m.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Idle buckets are removed.
+	now = now.Add(rateLimitIdleTimeout + time.Second)
+	m.cleanup()
+	assert.Empty(t, m.buckets)
+}
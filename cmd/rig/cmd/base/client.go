@@ -2,18 +2,23 @@ package base
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/bufbuild/connect-go"
 	"github.com/golang-jwt/jwt"
+	"github.com/rigdev/rig-go-api/api/v1/capsule/capsuleconnect"
 	"github.com/rigdev/rig-go-api/api/v1/project"
 	"github.com/rigdev/rig-go-sdk"
 	"github.com/rigdev/rig/cmd/rig/cmd/cmd_config"
 	"github.com/rigdev/rig/pkg/uuid"
 	"go.uber.org/fx"
+	"golang.org/x/net/http2"
 )
 
 const (
@@ -45,6 +50,58 @@ var clientModule = fx.Module("client",
 	}),
 )
 
+// NewBidiCapsuleClient returns a capsule client for the bidirectional streams,
+// which require HTTP/2 also for plain-text servers. Unlike the client of the
+// SDK, it doesn't refresh the access token, so it should be used after a call
+// with the client of the SDK.
+func NewBidiCapsuleClient(s *cmd_config.Service, cfg *cmd_config.Config, nc rig.Client) capsuleconnect.ServiceClient {
+	t := &http2.Transport{}
+	if strings.HasPrefix(s.Server, "http://") {
+		t.AllowHTTP = true
+		t.DialTLSContext = func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		}
+	}
+
+	return capsuleconnect.NewServiceClient(
+		&http.Client{Transport: t},
+		s.Server,
+		connect.WithInterceptors(
+			&userAgentInterceptor{},
+			&authInterceptor{cfg: cfg, nc: nc},
+			&bearerInterceptor{cfg: cfg},
+		),
+	)
+}
+
+type bearerInterceptor struct {
+	cfg *cmd_config.Config
+}
+
+func (i *bearerInterceptor) setBearer(h http.Header) {
+	h.Set("Authorization", "Bearer "+i.cfg.GetCurrentAuth().AccessToken)
+}
+
+func (i *bearerInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, ar connect.AnyRequest) (connect.AnyResponse, error) {
+		i.setBearer(ar.Header())
+		return next(ctx, ar)
+	}
+}
+
+func (i *bearerInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return func(ctx context.Context, s connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, s)
+		i.setBearer(conn.RequestHeader())
+		return conn
+	}
+}
+
+func (i *bearerInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
 type userAgentInterceptor struct{}
 
 func (i *userAgentInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
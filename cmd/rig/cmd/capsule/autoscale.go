@@ -0,0 +1,49 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func CapsuleAutoscale(ctx context.Context, cmd *cobra.Command, args []string, capsuleID CapsuleID, nc rig.Client) error {
+	a := &capsule.Autoscaler{}
+	if !disableAutoscaler {
+		if minReplicas < 1 || maxReplicas < minReplicas {
+			return errors.InvalidArgumentErrorf("--min must be at least 1 and --max at least --min")
+		}
+		if cpuTarget < 0 || memoryTarget < 0 || cpuTarget+memoryTarget == 0 {
+			return errors.InvalidArgumentErrorf("one of --cpu or --memory is required")
+		}
+
+		a = &capsule.Autoscaler{
+			MinReplicas:  uint32(minReplicas),
+			MaxReplicas:  uint32(maxReplicas),
+			CpuTarget:    uint32(cpuTarget),
+			MemoryTarget: uint32(memoryTarget),
+		}
+	}
+
+	if _, err := nc.Capsule().Deploy(ctx, &connect.Request[capsule.DeployRequest]{
+		Msg: &capsule.DeployRequest{
+			CapsuleId: capsuleID,
+			Changes: []*capsule.Change{{
+				Field: &capsule.Change_Autoscaler{Autoscaler: a},
+			}},
+		},
+	}); err != nil {
+		return err
+	}
+
+	if disableAutoscaler {
+		cmd.Println("Capsule autoscaling disabled")
+	} else {
+		cmd.Printf("Capsule autoscaled between %d and %d replicas\n", minReplicas, maxReplicas)
+	}
+
+	return nil
+}
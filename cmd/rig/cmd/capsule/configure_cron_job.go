@@ -0,0 +1,60 @@
+package capsule
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func CapsuleConfigureCronJob(ctx context.Context, cmd *cobra.Command, args []string, capsuleID CapsuleID, nc rig.Client) error {
+	cj := &capsule.CronJob{}
+	if !disableCronJob {
+		if cronSchedule == "" {
+			return errors.InvalidArgumentErrorf("--schedule is required")
+		}
+
+		cj.Schedule = cronSchedule
+		if historyLimit > 0 {
+			cj.HistoryLimit = uint32(historyLimit)
+		}
+		if jobTimeout > 0 {
+			cj.Timeout = durationpb.New(jobTimeout)
+		}
+
+		switch strings.ToLower(concurrencyPolicy) {
+		case "", "allow":
+			cj.ConcurrencyPolicy = capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_ALLOW
+		case "forbid":
+			cj.ConcurrencyPolicy = capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_FORBID
+		case "replace":
+			cj.ConcurrencyPolicy = capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_REPLACE
+		default:
+			return errors.InvalidArgumentErrorf("invalid concurrency policy '%s', must be allow, forbid or replace", concurrencyPolicy)
+		}
+	}
+
+	if _, err := nc.Capsule().Deploy(ctx, &connect.Request[capsule.DeployRequest]{
+		Msg: &capsule.DeployRequest{
+			CapsuleId: capsuleID,
+			Changes: []*capsule.Change{{
+				Field: &capsule.Change_CronJob{CronJob: cj},
+			}},
+		},
+	}); err != nil {
+		return err
+	}
+
+	if disableCronJob {
+		cmd.Println("Capsule runs as long-running instances")
+	} else {
+		cmd.Println("Capsule runs on the schedule", cronSchedule)
+	}
+
+	return nil
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bufbuild/connect-go"
@@ -24,6 +26,8 @@ import (
 	"github.com/rigdev/rig/pkg/utils"
 	"github.com/spf13/cobra"
 	"golang.org/x/exp/slices"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 type imageInfo struct {
@@ -33,7 +37,9 @@ type imageInfo struct {
 
 func CapsuleDeploy(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID, args []string, rc rig.Client) error {
 	var err error
-	if buildID == "" {
+	// A dry-run must not create a build, so without a build id only the other
+	// changes are previewed.
+	if buildID == "" && !dryRun {
 		dc, err := getDockerClient()
 		if err != nil {
 			return err
@@ -44,69 +50,243 @@ func CapsuleDeploy(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID,
 		}
 	}
 
+	var changes []*capsule.Change
+	if buildID != "" {
+		changes = append(changes, &capsule.Change{
+			Field: &capsule.Change_BuildId{BuildId: buildID},
+		})
+	}
+	if canary != "" {
+		strategy, err := parseCanary(canary)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, &capsule.Change{
+			Field: &capsule.Change_Strategy{Strategy: strategy},
+		})
+	}
+	if observeDeadline >= 0 {
+		changes = append(changes, &capsule.Change{
+			Field: &capsule.Change_ObserveDeadline{ObserveDeadline: durationpb.New(observeDeadline)},
+		})
+	}
+	if cmd.Flags().Changed("pre-deploy-hook") {
+		changes = append(changes, &capsule.Change{
+			Field: &capsule.Change_PreDeployHook{PreDeployHook: parseHook(preDeployHook)},
+		})
+	}
+	if cmd.Flags().Changed("post-deploy-hook") {
+		changes = append(changes, &capsule.Change{
+			Field: &capsule.Change_PostDeployHook{PostDeployHook: parseHook(postDeployHook)},
+		})
+	}
+
+	req := &capsule.DeployRequest{
+		CapsuleId:            capsuleID,
+		Changes:              changes,
+		DryRun:               dryRun,
+		OverrideDeployWindow: overrideDeployWindow,
+		Queue:                queue,
+	}
+	if scheduleAt != "" {
+		t, err := time.Parse(time.RFC3339, scheduleAt)
+		if err != nil {
+			return errors.InvalidArgumentErrorf("invalid schedule time '%s', must be RFC3339", scheduleAt)
+		}
+		req.ScheduleAt = timestamppb.New(t)
+	}
+
 	res, err := rc.Capsule().Deploy(ctx, &connect.Request[capsule.DeployRequest]{
-		Msg: &capsule.DeployRequest{
-			CapsuleId: capsuleID,
-			Changes: []*capsule.Change{{
-				Field: &capsule.Change_BuildId{BuildId: buildID},
-			}},
-		},
+		Msg: req,
 	})
 	if err != nil {
 		return err
 	}
+
+	if dryRun {
+		return printDryRun(ctx, cmd, capsuleID, rc, res.Msg)
+	}
+
+	if req.GetScheduleAt() != nil {
+		cmd.Printf("Rollout %v scheduled for %v\n", res.Msg.GetRolloutId(), req.GetScheduleAt().AsTime().Format(time.RFC3339))
+		return nil
+	}
+
 	cmd.Printf("Deploying build %v in rollout %v \n", buildID, res.Msg.GetRolloutId())
 	return listenForEvents(ctx, res.Msg.GetRolloutId(), rc, capsuleID, cmd)
 }
 
-func listenForEvents(ctx context.Context, rolloutID uint64, rc rig.Client, capsuleID string, cmd *cobra.Command) error {
-	eventCount := 0
+func printDryRun(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID, rc rig.Client, res *capsule.DeployResponse) error {
+	current := &capsule.RolloutConfig{}
+	currentName := "current rollout"
+	r, err := startedRollout(ctx, capsuleID, rc)
+	if err != nil {
+		return err
+	}
+	if r != nil {
+		current = r.GetConfig()
+		currentName = fmt.Sprintf("rollout #%d (current)", r.GetRolloutId())
+	}
+
+	diff, err := common.ProtoDiff(
+		rolloutConfigForDiff(current),
+		rolloutConfigForDiff(res.GetResolvedConfig()),
+		currentName,
+		"new rollout",
+	)
+	if err != nil {
+		return err
+	}
+
+	if diff == "" {
+		cmd.Println("No changes compared to the current rollout")
+	} else {
+		cmd.Println(diff)
+	}
+
+	for _, o := range res.GetKubernetesObjects() {
+		cmd.Printf("---\n%s", o)
+	}
+
+	return nil
+}
+
+// startedRollout returns the newest rollout that has started, which the new
+// rollout is based on. Rollouts that are queued or scheduled for later are
+// skipped. Returns nil if no rollout has started.
+func startedRollout(ctx context.Context, capsuleID CapsuleID, rc rig.Client) (*capsule.Rollout, error) {
+	p := &model.Pagination{
+		Limit:      10,
+		Descending: true,
+	}
 	for {
-		res, err := rc.Capsule().GetRollout(ctx, &connect.Request[capsule.GetRolloutRequest]{
-			Msg: &capsule.GetRolloutRequest{
-				CapsuleId: capsuleID,
-				RolloutId: rolloutID,
+		res, err := rc.Capsule().ListRollouts(ctx, &connect.Request[capsule.ListRolloutsRequest]{
+			Msg: &capsule.ListRolloutsRequest{
+				CapsuleId:  capsuleID,
+				Pagination: p,
 			},
 		})
 		if err != nil {
-			return err
+			return nil, err
 		}
 
-		eventRes, err := rc.Capsule().ListEvents(ctx, &connect.Request[capsule.ListEventsRequest]{
-			Msg: &capsule.ListEventsRequest{
-				CapsuleId: capsuleID,
-				RolloutId: rolloutID,
-				Pagination: &model.Pagination{
-					Offset: uint32(eventCount),
-				},
-			},
-		})
+		for _, r := range res.Msg.GetRollouts() {
+			switch r.GetStatus().GetState() {
+			case capsule.RolloutState_ROLLOUT_STATE_QUEUED:
+				continue
+			case capsule.RolloutState_ROLLOUT_STATE_PENDING:
+				if r.GetStatus().GetScheduledAt().AsTime().After(time.Now()) {
+					continue
+				}
+			}
+			return r, nil
+		}
+
+		p.Offset += p.Limit
+		if len(res.Msg.GetRollouts()) < int(p.Limit) || uint64(p.Offset) >= res.Msg.GetTotal() {
+			return nil, nil
+		}
+	}
+}
+
+// parseHook splits a hook command on whitespace. An empty command gives an
+// empty hook, which removes the hook.
+func parseHook(s string) *capsule.Hook {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return &capsule.Hook{}
+	}
+
+	return &capsule.Hook{
+		Command: fields[0],
+		Args:    fields[1:],
+	}
+}
+
+// parseCanary parses canary steps on the form `10:5m,50:10m,80`. A step
+// without a duration pauses the rollout until it is promoted.
+func parseCanary(s string) (*capsule.RolloutStrategy, error) {
+	canary := &capsule.RolloutStrategy_Canary{}
+	for _, step := range strings.Split(s, ",") {
+		weight, observe, hasObserve := strings.Cut(strings.TrimSpace(step), ":")
+		w, err := strconv.ParseUint(strings.TrimSuffix(weight, "%"), 10, 32)
 		if err != nil {
-			return err
+			return nil, errors.InvalidArgumentErrorf("invalid canary weight '%s'", weight)
 		}
-		for _, event := range eventRes.Msg.GetEvents() {
+
+		cs := &capsule.CanaryStep{Weight: uint32(w)}
+		if hasObserve {
+			d, err := time.ParseDuration(observe)
+			if err != nil {
+				return nil, errors.InvalidArgumentErrorf("invalid canary duration '%s'", observe)
+			}
+			cs.Observe = durationpb.New(d)
+		}
+		canary.Steps = append(canary.Steps, cs)
+	}
+
+	return &capsule.RolloutStrategy{
+		Kind: &capsule.RolloutStrategy_Canary_{Canary: canary},
+	}, nil
+}
+
+func listenForEvents(ctx context.Context, rolloutID uint64, rc rig.Client, capsuleID string, cmd *cobra.Command) error {
+	stream, err := rc.Capsule().WatchRollout(ctx, &connect.Request[capsule.WatchRolloutRequest]{
+		Msg: &capsule.WatchRolloutRequest{
+			CapsuleId: capsuleID,
+			RolloutId: rolloutID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	cmd.Println("Deploying build...")
+
+	var status *capsule.RolloutStatus
+	paused := false
+	queued := false
+	for stream.Receive() {
+		if event := stream.Msg().GetEvent(); event != nil {
 			cmd.Printf("[%v] %v\n", event.GetCreatedAt().AsTime().Format(base.RFC3339MilliFixed), event.GetMessage())
+			continue
 		}
-		eventCount += len(eventRes.Msg.GetEvents())
-
-		switch res.Msg.GetRollout().GetStatus().GetState() {
-		case capsule.RolloutState_ROLLOUT_STATE_DONE:
-			cmd.Println("Deployment complete")
-			return nil
-		case capsule.RolloutState_ROLLOUT_STATE_FAILED:
-			cmd.Println("Deployment failed")
-			return nil
-		case capsule.RolloutState_ROLLOUT_STATE_ABORTED:
-			cmd.Println("Deployment aborted")
-			return nil
+
+		status = stream.Msg().GetRollout().GetStatus()
+		if cs := status.GetCanary(); cs.GetPaused() != paused {
+			paused = cs.GetPaused()
+			if paused {
+				cmd.Printf("Rollout paused at %d%%, run `rig capsule promote` to continue\n", cs.GetWeight())
+			}
 		}
 
-		if len(eventRes.Msg.GetEvents()) == 0 {
-			cmd.Println("Deploying build...")
+		if (status.GetState() == capsule.RolloutState_ROLLOUT_STATE_QUEUED) != queued {
+			queued = !queued
+			if queued {
+				cmd.Printf("Rollout queued at position %d, waiting for the rollout in progress\n", status.GetQueuePosition())
+			}
 		}
+	}
+	if err := stream.Err(); err != nil {
+		return err
+	}
 
-		time.Sleep(1 * time.Second)
+	// The stream ends once the rollout has terminated.
+	switch status.GetState() {
+	case capsule.RolloutState_ROLLOUT_STATE_DONE:
+		cmd.Println("Deployment complete")
+	case capsule.RolloutState_ROLLOUT_STATE_FAILED:
+		cmd.Println("Deployment failed")
+		if id := status.GetRollbackRolloutId(); id != 0 {
+			cmd.Printf("Rolling back in rollout %v\n", id)
+			return listenForEvents(ctx, id, rc, capsuleID, cmd)
+		}
+	case capsule.RolloutState_ROLLOUT_STATE_ABORTED:
+		cmd.Println("Deployment aborted")
 	}
+
+	return nil
 }
 
 // TODO Should be supplied by FX instead
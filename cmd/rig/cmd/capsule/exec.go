@@ -0,0 +1,160 @@
+package capsule
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/rigdev/rig/cmd/rig/cmd/base"
+	"github.com/rigdev/rig/cmd/rig/cmd/cmd_config"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// execResizeInterval is how often the size of the terminal is checked. The
+// size is polled, as there is no portable signal for it.
+const execResizeInterval = 250 * time.Millisecond
+
+func CapsuleExec(ctx context.Context, cmd *cobra.Command, args []string, capsuleID CapsuleID, nc rig.Client, s *cmd_config.Service, cfg *cmd_config.Config) error {
+	var arg string
+	if len(args) > 1 {
+		arg = args[1]
+	}
+
+	instanceID, err := provideInstanceID(ctx, nc, capsuleID, arg)
+	if err != nil {
+		return err
+	}
+
+	code, err := execInstance(ctx, base.NewBidiCapsuleClient(s, cfg, nc).Exec(ctx), &capsule.ExecRequest_Start{
+		CapsuleId:   capsuleID,
+		InstanceId:  instanceID,
+		Command:     execCommand[0],
+		Args:        execCommand[1:],
+		Interactive: interactive,
+		Tty:         tty,
+	})
+	if err != nil {
+		return err
+	}
+
+	if code != 0 {
+		os.Exit(int(code))
+	}
+
+	return nil
+}
+
+// execInstance runs the command, with the terminal in raw mode for a TTY, and
+// returns its exit code.
+func execInstance(ctx context.Context, stream *connect.BidiStreamForClient[capsule.ExecRequest, capsule.ExecResponse], start *capsule.ExecRequest_Start) (int32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	es := &execSender{stream: stream}
+	if err := es.send(&capsule.ExecRequest{
+		Request: &capsule.ExecRequest_Start_{Start: start},
+	}); err != nil {
+		return 0, err
+	}
+
+	fd := int(os.Stdin.Fd())
+	if start.GetTty() && term.IsTerminal(fd) {
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return 0, err
+		}
+		defer term.Restore(fd, state)
+
+		go es.resize(ctx, fd)
+	}
+
+	if start.GetInteractive() {
+		go es.stdin()
+	}
+
+	for {
+		res, err := stream.Receive()
+		if err != nil {
+			return 0, err
+		}
+
+		switch v := res.GetResponse().(type) {
+		case *capsule.ExecResponse_Stdout:
+			if _, err := os.Stdout.Write(v.Stdout); err != nil {
+				return 0, err
+			}
+		case *capsule.ExecResponse_Stderr:
+			if _, err := os.Stderr.Write(v.Stderr); err != nil {
+				return 0, err
+			}
+		case *capsule.ExecResponse_ExitCode:
+			return v.ExitCode, nil
+		}
+	}
+}
+
+// execSender sends the requests of an exec stream, which can't be sent on
+// concurrently.
+type execSender struct {
+	mu     sync.Mutex
+	stream *connect.BidiStreamForClient[capsule.ExecRequest, capsule.ExecResponse]
+}
+
+func (s *execSender) send(req *capsule.ExecRequest) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream.Send(req)
+}
+
+// stdin sends the input of the command until stdin is closed.
+func (s *execSender) stdin() {
+	buf := make([]byte, 4096)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			if err := s.send(&capsule.ExecRequest{
+				Request: &capsule.ExecRequest_Stdin{Stdin: buf[:n]},
+			}); err != nil {
+				return
+			}
+		}
+		if err != nil {
+			s.send(&capsule.ExecRequest{
+				Request: &capsule.ExecRequest_CloseStdin{CloseStdin: true},
+			})
+			return
+		}
+	}
+}
+
+// resize sends the size of the terminal whenever it changes.
+func (s *execSender) resize(ctx context.Context, fd int) {
+	t := time.NewTicker(execResizeInterval)
+	defer t.Stop()
+
+	var width, height int
+	for {
+		if w, h, err := term.GetSize(fd); err == nil && (w != width || h != height) {
+			width, height = w, h
+			if err := s.send(&capsule.ExecRequest{
+				Request: &capsule.ExecRequest_Resize_{Resize: &capsule.ExecRequest_Resize{
+					Height: uint32(height),
+					Width:  uint32(width),
+				}},
+			}); err != nil {
+				return
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
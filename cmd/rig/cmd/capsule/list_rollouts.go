@@ -41,7 +41,9 @@ func CapsuleListRollouts(ctx context.Context, cmd *cobra.Command, capsuleID Caps
 	t.AppendHeader(table.Row{fmt.Sprintf("Rollouts (%d)", resp.Msg.GetTotal()), "Deployed At", "Replicas", "State", "Created By"})
 	for i, r := range resp.Msg.GetRollouts() {
 		id := fmt.Sprint("#", r.GetRolloutId())
-		if i == 0 {
+		if pos := r.GetStatus().GetQueuePosition(); pos > 0 {
+			id = fmt.Sprint(id, " (queued #", pos, ")")
+		} else if i == 0 {
 			id = fmt.Sprint(id, " (current)")
 		}
 
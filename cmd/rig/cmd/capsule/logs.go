@@ -23,6 +23,7 @@ func CapsuleLogs(ctx context.Context, cmd *cobra.Command, args []string, capsule
 			CapsuleId:  capsuleID,
 			InstanceId: instanceID,
 			Follow:     follow,
+			AccessLogs: accessLogs,
 		},
 	})
 	if err != nil {
@@ -30,23 +31,37 @@ func CapsuleLogs(ctx context.Context, cmd *cobra.Command, args []string, capsule
 	}
 
 	for s.Receive() {
-		switch v := s.Msg().GetLog().GetMessage().GetMessage().(type) {
-		case *capsule.LogMessage_Stdout:
-			os.Stdout.WriteString(s.Msg().GetLog().GetTimestamp().AsTime().Format(base.RFC3339NanoFixed))
-			os.Stdout.WriteString(": ")
-			if _, err := os.Stdout.Write(v.Stdout); err != nil {
-				return err
-			}
-		case *capsule.LogMessage_Stderr:
-			os.Stderr.WriteString(s.Msg().GetLog().GetTimestamp().AsTime().Format(base.RFC3339NanoFixed))
-			os.Stderr.WriteString(": ")
-			if _, err := os.Stderr.Write(v.Stderr); err != nil {
-				return err
-			}
-		default:
-			return errors.InvalidArgumentErrorf("invalid log message")
+		if err := printLog(s.Msg().GetLog()); err != nil {
+			return err
 		}
 	}
 
 	return s.Err()
 }
+
+func printLog(l *capsule.Log) error {
+	switch v := l.GetMessage().GetMessage().(type) {
+	case *capsule.LogMessage_Stdout:
+		os.Stdout.WriteString(l.GetTimestamp().AsTime().Format(base.RFC3339NanoFixed))
+		os.Stdout.WriteString(": ")
+		if _, err := os.Stdout.Write(v.Stdout); err != nil {
+			return err
+		}
+	case *capsule.LogMessage_Stderr:
+		os.Stderr.WriteString(l.GetTimestamp().AsTime().Format(base.RFC3339NanoFixed))
+		os.Stderr.WriteString(": ")
+		if _, err := os.Stderr.Write(v.Stderr); err != nil {
+			return err
+		}
+	case *capsule.LogMessage_AccessLog:
+		os.Stdout.WriteString(l.GetTimestamp().AsTime().Format(base.RFC3339NanoFixed))
+		os.Stdout.WriteString(": ")
+		if _, err := os.Stdout.Write(v.AccessLog); err != nil {
+			return err
+		}
+	default:
+		return errors.InvalidArgumentErrorf("invalid log message")
+	}
+
+	return nil
+}
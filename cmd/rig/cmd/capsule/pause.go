@@ -0,0 +1,34 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/spf13/cobra"
+)
+
+func CapsulePause(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID, nc rig.Client) error {
+	c, err := nc.Capsule().Get(ctx, &connect.Request[capsule.GetRequest]{
+		Msg: &capsule.GetRequest{
+			CapsuleId: capsuleID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := nc.Capsule().PauseRollout(ctx, &connect.Request[capsule.PauseRolloutRequest]{
+		Msg: &capsule.PauseRolloutRequest{
+			CapsuleId: capsuleID,
+			RolloutId: c.Msg.GetCapsule().GetCurrentRollout(),
+		},
+	}); err != nil {
+		return err
+	}
+
+	cmd.Println("Rollout paused")
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/api/v1/capsule/capsuleconnect"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/rigdev/rig/cmd/rig/cmd/base"
+	"github.com/rigdev/rig/cmd/rig/cmd/cmd_config"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func CapsulePortForward(ctx context.Context, cmd *cobra.Command, args []string, capsuleID CapsuleID, nc rig.Client, s *cmd_config.Service, cfg *cmd_config.Config) error {
+	localPort, start, err := parsePortForwardTarget(portForwardTarget)
+	if err != nil {
+		return err
+	}
+
+	var arg string
+	if len(args) > 1 {
+		arg = args[1]
+	}
+
+	instanceID, err := provideInstanceID(ctx, nc, capsuleID, arg)
+	if err != nil {
+		return err
+	}
+
+	start.CapsuleId = capsuleID
+	start.InstanceId = instanceID
+
+	l, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", localPort))
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	cmd.Printf("Forwarding from %s to instance %s\n", l.Addr(), instanceID)
+
+	client := base.NewBidiCapsuleClient(s, cfg, nc)
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			if err := forwardConn(ctx, client, start, conn); err != nil {
+				fmt.Fprintln(os.Stderr, "connection closed:", err)
+			}
+		}()
+	}
+}
+
+// parsePortForwardTarget parses '[local-port:]<port|interface>'.
+func parsePortForwardTarget(target string) (uint16, *capsule.PortForwardRequest_Start, error) {
+	local, remote, found := strings.Cut(target, ":")
+	if !found {
+		local, remote = "", target
+	}
+
+	if remote == "" {
+		return 0, nil, errors.InvalidArgumentErrorf("missing port to forward to")
+	}
+
+	start := &capsule.PortForwardRequest_Start{}
+	var localPort uint16
+	if p, err := strconv.ParseUint(remote, 10, 16); err == nil {
+		if p == 0 {
+			return 0, nil, errors.InvalidArgumentErrorf("invalid port '%s'", remote)
+		}
+		start.Target = &capsule.PortForwardRequest_Start_Port{Port: uint32(p)}
+		localPort = uint16(p)
+	} else {
+		start.Target = &capsule.PortForwardRequest_Start_Interface{Interface: remote}
+	}
+
+	if found && local != "" {
+		p, err := strconv.ParseUint(local, 10, 16)
+		if err != nil {
+			return 0, nil, errors.InvalidArgumentErrorf("invalid local port '%s'", local)
+		}
+		localPort = uint16(p)
+	}
+
+	return localPort, start, nil
+}
+
+// forwardConn tunnels the local connection through a new stream.
+func forwardConn(ctx context.Context, client capsuleconnect.ServiceClient, start *capsule.PortForwardRequest_Start, conn net.Conn) error {
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream := client.PortForward(ctx)
+	if err := stream.Send(&capsule.PortForwardRequest{
+		Request: &capsule.PortForwardRequest_Start_{Start: start},
+	}); err != nil {
+		return err
+	}
+
+	go func() {
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buf)
+			if n > 0 {
+				if err := stream.Send(&capsule.PortForwardRequest{
+					Request: &capsule.PortForwardRequest_Data{Data: buf[:n]},
+				}); err != nil {
+					cancel()
+					return
+				}
+			}
+
+			if err != nil {
+				stream.CloseRequest()
+				return
+			}
+		}
+	}()
+
+	for {
+		res, err := stream.Receive()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if _, err := conn.Write(res.GetData()); err != nil {
+			return err
+		}
+	}
+}
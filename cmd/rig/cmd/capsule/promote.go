@@ -0,0 +1,35 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/spf13/cobra"
+)
+
+func CapsulePromote(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID, nc rig.Client) error {
+	c, err := nc.Capsule().Get(ctx, &connect.Request[capsule.GetRequest]{
+		Msg: &capsule.GetRequest{
+			CapsuleId: capsuleID,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := nc.Capsule().PromoteRollout(ctx, &connect.Request[capsule.PromoteRolloutRequest]{
+		Msg: &capsule.PromoteRolloutRequest{
+			CapsuleId: capsuleID,
+			RolloutId: c.Msg.GetCapsule().GetCurrentRollout(),
+			Full:      full,
+		},
+	}); err != nil {
+		return err
+	}
+
+	cmd.Println("Rollout promoted")
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/proto"
+)
+
+func CapsuleRollback(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID, args []string, nc rig.Client) error {
+	res, err := nc.Capsule().ListRollouts(ctx, &connect.Request[capsule.ListRolloutsRequest]{
+		Msg: &capsule.ListRolloutsRequest{
+			CapsuleId: capsuleID,
+			Pagination: &model.Pagination{
+				Descending: true,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	rollouts := res.Msg.GetRollouts()
+	if len(rollouts) == 0 {
+		return errors.FailedPreconditionErrorf("capsule has no rollouts")
+	}
+	current := rollouts[0]
+
+	var target *capsule.Rollout
+	if len(args) > 1 {
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return errors.InvalidArgumentErrorf("invalid rollout id '%s'", args[1])
+		}
+
+		r, err := nc.Capsule().GetRollout(ctx, &connect.Request[capsule.GetRolloutRequest]{
+			Msg: &capsule.GetRolloutRequest{
+				CapsuleId: capsuleID,
+				RolloutId: id,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		target = r.Msg.GetRollout()
+	} else {
+		var candidates []*capsule.Rollout
+		var prompts []string
+		for _, r := range rollouts[1:] {
+			if r.GetStatus().GetState() != capsule.RolloutState_ROLLOUT_STATE_DONE {
+				continue
+			}
+
+			candidates = append(candidates, r)
+			prompts = append(prompts, fmt.Sprintf("#%d - build %s (%s)", r.GetRolloutId(), r.GetConfig().GetBuildId(), r.GetConfig().GetCreatedBy().GetPrintableName()))
+		}
+
+		if len(candidates) == 0 {
+			return errors.FailedPreconditionErrorf("no previous rollouts to roll back to")
+		}
+
+		idx, _, err := common.PromptSelect("Rollout to roll back to:", prompts)
+		if err != nil {
+			return err
+		}
+		target = candidates[idx]
+	}
+
+	diff, err := common.ProtoDiff(
+		rolloutConfigForDiff(current.GetConfig()),
+		rolloutConfigForDiff(target.GetConfig()),
+		fmt.Sprintf("rollout #%d (current)", current.GetRolloutId()),
+		fmt.Sprintf("rollout #%d", target.GetRolloutId()),
+	)
+	if err != nil {
+		return err
+	}
+
+	if diff == "" {
+		cmd.Println("No changes compared to the current rollout")
+	} else {
+		cmd.Println(diff)
+	}
+
+	ok, err := common.PromptConfirm(fmt.Sprintf("Roll back to rollout #%d?", target.GetRolloutId()), false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	rb, err := nc.Capsule().Rollback(ctx, &connect.Request[capsule.RollbackRequest]{
+		Msg: &capsule.RollbackRequest{
+			CapsuleId: capsuleID,
+			RolloutId: target.GetRolloutId(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	cmd.Printf("Rolling back to rollout %v in rollout %v \n", target.GetRolloutId(), rb.Msg.GetRolloutId())
+	return listenForEvents(ctx, rb.Msg.GetRolloutId(), nc, capsuleID, cmd)
+}
+
+// rolloutConfigForDiff strips the fields of the config that describe the
+// rollout rather than the capsule.
+func rolloutConfigForDiff(rc *capsule.RolloutConfig) *capsule.RolloutConfig {
+	rc = proto.Clone(rc).(*capsule.RolloutConfig)
+	rc.CreatedAt = nil
+	rc.CreatedBy = nil
+	rc.Changes = nil
+	rc.RollbackOf = 0
+	return rc
+}
@@ -0,0 +1,43 @@
+package capsule
+
+import (
+	"context"
+	"os"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/spf13/cobra"
+)
+
+func CapsuleRun(ctx context.Context, cmd *cobra.Command, capsuleID CapsuleID, nc rig.Client) error {
+	s, err := nc.Capsule().RunTask(ctx, &connect.Request[capsule.RunTaskRequest]{
+		Msg: &capsule.RunTaskRequest{
+			CapsuleId: capsuleID,
+			Command:   taskCommand[0],
+			Args:      taskCommand[1:],
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	for s.Receive() {
+		switch v := s.Msg().GetKind().(type) {
+		case *capsule.RunTaskResponse_InstanceId:
+			cmd.Printf("Running task in instance %s\n", v.InstanceId)
+		case *capsule.RunTaskResponse_Log:
+			if err := printLog(v.Log); err != nil {
+				return err
+			}
+		case *capsule.RunTaskResponse_ExitCode:
+			cmd.Printf("Task exited with code %d\n", v.ExitCode)
+			if v.ExitCode != 0 {
+				os.Exit(int(v.ExitCode))
+			}
+			return nil
+		}
+	}
+
+	return s.Err()
+}
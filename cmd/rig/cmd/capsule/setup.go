@@ -2,6 +2,7 @@ package capsule
 
 import (
 	"context"
+	"time"
 
 	"github.com/bufbuild/connect-go"
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
@@ -21,19 +22,62 @@ var (
 )
 
 var (
-	deploy         bool
-	follow         bool
-	interactive    bool
-	outputJSON     bool
-	skipImageCheck bool
+	observeDeadline time.Duration
 )
 
 var (
-	name        string
-	image       string
-	buildID     string
-	networkFile string
-	instanceID  string
+	minReplicas       int
+	maxReplicas       int
+	cpuTarget         int
+	memoryTarget      int
+	disableAutoscaler bool
+)
+
+var (
+	cronSchedule      string
+	concurrencyPolicy string
+	historyLimit      int
+	jobTimeout        time.Duration
+	disableCronJob    bool
+)
+
+var (
+	volumeName   string
+	volumePath   string
+	volumeSize   string
+	storageClass string
+)
+
+var (
+	deploy               bool
+	overrideDeployWindow bool
+	dryRun               bool
+	queue                bool
+	full                 bool
+	follow               bool
+	accessLogs           bool
+	interactive          bool
+	tty                  bool
+	outputJSON           bool
+	skipImageCheck       bool
+)
+
+var (
+	name           string
+	image          string
+	buildID        string
+	networkFile    string
+	instanceID     string
+	canary         string
+	scheduleAt     string
+	preDeployHook  string
+	postDeployHook string
+)
+
+var (
+	taskCommand       []string
+	execCommand       []string
+	portForwardTarget string
 )
 
 func Setup(parent *cobra.Command) {
@@ -80,6 +124,14 @@ func Setup(parent *cobra.Command) {
 		RunE:  base.Register(CapsuleDeploy),
 	}
 	deploy.Flags().StringVarP(&buildID, "build-id", "b", "", "build id to deploy")
+	deploy.Flags().StringVar(&scheduleAt, "schedule-at", "", "start the rollout at the given RFC3339 time")
+	deploy.Flags().BoolVar(&overrideDeployWindow, "override-deploy-window", false, "start the rollout even if outside the deploy windows of the project")
+	deploy.Flags().BoolVar(&queue, "queue", false, "queue the rollout behind the rollout in progress instead of failing")
+	deploy.Flags().BoolVar(&dryRun, "dry-run", false, "show the changes and cluster objects of the rollout without deploying it")
+	deploy.Flags().DurationVar(&observeDeadline, "observe-deadline", -1, "roll back if the new instances are not healthy within the duration. 0 disables the deadline for the capsule")
+	deploy.Flags().StringVar(&preDeployHook, "pre-deploy-hook", "", "command to run with the new build before deploying it, e.g. `./migrate up`. The rollout fails if it exits with a non-zero code. An empty command removes the hook")
+	deploy.Flags().StringVar(&postDeployHook, "post-deploy-hook", "", "command to run with the new build after the rollout is done. An empty command removes the hook")
+	deploy.Flags().StringVar(&canary, "canary", "", "roll out in canary steps, e.g. `10:5m,50:10m,80`. A step without a duration waits to be promoted")
 	capsule.AddCommand(deploy)
 
 	scale := &cobra.Command{
@@ -92,14 +144,95 @@ func Setup(parent *cobra.Command) {
 	scale.Flags().IntVarP(&replicas, "replicas", "r", -1, "number of replicas to scale to")
 	capsule.AddCommand(scale)
 
-	abort := &cobra.Command{
-		Use:   "abort [capsule-name]",
-		Short: "abort the current rollout. This will leave the capsule in a undefined state",
+	autoscale := &cobra.Command{
+		Use:   "autoscale [capsule-name]",
+		Short: "scale the number of replicas of the capsule with its cpu or memory utilization",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  base.Register(CapsuleAutoscale),
+	}
+
+	autoscale.Flags().IntVar(&minReplicas, "min", 1, "minimum number of replicas")
+	autoscale.Flags().IntVar(&maxReplicas, "max", 0, "maximum number of replicas")
+	autoscale.Flags().IntVar(&cpuTarget, "cpu", 0, "target average cpu utilization in percent of the requested cpu")
+	autoscale.Flags().IntVar(&memoryTarget, "memory", 0, "target average memory utilization in percent of the requested memory")
+	autoscale.Flags().BoolVar(&disableAutoscaler, "disable", false, "turn off autoscaling, going back to the replicas set by scale")
+	capsule.AddCommand(autoscale)
+
+	configureCronJob := &cobra.Command{
+		Use:   "configure-cron-job [capsule-name]",
+		Short: "run the capsule to completion on a schedule instead of as long-running instances",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  base.Register(CapsuleConfigureCronJob),
+	}
+
+	configureCronJob.Flags().StringVar(&cronSchedule, "schedule", "", "cron expression of when to start a run, in UTC, e.g. `0 3 * * *`")
+	configureCronJob.Flags().StringVar(&concurrencyPolicy, "concurrency-policy", "allow", "what to do if the previous run is still going: allow, forbid or replace")
+	configureCronJob.Flags().IntVar(&historyLimit, "history-limit", 0, "number of finished runs to keep. Defaults to 3")
+	configureCronJob.Flags().DurationVar(&jobTimeout, "timeout", 0, "stop runs going for longer than the duration")
+	configureCronJob.Flags().BoolVar(&disableCronJob, "disable", false, "turn the capsule back into long-running instances")
+	capsule.AddCommand(configureCronJob)
+
+	setVolume := &cobra.Command{
+		Use:   "set-volume [capsule-name]",
+		Short: "mount a persistent volume into each instance of the capsule",
+		Long: `Mount a persistent volume into each instance of the capsule, or change where
+an existing volume is mounted. Every instance gets its own volume, which keeps
+its data when the instance is restarted or removed. The size and storage class
+of an existing volume cannot be changed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: base.Register(CapsuleSetVolume),
+	}
+
+	setVolume.Flags().StringVar(&volumeName, "name", "", "name of the volume")
+	setVolume.Flags().StringVar(&volumePath, "path", "", "absolute path in the container where the volume is mounted")
+	setVolume.Flags().StringVar(&volumeSize, "size", "", "size of the volume, e.g. 10Gi")
+	setVolume.Flags().StringVar(&storageClass, "storage-class", "", "storage class of the volume. Uses the default storage class if empty")
+	capsule.AddCommand(setVolume)
+
+	removeVolume := &cobra.Command{
+		Use:   "remove-volume [capsule-name]",
+		Short: "unmount a persistent volume from the instances of the capsule. The data of the volume is kept",
 		Args:  cobra.MaximumNArgs(1),
-		RunE:  base.Register(CapsuleAbort),
+		RunE:  base.Register(CapsuleRemoveVolume),
+	}
+
+	removeVolume.Flags().StringVar(&volumeName, "name", "", "name of the volume")
+	capsule.AddCommand(removeVolume)
+
+	abort := &cobra.Command{
+		Use:     "abort [capsule-name]",
+		Aliases: []string{"cancel"},
+		Short:   "abort the current rollout, or cancel it if still pending. Aborting a started rollout will leave the capsule in a undefined state",
+		Args:    cobra.MaximumNArgs(1),
+		RunE:    base.Register(CapsuleAbort),
 	}
 	capsule.AddCommand(abort)
 
+	rollback := &cobra.Command{
+		Use:   "rollback [capsule-name] [rollout-id]",
+		Short: "roll back to a previous rollout, showing the changes before confirming",
+		Args:  cobra.MaximumNArgs(2),
+		RunE:  base.Register(CapsuleRollback),
+	}
+	capsule.AddCommand(rollback)
+
+	pause := &cobra.Command{
+		Use:   "pause [capsule-name]",
+		Short: "pause the current canary rollout at its current step",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  base.Register(CapsulePause),
+	}
+	capsule.AddCommand(pause)
+
+	promote := &cobra.Command{
+		Use:   "promote [capsule-name]",
+		Short: "promote the current canary rollout to its next step",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  base.Register(CapsulePromote),
+	}
+	promote.Flags().BoolVar(&full, "full", false, "skip the remaining canary steps and roll out to all instances")
+	capsule.AddCommand(promote)
+
 	configureNetwork := &cobra.Command{
 		Use:   "configure-network [capsule-name]",
 		Short: "configure the network of the capsule",
@@ -179,8 +312,72 @@ func Setup(parent *cobra.Command) {
 	}
 	logs.Flags().StringVarP(&instanceID, "instance-id", "i", "", "instance id to restart")
 	logs.Flags().BoolVarP(&follow, "follow", "f", false, "keep the connection open and read out logs as they are produced")
+	logs.Flags().BoolVar(&accessLogs, "access-logs", false, "read the access log of the requests to the instance, if logging is enabled for an interface")
 	capsule.AddCommand(logs)
 
+	run := &cobra.Command{
+		Use:   "run [capsule-name] -- <command> [args...]",
+		Short: "Run a command in a new instance with the config of the current rollout",
+		Long: `Run a command to completion in a new instance of the capsule, with the
+image, environment variables and config files of the current rollout. The logs
+of the instance are printed, and the command exits with the exit code of the
+task. The instance is removed afterwards. Requires the capsule-exec permission.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return errors.InvalidArgumentErrorf("missing command, given after '--'")
+			}
+			return cobra.MaximumNArgs(1)(cmd, args[:dash])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			taskCommand = args[dash:]
+			return base.Register(CapsuleRun)(cmd, args[:dash])
+		},
+	}
+	capsule.AddCommand(run)
+
+	exec := &cobra.Command{
+		Use:   "exec [capsule-name] [instance-id] -- <command> [args...]",
+		Short: "Execute a command in an instance of the capsule",
+		Long: `Execute a command in a running instance of the capsule, e.g. to open a shell
+with 'rig capsule exec my-capsule -it -- sh'. Requires the capsule-exec
+permission.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			if dash < 0 || dash == len(args) {
+				return errors.InvalidArgumentErrorf("missing command, given after '--'")
+			}
+			return cobra.MaximumNArgs(2)(cmd, args[:dash])
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+			execCommand = args[dash:]
+			return base.Register(CapsuleExec)(cmd, args[:dash])
+		},
+	}
+	exec.Flags().BoolVarP(&interactive, "interactive", "i", false, "pass stdin to the command")
+	exec.Flags().BoolVarP(&tty, "tty", "t", false, "run the command in a terminal")
+	capsule.AddCommand(exec)
+
+	portForward := &cobra.Command{
+		Use:   "port-forward [capsule-name] [instance-id] [local-port:]<port|interface>",
+		Short: "Forward a local port to a port of an instance of the capsule",
+		Long: `Forward connections to a local port to a port of a running instance of the
+capsule, tunneled through rig-server. The port of the instance can be given
+either as a number or as the name of an interface of the capsule, e.g.
+'rig capsule port-forward my-capsule 8080:80' or
+'rig capsule port-forward my-capsule 8080:http'. If no local port is given,
+the same port is used, or a random one for an interface. Requires the
+capsule-port-forward permission.`,
+		Args: cobra.RangeArgs(1, 3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			portForwardTarget = args[len(args)-1]
+			return base.Register(CapsulePortForward)(cmd, args[:len(args)-1])
+		},
+	}
+	capsule.AddCommand(portForward)
+
 	config := &cobra.Command{
 		Use:   "config [capsule-name]",
 		Short: "Configure a capsule",
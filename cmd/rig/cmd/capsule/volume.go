@@ -0,0 +1,77 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-sdk"
+	"github.com/rigdev/rig/cmd/common"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func CapsuleSetVolume(ctx context.Context, cmd *cobra.Command, args []string, capsuleID CapsuleID, nc rig.Client) error {
+	if volumeName == "" {
+		return errors.InvalidArgumentErrorf("--name is required")
+	}
+	if volumePath == "" {
+		return errors.InvalidArgumentErrorf("--path is required")
+	}
+	if volumeSize == "" {
+		return errors.InvalidArgumentErrorf("--size is required")
+	}
+
+	size, err := parseBytes(volumeSize)
+	if err != nil {
+		return errors.InvalidArgumentErrorf("invalid size '%s': %v", volumeSize, err)
+	}
+
+	if _, err := nc.Capsule().Deploy(ctx, &connect.Request[capsule.DeployRequest]{
+		Msg: &capsule.DeployRequest{
+			CapsuleId: capsuleID,
+			Changes: []*capsule.Change{{
+				Field: &capsule.Change_SetVolume{SetVolume: &capsule.Volume{
+					Name:         volumeName,
+					Path:         volumePath,
+					SizeBytes:    size,
+					StorageClass: storageClass,
+				}},
+			}},
+		},
+	}); err != nil {
+		return err
+	}
+
+	cmd.Printf("Volume %s mounted at %s\n", volumeName, volumePath)
+	return nil
+}
+
+func CapsuleRemoveVolume(ctx context.Context, cmd *cobra.Command, args []string, capsuleID CapsuleID, nc rig.Client) error {
+	if volumeName == "" {
+		return errors.InvalidArgumentErrorf("--name is required")
+	}
+
+	ok, err := common.PromptConfirm(fmt.Sprintf("Unmount volume %s from the instances? The data is kept, and mounted again if the volume is added back", volumeName), false)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return nil
+	}
+
+	if _, err := nc.Capsule().Deploy(ctx, &connect.Request[capsule.DeployRequest]{
+		Msg: &capsule.DeployRequest{
+			CapsuleId: capsuleID,
+			Changes: []*capsule.Change{{
+				Field: &capsule.Change_RemoveVolume{RemoveVolume: volumeName},
+			}},
+		},
+	}); err != nil {
+		return err
+	}
+
+	cmd.Printf("Volume %s unmounted\n", volumeName)
+	return nil
+}
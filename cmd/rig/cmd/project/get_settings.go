@@ -2,6 +2,8 @@ package project
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
 	"github.com/bufbuild/connect-go"
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -33,6 +35,24 @@ func ProjectGetSettings(ctx context.Context, cmd *cobra.Command, args []string,
 		dockerRegistries = append(dockerRegistries, table.Row{"", r.GetHost()})
 	}
 
+	deployWindows := []table.Row{}
+	for i, w := range set.GetDeployWindows() {
+		var days []string
+		for _, d := range w.GetDays() {
+			days = append(days, strings.TrimPrefix(d.String(), "WEEKDAY_"))
+		}
+		if len(days) == 0 {
+			days = append(days, "ALL")
+		}
+
+		window := fmt.Sprintf("%s %s-%s UTC", strings.Join(days, ","), w.GetStart(), w.GetEnd())
+		if i == 0 {
+			deployWindows = append(deployWindows, table.Row{"Deploy Windows", window})
+			continue
+		}
+		deployWindows = append(deployWindows, table.Row{"", window})
+	}
+
 	t := table.NewWriter()
 	t.AppendHeader(table.Row{"Attribute", "Value"})
 	t.AppendRows([]table.Row{
@@ -44,6 +64,7 @@ func ProjectGetSettings(ctx context.Context, cmd *cobra.Command, args []string,
 		{" - From Phone", set.GetTextProvider().GetFrom()},
 	})
 	t.AppendRows(dockerRegistries)
+	t.AppendRows(deployWindows)
 
 	cmd.Println(t.Render())
 	return nil
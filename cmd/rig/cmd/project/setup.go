@@ -56,6 +56,7 @@ func Setup(parent *cobra.Command) {
 					"  email-provder - json \n" +
 					"  add-docker-registry - json \n" +
 					"  delete-docker-registry - string \n" +
+					"  deploy-windows - json \n" +
 					"  template - json \n"),
 			)
 		},
@@ -28,6 +28,7 @@ const (
 	templateEmailWelcome
 	templateVerifyEmail
 	templateResetPasswordEmail
+	settingsDeployWindows
 )
 
 const (
@@ -87,6 +88,8 @@ func (f settingsField) String() string {
 		return "Verify Email Template"
 	case templateResetPasswordEmail:
 		return "Reset Password Email Template"
+	case settingsDeployWindows:
+		return "Deploy Windows"
 	default:
 		return "Undefined"
 	}
@@ -450,6 +453,17 @@ func parseSettingsUpdate() (*settings.Update, error) {
 				DeleteDockerRegistry: value,
 			},
 		}, nil
+	case common.FormatField(settingsDeployWindows.String()):
+		jsonValue := []byte(value)
+		ws := settings.DeployWindows{}
+		if err := protojson.Unmarshal(jsonValue, &ws); err != nil {
+			return nil, err
+		}
+		return &settings.Update{
+			Field: &settings.Update_SetDeployWindows{
+				SetDeployWindows: &ws,
+			},
+		}, nil
 	case "template":
 		jsonValue := []byte(value)
 		t := settings.Template{}
@@ -35,6 +35,10 @@ cluster:
     host: {{ .host }}
     cluster_host: {{ .cluster_host }}
   {{- end }}
+  {{- with .Values.rig.cluster.cert_manager }}
+  cert_manager:
+    cluster_issuer: {{ .cluster_issuer | quote }}
+  {{- end }}
 {{- with .Values.rig.email }}
 email:
   type: {{ .type | quote }}
@@ -0,0 +1,59 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"go.uber.org/zap"
+)
+
+func canaryPrefix(capsuleID string) string {
+	return fmt.Sprint(capsuleID, "-instance-canary-")
+}
+
+func isCanaryContainer(c types.Container) bool {
+	capsuleID := c.Labels[_rigCapsuleIDLabel]
+	return capsuleID != "" && strings.HasPrefix(containerName(c), canaryPrefix(capsuleID))
+}
+
+// UpsertCanary implements cluster.ConfigGateway. Canary instances share the
+// network alias of the capsule, which makes docker balance requests between
+// stable and canary instances.
+func (c *Client) UpsertCanary(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string) error {
+	c.logger.Debug("creating docker canary", zap.String("capsuleID", cfg.GetName()))
+
+	ic, err := c.createInstanceConfig(ctx, cfg, envs)
+	if err != nil {
+		return err
+	}
+
+	existing, err := c.getContainers(ctx, canaryPrefix(cfg.GetName()))
+	if err != nil {
+		return err
+	}
+
+	return c.upsertInstances(ctx, cfg.GetName(), canaryPrefix(cfg.GetName()), int(cfg.Spec.Replicas), ic, existing)
+}
+
+// DeleteCanary implements cluster.ConfigGateway.
+func (c *Client) DeleteCanary(ctx context.Context, capsuleID string) error {
+	c.logger.Debug("deleting docker canary", zap.String("capsuleID", capsuleID))
+
+	cs, err := c.getContainers(ctx, canaryPrefix(capsuleID))
+	if err != nil {
+		return err
+	}
+
+	for _, ci := range cs {
+		if err := c.dc.ContainerRemove(ctx, containerName(ci), types.ContainerRemoveOptions{
+			Force: true,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -29,6 +29,12 @@ func (c *Client) ListInstances(ctx context.Context, capsuleID string) (iterator.
 		return nil, 0, err
 	}
 
+	runs, err := c.getJobRuns(ctx, capsuleID)
+	if err != nil {
+		return nil, 0, err
+	}
+	cs = append(cs, runs...)
+
 	var is []*capsule.Instance
 	for _, ci := range cs {
 		i := &capsule.Instance{
@@ -72,6 +78,7 @@ func (c *Client) ListInstances(ctx context.Context, capsuleID string) (iterator.
 			}
 		case "running":
 			i.State = capsule.State_STATE_RUNNING
+			i.Ready = cj.State.Health == nil || cj.State.Health.Status == types.Healthy
 		case "created":
 			i.State = capsule.State_STATE_PENDING
 		default:
@@ -110,7 +117,11 @@ func (c *Client) deleteCapsule(ctx context.Context, capsuleID string) error {
 		}
 	}
 
-	return nil
+	if err := c.deleteCronJob(ctx, capsuleID); err != nil {
+		return err
+	}
+
+	return c.deleteHooks(ctx, capsuleID, "")
 }
 
 func (c *Client) getInstances(ctx context.Context, capsuleID string) ([]types.Container, error) {
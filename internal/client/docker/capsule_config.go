@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
@@ -16,6 +17,7 @@ import (
 	"github.com/rigdev/rig/gen/go/registry"
 	"github.com/rigdev/rig/internal/gateway/cluster"
 	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
 	"github.com/rigdev/rig/pkg/errors"
 	"github.com/rigdev/rig/pkg/iterator"
 	"go.uber.org/zap"
@@ -56,21 +58,70 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 		return err
 	}
 
-	image := cfg.Spec.Image
-	if image == "" {
+	if cfg.Spec.Image == "" {
 		return nil
 	}
 
-	netID, err := c.ensureNetwork(ctx)
+	ic, err := c.createInstanceConfig(ctx, cfg, envs)
+	if err != nil {
+		return err
+	}
+
+	existing, err := c.getInstances(ctx, cfg.GetName())
 	if err != nil {
 		return err
 	}
 
+	// Canary instances are managed by UpsertCanary and DeleteCanary.
+	var stable []types.Container
+	for _, e := range existing {
+		if !isCanaryContainer(e) {
+			stable = append(stable, e)
+		}
+	}
+
+	replicas := int(cfg.Spec.Replicas)
+	if cfg.Spec.CronJob != nil {
+		// The runs of a cron job are started by the scheduler.
+		projectID, err := auth.GetProjectID(ctx)
+		if err != nil {
+			return err
+		}
+
+		if err := c.cron.set(projectID, cfg.GetName(), *cfg.Spec.CronJob, time.Now()); err != nil {
+			return err
+		}
+		replicas = 0
+	} else if err := c.deleteCronJob(ctx, cfg.GetName()); err != nil {
+		return err
+	}
+
+	return c.upsertInstances(ctx, cfg.GetName(), fmt.Sprint(cfg.GetName(), "-instance-"), replicas, ic, stable)
+}
+
+// instanceConfig is the configuration shared by all instances of a capsule.
+type instanceConfig struct {
+	netID       string
+	cc          *container.Config
+	hc          *container.HostConfig
+	configFiles []*capsule.ConfigFile
+	volumes     []v1alpha1.CapsuleVolume
+}
+
+func (c *Client) createInstanceConfig(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string) (*instanceConfig, error) {
+	capsuleID := cfg.GetName()
+	image := cfg.Spec.Image
+
+	netID, err := c.ensureNetwork(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var regAuth *cluster.RegistryAuth
 	if cfg.Spec.ImagePullSecret != nil {
 		s, err := c.GetSecret(ctx, capsuleID, cfg.Spec.ImagePullSecret.Name, cfg.Namespace)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var out struct {
@@ -79,18 +130,18 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 			}
 		}
 		if err := json.Unmarshal(s.Data[".dockerconfigjson"], &out); err != nil {
-			return err
+			return nil, err
 		}
 
 		for host, a := range out.Auths {
 			auth, err := base64.StdEncoding.DecodeString(a.Auth)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			parts := strings.SplitN(string(auth), ":", 2)
 			if len(parts) != 2 {
-				return errors.InvalidArgumentErrorf("invalid .dockerconfigjson auth")
+				return nil, errors.InvalidArgumentErrorf("invalid .dockerconfigjson auth")
 			}
 
 			regAuth = &cluster.RegistryAuth{
@@ -104,7 +155,7 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 	}
 
 	if err := c.ensureImage(ctx, image, regAuth); err != nil {
-		return err
+		return nil, err
 	}
 
 	var cmd []string
@@ -124,6 +175,7 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 			// TODO(anders): Get port from config.
 			"RIG_HOST=http://rig:4747",
 		},
+		Healthcheck: createHealthcheck(cfg.Spec.Probes),
 	}
 	for k, v := range envs {
 		dcc.Env = append(dcc.Env, fmt.Sprint(k, "=", v))
@@ -137,32 +189,23 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 		},
 	}
 
-	dnc := &network.NetworkingConfig{
-		EndpointsConfig: map[string]*network.EndpointSettings{},
-	}
-
 	for _, i := range cfg.Spec.Interfaces {
 		if i.Public != nil {
 			switch {
 			case i.Public.LoadBalancer != nil:
 				dcc.ExposedPorts[nat.Port(fmt.Sprint(i.Public.LoadBalancer.Port, "/tcp"))] = struct{}{}
 			default:
-				return errors.InvalidArgumentErrorf("docker only supports LoadBalancer as routing method for public interfaces")
+				return nil, errors.InvalidArgumentErrorf("docker only supports LoadBalancer as routing method for public interfaces")
 			}
 		}
 	}
 
-	existing, err := c.getInstances(ctx, cfg.GetName())
-	if err != nil {
-		return err
-	}
-
 	var cf []*capsule.ConfigFile
 	for _, f := range cfg.Spec.Files {
 		if f.ConfigMap != nil {
 			cm, err := c.GetFile(ctx, capsuleID, f.ConfigMap.Name, cfg.Namespace)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			cf = append(cf, &capsule.ConfigFile{
@@ -172,17 +215,38 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 		}
 	}
 
-	for i := 0; i < int(cfg.Spec.Replicas); i++ {
-		containerID := fmt.Sprint(cfg.GetName(), "-instance-", i)
+	return &instanceConfig{
+		netID:       netID,
+		cc:          dcc,
+		hc:          dhc,
+		configFiles: cf,
+		volumes:     cfg.Spec.Volumes,
+	}, nil
+}
+
+// upsertInstances (re)creates `replicas` containers named with the given prefix
+// and removes the containers in existing that are no longer needed.
+func (c *Client) upsertInstances(ctx context.Context, capsuleID, prefix string, replicas int, ic *instanceConfig, existing []types.Container) error {
+	dnc := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{},
+	}
+
+	for i := 0; i < replicas; i++ {
+		containerID := fmt.Sprint(prefix, i)
 
-		dnc.EndpointsConfig[netID] = &network.EndpointSettings{
-			Aliases: []string{cfg.GetName(), containerID},
+		dnc.EndpointsConfig[ic.netID] = &network.EndpointSettings{
+			Aliases: []string{capsuleID, containerID},
 		}
-		if err := c.deleteService(ctx, cfg.GetName()); err != nil {
+		if err := c.deleteService(ctx, capsuleID); err != nil {
 			return err
 		}
 
-		if err := c.createAndStartContainer(ctx, containerID, dcc, dhc, dnc, cf); err != nil {
+		hc, err := c.mountVolumes(ctx, containerID, ic)
+		if err != nil {
+			return err
+		}
+
+		if err := c.createAndStartContainer(ctx, containerID, ic.cc, hc, dnc, ic.configFiles); err != nil {
 			return err
 		}
 
@@ -220,6 +284,10 @@ func (c *Client) DeleteCapsuleConfig(ctx context.Context, capsuleID string) erro
 		}
 	}
 
+	if err := c.deleteCronJob(ctx, capsuleID); err != nil {
+		return err
+	}
+
 	return c.rcc.DeleteCapsuleConfig(ctx, capsuleID)
 }
 
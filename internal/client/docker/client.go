@@ -38,6 +38,7 @@ type Client struct {
 	logger *zap.Logger
 	dc     *client.Client
 	rcc    repository.ClusterConfig
+	cron   *cronJobs
 }
 
 func New(cfg config.Config, logger *zap.Logger, rcc repository.ClusterConfig) (*Client, error) {
@@ -51,11 +52,16 @@ func New(cfg config.Config, logger *zap.Logger, rcc repository.ClusterConfig) (*
 		return nil, err
 	}
 
-	return &Client{
+	c := &Client{
 		logger: logger,
 		dc:     dc,
 		rcc:    rcc,
-	}, nil
+		cron:   newCronJobs(),
+	}
+
+	go c.runCronJobs(context.Background())
+
+	return c, nil
 }
 
 func (c *Client) Logs(ctx context.Context, capsuleName string, instanceID string, follow bool) (iterator.Iterator[*capsule.Log], error) {
@@ -73,8 +79,8 @@ func (c *Client) Logs(ctx context.Context, capsuleName string, instanceID string
 
 	p := iterator.NewProducer[*capsule.Log]()
 
-	stdout := newLogsWriter(p, false)
-	stderr := newLogsWriter(p, true)
+	stdout := newLogsWriter(p, logStreamStdout)
+	stderr := newLogsWriter(p, logStreamStderr)
 	go func() {
 		_, err := stdcopy.StdCopy(stdout, stderr, ls)
 		p.Error(err)
@@ -83,15 +89,51 @@ func (c *Client) Logs(ctx context.Context, capsuleName string, instanceID string
 	return p, nil
 }
 
+// AccessLogs implements cluster.Gateway. The proxy of the capsule is shared by
+// all the instances, so the access log covers every instance of the capsule.
+// The proxy writes the access log to stdout and its own logs to stderr.
+func (c *Client) AccessLogs(ctx context.Context, capsuleID string, instanceID string, follow bool) (iterator.Iterator[*capsule.Log], error) {
+	c.logger.Debug("reading docker access logs", zap.String("capsule_id", capsuleID), zap.String("instance_id", instanceID))
+
+	ls, err := c.dc.ContainerLogs(ctx, fmt.Sprint(capsuleID, "-service"), types.ContainerLogsOptions{
+		ShowStdout: true,
+		Follow:     follow,
+		Timestamps: true,
+	})
+	if client.IsErrNotFound(err) {
+		return nil, errors.NotFoundErrorf("capsule '%s' has no proxy", capsuleID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	p := iterator.NewProducer[*capsule.Log]()
+
+	stdout := newLogsWriter(p, logStreamAccessLog)
+	go func() {
+		_, err := stdcopy.StdCopy(stdout, io.Discard, ls)
+		p.Error(err)
+	}()
+
+	return p, nil
+}
+
+type logStream int
+
+const (
+	logStreamStdout logStream = iota
+	logStreamStderr
+	logStreamAccessLog
+)
+
 type logsWriter struct {
 	p      *iterator.Producer[*capsule.Log]
-	stderr bool
+	stream logStream
 }
 
-func newLogsWriter(p *iterator.Producer[*capsule.Log], stderr bool) *logsWriter {
+func newLogsWriter(p *iterator.Producer[*capsule.Log], stream logStream) *logsWriter {
 	return &logsWriter{
 		p:      p,
-		stderr: stderr,
+		stream: stream,
 	}
 }
 
@@ -108,9 +150,12 @@ func (w *logsWriter) Write(bs []byte) (int, error) {
 
 	// Note that when returning from `Write`, the buffer may no longer be referenced -> dup.
 	out := bytes.Clone(bs[index+1:])
-	if w.stderr {
+	switch w.stream {
+	case logStreamStderr:
 		l.Message.Message = &capsule.LogMessage_Stderr{Stderr: out}
-	} else {
+	case logStreamAccessLog:
+		l.Message.Message = &capsule.LogMessage_AccessLog{AccessLog: out}
+	default:
 		l.Message.Message = &capsule.LogMessage_Stdout{Stdout: out}
 	}
 	if err := w.p.Value(l); err != nil {
@@ -0,0 +1,351 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// _cronJobTick is how often the scheduler starts the runs that are due and
+// stops the runs that timed out.
+const _cronJobTick = 5 * time.Second
+
+func jobRunPrefix(capsuleID string) string {
+	return fmt.Sprint(capsuleID, "-job-")
+}
+
+// cronJob is a cron job capsule registered with the scheduler.
+type cronJob struct {
+	projectID uuid.UUID
+	capsuleID string
+	spec      v1alpha1.CapsuleCronJob
+	schedule  cron.Schedule
+	next      time.Time
+}
+
+// cronJobs holds the cron job capsules the scheduler of the server runs.
+type cronJobs struct {
+	lock sync.Mutex
+	jobs map[string]*cronJob
+}
+
+func newCronJobs() *cronJobs {
+	return &cronJobs{
+		jobs: map[string]*cronJob{},
+	}
+}
+
+func cronJobKey(projectID uuid.UUID, capsuleID string) string {
+	return fmt.Sprint(projectID, "/", capsuleID)
+}
+
+func (cj *cronJobs) set(projectID uuid.UUID, capsuleID string, spec v1alpha1.CapsuleCronJob, now time.Time) error {
+	schedule, err := cron.ParseStandard(spec.Schedule)
+	if err != nil {
+		return errors.InvalidArgumentErrorf("invalid cron job schedule: %v", err)
+	}
+
+	cj.lock.Lock()
+	defer cj.lock.Unlock()
+
+	key := cronJobKey(projectID, capsuleID)
+	if j, ok := cj.jobs[key]; ok && j.spec.Schedule == spec.Schedule {
+		j.spec = spec
+		return nil
+	}
+
+	cj.jobs[key] = &cronJob{
+		projectID: projectID,
+		capsuleID: capsuleID,
+		spec:      spec,
+		schedule:  schedule,
+		next:      schedule.Next(now.UTC()),
+	}
+	return nil
+}
+
+func (cj *cronJobs) remove(projectID uuid.UUID, capsuleID string) {
+	cj.lock.Lock()
+	defer cj.lock.Unlock()
+
+	delete(cj.jobs, cronJobKey(projectID, capsuleID))
+}
+
+// due returns the jobs with a run scheduled at or before now, with next set
+// to the time of that run, and moves them on to their next run.
+func (cj *cronJobs) due(now time.Time) []cronJob {
+	cj.lock.Lock()
+	defer cj.lock.Unlock()
+
+	var due []cronJob
+	for _, j := range cj.jobs {
+		if !j.next.After(now) {
+			due = append(due, *j)
+			j.next = j.schedule.Next(now.UTC())
+		}
+	}
+	return due
+}
+
+func (cj *cronJobs) list() []cronJob {
+	cj.lock.Lock()
+	defer cj.lock.Unlock()
+
+	var jobs []cronJob
+	for _, j := range cj.jobs {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// runCronJobs runs the scheduler of the cron job capsules until ctx is done.
+func (c *Client) runCronJobs(ctx context.Context) {
+	if err := c.loadCronJobs(ctx); err != nil {
+		c.logger.Warn("could not load cron jobs", zap.Error(err))
+	}
+
+	t := time.NewTicker(_cronJobTick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			for _, j := range c.cron.due(now) {
+				if err := c.startJobRun(ctx, j, j.next); err != nil {
+					c.logger.Warn("could not start cron job run", zap.Error(err), zap.String("capsule_id", j.capsuleID))
+				}
+			}
+
+			for _, j := range c.cron.list() {
+				if err := c.stopTimedOutJobRuns(ctx, j, now); err != nil {
+					c.logger.Warn("could not stop timed out cron job runs", zap.Error(err), zap.String("capsule_id", j.capsuleID))
+				}
+			}
+		}
+	}
+}
+
+// loadCronJobs registers the cron job capsules of all projects, such that
+// their schedules survive a restart of the server.
+func (c *Client) loadCronJobs(ctx context.Context) error {
+	it, err := c.rcc.ListAllCapsuleConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for {
+		cfg, err := it.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if cfg.Spec.CronJob == nil || cfg.Spec.Image == "" {
+			continue
+		}
+
+		projectID, err := uuid.Parse(cfg.GetNamespace())
+		if err != nil {
+			c.logger.Warn("invalid project of cron job", zap.Error(err), zap.String("capsule_id", cfg.GetName()))
+			continue
+		}
+
+		if err := c.cron.set(projectID, cfg.GetName(), *cfg.Spec.CronJob, time.Now()); err != nil {
+			c.logger.Warn("invalid cron job", zap.Error(err), zap.String("capsule_id", cfg.GetName()))
+		}
+	}
+}
+
+// startJobRun starts a run of the cron job with the current config of the
+// capsule. The container of a run is named by its scheduled time, so a run is
+// only started once.
+func (c *Client) startJobRun(ctx context.Context, j cronJob, scheduledAt time.Time) error {
+	ctx = auth.WithProjectID(ctx, j.projectID)
+
+	cfg, err := c.rcc.GetCapsuleConfig(ctx, j.capsuleID)
+	if errors.IsNotFound(err) {
+		c.cron.remove(j.projectID, j.capsuleID)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if cfg.Spec.CronJob == nil {
+		c.cron.remove(j.projectID, j.capsuleID)
+		return nil
+	}
+
+	containerID := fmt.Sprint(jobRunPrefix(j.capsuleID), scheduledAt.Unix())
+	if _, err := c.dc.ContainerInspect(ctx, containerID); err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	envs, err := c.rcc.GetEnvironmentVariables(ctx, j.capsuleID)
+	if err != nil {
+		return err
+	}
+
+	runs, err := c.getJobRuns(ctx, j.capsuleID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range runs {
+		if r.State != "running" {
+			continue
+		}
+
+		switch cfg.Spec.CronJob.ConcurrencyPolicy {
+		case batchv1.ForbidConcurrent:
+			c.logger.Info("skipping cron job run, previous run is still running", zap.String("capsule_id", j.capsuleID))
+			return nil
+		case batchv1.ReplaceConcurrent:
+			if err := c.dc.ContainerStop(ctx, r.ID, container.StopOptions{}); err != nil && !client.IsErrNotFound(err) {
+				return err
+			}
+		}
+	}
+
+	ic, err := c.createInstanceConfig(ctx, cfg, envs)
+	if err != nil {
+		return err
+	}
+
+	dcc := *ic.cc
+	dcc.ExposedPorts = nil
+	dcc.Healthcheck = nil
+
+	dhc := *ic.hc
+	dhc.PortBindings = nil
+	dhc.RestartPolicy = container.RestartPolicy{Name: "no"}
+
+	dnc := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			ic.netID: {Aliases: []string{containerID}},
+		},
+	}
+
+	if err := c.createAndStartContainer(ctx, containerID, &dcc, &dhc, dnc, ic.configFiles); err != nil {
+		return err
+	}
+
+	return c.pruneJobRuns(ctx, j.capsuleID, int(cfg.Spec.CronJob.HistoryLimit))
+}
+
+// pruneJobRuns removes the oldest finished runs of the cron job, keeping
+// historyLimit of them.
+func (c *Client) pruneJobRuns(ctx context.Context, capsuleID string, historyLimit int) error {
+	runs, err := c.getJobRuns(ctx, capsuleID)
+	if err != nil {
+		return err
+	}
+
+	var finished []types.Container
+	for _, r := range runs {
+		if r.State == "exited" || r.State == "dead" {
+			finished = append(finished, r)
+		}
+	}
+
+	sort.Slice(finished, func(i, j int) bool { return finished[i].Created > finished[j].Created })
+	if len(finished) <= historyLimit {
+		return nil
+	}
+
+	for _, r := range finished[historyLimit:] {
+		if err := c.dc.ContainerRemove(ctx, r.ID, types.ContainerRemoveOptions{
+			Force: true,
+		}); err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) stopTimedOutJobRuns(ctx context.Context, j cronJob, now time.Time) error {
+	if j.spec.TimeoutSeconds <= 0 {
+		return nil
+	}
+
+	runs, err := c.getJobRuns(ctx, j.capsuleID)
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(j.spec.TimeoutSeconds) * time.Second
+	for _, r := range runs {
+		if r.State != "running" || now.Sub(time.Unix(r.Created, 0)) < timeout {
+			continue
+		}
+
+		c.logger.Info("stopping timed out cron job run", zap.String("capsule_id", j.capsuleID), zap.String("instance_id", containerName(r)))
+		if err := c.dc.ContainerStop(ctx, r.ID, container.StopOptions{}); err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getJobRuns returns the containers of the runs of the cron job.
+func (c *Client) getJobRuns(ctx context.Context, capsuleID string) ([]types.Container, error) {
+	cs, err := c.getContainers(ctx, jobRunPrefix(capsuleID))
+	if err != nil {
+		return nil, err
+	}
+
+	var runs []types.Container
+	for _, ci := range cs {
+		if ci.Labels[_rigCapsuleIDLabel] == capsuleID {
+			runs = append(runs, ci)
+		}
+	}
+	return runs, nil
+}
+
+// deleteCronJob stops scheduling runs of the capsule and removes its runs.
+func (c *Client) deleteCronJob(ctx context.Context, capsuleID string) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	c.cron.remove(projectID, capsuleID)
+
+	runs, err := c.getJobRuns(ctx, capsuleID)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range runs {
+		if err := c.dc.ContainerRemove(ctx, r.ID, types.ContainerRemoveOptions{
+			Force: true,
+		}); err != nil && !client.IsErrNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
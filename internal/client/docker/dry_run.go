@@ -0,0 +1,14 @@
+package docker
+
+import (
+	"context"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DryRunCapsuleConfig implements cluster.ConfigGateway. Docker capsules are
+// not backed by Kubernetes objects, so there is nothing to return.
+func (c *Client) DryRunCapsuleConfig(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, files []*v1.ConfigMap) ([]interface{}, error) {
+	return nil, nil
+}
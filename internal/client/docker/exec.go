@@ -0,0 +1,98 @@
+package docker
+
+import (
+	"context"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// Exec implements cluster.Gateway.
+func (c *Client) Exec(ctx context.Context, capsuleID, instanceID string, command []string, streams *cluster.ExecStreams) (int32, error) {
+	c.logger.Debug("executing in docker container", zap.String("capsule_id", capsuleID), zap.String("instance_id", instanceID))
+
+	// Only the containers of the capsule can be executed in, not e.g. the
+	// containers of the rig setup itself.
+	cj, err := c.dc.ContainerInspect(ctx, instanceID)
+	if client.IsErrNotFound(err) || (err == nil && cj.Config.Labels[_rigCapsuleIDLabel] != capsuleID) {
+		return 0, errors.NotFoundErrorf("instance '%s' not found", instanceID)
+	} else if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	er, err := c.dc.ContainerExecCreate(ctx, instanceID, types.ExecConfig{
+		Tty:          streams.TTY,
+		AttachStdin:  streams.Stdin != nil,
+		AttachStdout: true,
+		AttachStderr: !streams.TTY,
+		Cmd:          command,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	hr, err := c.dc.ContainerExecAttach(ctx, er.ID, types.ExecStartCheck{
+		Tty: streams.TTY,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer hr.Close()
+
+	if streams.Stdin != nil {
+		go func() {
+			if _, err := io.Copy(hr.Conn, streams.Stdin); err != nil {
+				c.logger.Debug("error copying exec input", zap.Error(err))
+			}
+			hr.CloseWrite()
+		}()
+	}
+
+	if streams.Resize != nil {
+		go c.resizeExec(ctx, er.ID, streams.Resize)
+	}
+
+	if streams.TTY {
+		_, err = io.Copy(streams.Stdout, hr.Reader)
+	} else {
+		_, err = stdcopy.StdCopy(streams.Stdout, streams.Stderr, hr.Reader)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	ei, err := c.dc.ContainerExecInspect(ctx, er.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return int32(ei.ExitCode), nil
+}
+
+func (c *Client) resizeExec(ctx context.Context, execID string, sizes <-chan cluster.TerminalSize) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case s, ok := <-sizes:
+			if !ok {
+				return
+			}
+
+			if err := c.dc.ContainerExecResize(ctx, execID, types.ResizeOptions{
+				Height: uint(s.Height),
+				Width:  uint(s.Width),
+			}); err != nil {
+				c.logger.Debug("could not resize exec", zap.String("exec_id", execID), zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const _rigHookLabel = "io.rig.hook"
+
+func hookPrefix(capsuleID string) string {
+	return fmt.Sprint(capsuleID, "-hook-")
+}
+
+// StartHook implements cluster.ConfigGateway. The hook runs in a container
+// named by the instance ID of the hook.
+func (c *Client) StartHook(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, hook *cluster.Hook) error {
+	c.logger.Debug("starting docker hook", zap.String("capsuleID", cfg.GetName()), zap.String("instance_id", hook.InstanceID))
+
+	if _, err := c.dc.ContainerInspect(ctx, hook.InstanceID); err == nil {
+		return nil
+	} else if !client.IsErrNotFound(err) {
+		return err
+	}
+
+	if hook.Kind != cluster.HookKindTask {
+		if err := c.deleteHooks(ctx, cfg.GetName(), hook.Kind); err != nil {
+			return err
+		}
+	}
+
+	ic, err := c.createInstanceConfig(ctx, cfg, envs)
+	if err != nil {
+		return err
+	}
+
+	dcc := *ic.cc
+	dcc.Entrypoint = []string{hook.Command}
+	dcc.Cmd = hook.Args
+	dcc.ExposedPorts = nil
+	dcc.Healthcheck = nil
+	dcc.Labels = map[string]string{
+		_rigCapsuleIDLabel: cfg.GetName(),
+		_rigProjectIDLabel: cfg.GetNamespace(),
+		_rigHookLabel:      hook.Kind,
+	}
+
+	dhc := *ic.hc
+	dhc.PortBindings = nil
+	dhc.RestartPolicy = container.RestartPolicy{Name: "no"}
+
+	dnc := &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			ic.netID: {Aliases: []string{hook.InstanceID}},
+		},
+	}
+
+	return c.createAndStartContainer(ctx, hook.InstanceID, &dcc, &dhc, dnc, ic.configFiles)
+}
+
+// GetHookStatus implements cluster.ConfigGateway.
+func (c *Client) GetHookStatus(ctx context.Context, capsuleID, instanceID string) (bool, int32, error) {
+	cj, err := c.dc.ContainerInspect(ctx, instanceID)
+	if client.IsErrNotFound(err) {
+		return false, 0, errors.NotFoundErrorf("hook '%s' not found", instanceID)
+	} else if err != nil {
+		return false, 0, err
+	}
+
+	switch {
+	case cj.State.Status == "exited", cj.State.Status == "dead":
+		return true, int32(cj.State.ExitCode), nil
+	case cj.State.Status == "created" && cj.State.Error != "":
+		// The container could not be started.
+		c.logger.Info("hook not started", zap.String("instance_id", instanceID), zap.String("error", cj.State.Error))
+		if cj.State.ExitCode == 0 {
+			return true, -1, nil
+		}
+		return true, int32(cj.State.ExitCode), nil
+	default:
+		return false, 0, nil
+	}
+}
+
+// DeleteHook implements cluster.ConfigGateway.
+func (c *Client) DeleteHook(ctx context.Context, capsuleID, instanceID string) error {
+	if err := c.dc.ContainerRemove(ctx, instanceID, types.ContainerRemoveOptions{
+		Force: true,
+	}); client.IsErrNotFound(err) {
+		return errors.NotFoundErrorf("hook '%s' not found", instanceID)
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// deleteHooks removes the hook containers of the capsule with the given kind,
+// or all hook containers if the kind is empty.
+func (c *Client) deleteHooks(ctx context.Context, capsuleID, kind string) error {
+	cs, err := c.getContainers(ctx, hookPrefix(capsuleID))
+	if err != nil {
+		return err
+	}
+
+	for _, ci := range cs {
+		if ci.Labels[_rigCapsuleIDLabel] != capsuleID || ci.Labels[_rigHookLabel] == "" {
+			continue
+		}
+
+		if kind != "" && ci.Labels[_rigHookLabel] != kind {
+			continue
+		}
+
+		if err := c.dc.ContainerRemove(ctx, containerName(ci), types.ContainerRemoveOptions{
+			Force: true,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/docker/docker/client"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+)
+
+// PortForward implements cluster.Gateway. The container is dialed directly,
+// preferably on the rig network shared with rig-server.
+func (c *Client) PortForward(ctx context.Context, capsuleID, instanceID string, port uint32) (cluster.Conn, error) {
+	c.logger.Debug("port-forwarding to docker container", zap.String("capsule_id", capsuleID), zap.String("instance_id", instanceID), zap.Uint32("port", port))
+
+	cj, err := c.dc.ContainerInspect(ctx, instanceID)
+	if client.IsErrNotFound(err) || (err == nil && cj.Config.Labels[_rigCapsuleIDLabel] != capsuleID) {
+		return nil, errors.NotFoundErrorf("instance '%s' not found", instanceID)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var ip string
+	for name, es := range cj.NetworkSettings.Networks {
+		if es.IPAddress == "" {
+			continue
+		}
+
+		if ip == "" || name == "rig" {
+			ip = es.IPAddress
+		}
+	}
+
+	if ip == "" {
+		return nil, errors.FailedPreconditionErrorf("instance '%s' has no IP address", instanceID)
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(ip, fmt.Sprint(port)))
+	if err != nil {
+		return nil, errors.UnavailableErrorf("could not connect to port %d of instance '%s': %v", port, instanceID, err)
+	}
+
+	return conn.(*net.TCPConn), nil
+}
@@ -0,0 +1,88 @@
+package docker
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+)
+
+// Defaults of the probe settings, matching the ones of Kubernetes.
+const (
+	_defaultProbePeriod           = 10 * time.Second
+	_defaultProbeTimeout          = time.Second
+	_defaultProbeFailureThreshold = 3
+)
+
+// createHealthcheck returns the HEALTHCHECK of the capsule instances, or nil if
+// the capsule has no probes. Docker supports a single health check, so it
+// runs the readiness probe, or the liveness probe if there is none. The
+// startup probe extends the start period of the health check.
+//
+// The health check runs inside the container, so HTTP, TCP and gRPC probes
+// require wget or curl, nc and grpc_health_probe respectively in the image.
+func createHealthcheck(ps *v1alpha1.CapsuleProbes) *container.HealthConfig {
+	if ps == nil {
+		return nil
+	}
+
+	p := ps.Readiness
+	if p == nil {
+		p = ps.Liveness
+	}
+	if p == nil {
+		return nil
+	}
+
+	var test []string
+	switch {
+	case p.HTTP != nil:
+		url := fmt.Sprintf("http://localhost:%d%s", p.HTTP.Port, p.HTTP.Path)
+		test = []string{"CMD-SHELL", fmt.Sprintf("wget -q -O /dev/null %s || curl -fsS -o /dev/null %s", url, url)}
+	case p.TCP != nil:
+		test = []string{"CMD-SHELL", fmt.Sprintf("nc -z localhost %d", p.TCP.Port)}
+	case p.GRPC != nil:
+		test = []string{"CMD", "grpc_health_probe", fmt.Sprintf("-addr=localhost:%d", p.GRPC.Port)}
+		if p.GRPC.Service != "" {
+			test = append(test, fmt.Sprint("-service=", p.GRPC.Service))
+		}
+	case p.Exec != nil:
+		test = append([]string{"CMD"}, p.Exec.Command...)
+	default:
+		return nil
+	}
+
+	startPeriod := time.Duration(p.InitialDelaySeconds) * time.Second
+	if s := ps.Startup; s != nil {
+		startPeriod += time.Duration(s.InitialDelaySeconds)*time.Second +
+			probePeriod(s)*time.Duration(probeFailureThreshold(s))
+	}
+
+	hc := &container.HealthConfig{
+		Test:        test,
+		Interval:    probePeriod(p),
+		Timeout:     _defaultProbeTimeout,
+		StartPeriod: startPeriod,
+		Retries:     probeFailureThreshold(p),
+	}
+	if p.TimeoutSeconds > 0 {
+		hc.Timeout = time.Duration(p.TimeoutSeconds) * time.Second
+	}
+
+	return hc
+}
+
+func probePeriod(p *v1alpha1.CapsuleProbe) time.Duration {
+	if p.PeriodSeconds > 0 {
+		return time.Duration(p.PeriodSeconds) * time.Second
+	}
+	return _defaultProbePeriod
+}
+
+func probeFailureThreshold(p *v1alpha1.CapsuleProbe) int {
+	if p.FailureThreshold > 0 {
+		return int(p.FailureThreshold)
+	}
+	return _defaultProbeFailureThreshold
+}
@@ -4,15 +4,54 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/volume"
 	"go.uber.org/zap"
 )
 
-func (c *Client) CreateVolume(ctx context.Context, id string) error {
-	logger := c.logger.With(zap.String("volume", id))
-	logger.Info("creating volume")
+// volumeName is the name of the volume of the container. Like the volume
+// claims of a StatefulSet, an instance gets its volume back when recreated.
+func volumeName(volume, containerID string) string {
+	return fmt.Sprint(volume, "-", containerID)
+}
+
+// mountVolumes returns the host config of the container, with the volumes of
+// the capsule mounted. The volumes are created if they don't exist, and are
+// never removed by rig, so the data is kept when the container, or the
+// capsule, is removed.
+func (c *Client) mountVolumes(ctx context.Context, containerID string, ic *instanceConfig) (*container.HostConfig, error) {
+	if len(ic.volumes) == 0 {
+		return ic.hc, nil
+	}
+
+	hc := *ic.hc
+	hc.Mounts = nil
+	for _, v := range ic.volumes {
+		name := volumeName(v.Name, containerID)
+		if err := c.createVolume(ctx, name, ic.cc.Labels); err != nil {
+			return nil, err
+		}
+
+		hc.Mounts = append(hc.Mounts, mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: name,
+			Target: v.Path,
+		})
+	}
+
+	return &hc, nil
+}
+
+// createVolume creates the volume, or does nothing if it already exists.
+func (c *Client) createVolume(ctx context.Context, name string, labels map[string]string) error {
+	logger := c.logger.With(zap.String("volume", name))
+	logger.Debug("creating volume")
 
-	if _, err := c.dc.VolumeCreate(ctx, volume.CreateOptions{Name: fmt.Sprint(id)}); err != nil {
+	if _, err := c.dc.VolumeCreate(ctx, volume.CreateOptions{
+		Name:   name,
+		Labels: labels,
+	}); err != nil {
 		return err
 	}
 
@@ -0,0 +1,133 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	acsautoscalingv2 "k8s.io/client-go/applyconfigurations/autoscaling/v2"
+)
+
+func autoscalerToProto(a *v1alpha1.CapsuleAutoscaler) *capsule.Autoscaler {
+	if a == nil {
+		return nil
+	}
+
+	return &capsule.Autoscaler{
+		MinReplicas:  uint32(a.MinReplicas),
+		MaxReplicas:  uint32(a.MaxReplicas),
+		CpuTarget:    uint32(a.CPUUtilization),
+		MemoryTarget: uint32(a.MemoryUtilization),
+	}
+}
+
+func hasAutoscaler(cc *cluster.Capsule) bool {
+	return cc.Autoscaler != nil
+}
+
+func (c *Client) reconcileAutoscaler(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
+	if !hasAutoscaler(cc) {
+		return c.deleteAutoscaler(ctx, capsuleID, namespace)
+	}
+
+	if _, err := c.cs.AutoscalingV2().
+		HorizontalPodAutoscalers(namespace).
+		Apply(ctx, createAutoscaler(capsuleID, namespace, cc), applyOpts()); err != nil {
+		return fmt.Errorf("could not apply HorizontalPodAutoscaler: %w", err)
+	}
+	return nil
+}
+
+func createAutoscaler(capsuleID, namespace string, cc *cluster.Capsule) *acsautoscalingv2.HorizontalPodAutoscalerApplyConfiguration {
+	a := cc.Autoscaler
+
+	kind := "Deployment"
+	if hasVolumes(cc) {
+		kind = "StatefulSet"
+	}
+
+	var metrics []*acsautoscalingv2.MetricSpecApplyConfiguration
+	if a.GetCpuTarget() > 0 {
+		metrics = append(metrics, resourceMetric(v1.ResourceCPU, a.GetCpuTarget()))
+	}
+	if a.GetMemoryTarget() > 0 {
+		metrics = append(metrics, resourceMetric(v1.ResourceMemory, a.GetMemoryTarget()))
+	}
+
+	return acsautoscalingv2.HorizontalPodAutoscaler(capsuleID, namespace).
+		WithLabels(commonLabels(capsuleID, cc)).
+		WithSpec(acsautoscalingv2.HorizontalPodAutoscalerSpec().
+			WithScaleTargetRef(acsautoscalingv2.CrossVersionObjectReference().
+				WithAPIVersion("apps/v1").
+				WithKind(kind).
+				WithName(capsuleID),
+			).
+			WithMinReplicas(int32(a.GetMinReplicas())).
+			WithMaxReplicas(int32(a.GetMaxReplicas())).
+			WithMetrics(metrics...),
+		)
+}
+
+func resourceMetric(name v1.ResourceName, target uint32) *acsautoscalingv2.MetricSpecApplyConfiguration {
+	return acsautoscalingv2.MetricSpec().
+		WithType(autoscalingv2.ResourceMetricSourceType).
+		WithResource(acsautoscalingv2.ResourceMetricSource().
+			WithName(name).
+			WithTarget(acsautoscalingv2.MetricTarget().
+				WithType(autoscalingv2.UtilizationMetricType).
+				WithAverageUtilization(int32(target)),
+			),
+		)
+}
+
+// autoscaledReplicas returns the replicas of the Deployment, or StatefulSet,
+// of the capsule, so applying it does not revert the scaling done by the
+// autoscaler. If it does not exist yet, the min replicas are used.
+func (c *Client) autoscaledReplicas(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) (int32, error) {
+	var replicas *int32
+	if hasVolumes(cc) {
+		ss, err := c.cs.AppsV1().
+			StatefulSets(namespace).
+			Get(ctx, capsuleID, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return int32(cc.Autoscaler.GetMinReplicas()), nil
+		} else if err != nil {
+			return 0, fmt.Errorf("could not get StatefulSet: %w", err)
+		}
+		replicas = ss.Spec.Replicas
+	} else {
+		d, err := c.cs.AppsV1().
+			Deployments(namespace).
+			Get(ctx, capsuleID, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return int32(cc.Autoscaler.GetMinReplicas()), nil
+		} else if err != nil {
+			return 0, fmt.Errorf("could not get Deployment: %w", err)
+		}
+		replicas = d.Spec.Replicas
+	}
+
+	if replicas == nil || *replicas < int32(cc.Autoscaler.GetMinReplicas()) {
+		return int32(cc.Autoscaler.GetMinReplicas()), nil
+	}
+	return *replicas, nil
+}
+
+func (c *Client) deleteAutoscaler(ctx context.Context, capsuleID, namespace string) error {
+	err := c.cs.AutoscalingV2().
+		HorizontalPodAutoscalers(namespace).
+		Delete(ctx, capsuleID, metav1.DeleteOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not delete HorizontalPodAutoscaler: %w", err)
+	}
+	return nil
+}
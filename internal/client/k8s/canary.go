@@ -0,0 +1,105 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	acsv1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+func canaryName(capsuleID string) string {
+	return fmt.Sprintf("%s-canary", capsuleID)
+}
+
+// UpsertCanary implements cluster.ConfigGateway.
+func (c *Client) UpsertCanary(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+	ns := projectID.String()
+	capsuleID := cfg.GetName()
+
+	cc, err := c.toClusterCapsule(ctx, cfg, envs, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.reconcilePullSecret(ctx, ns, cc.RegistryAuth); err != nil {
+		return err
+	}
+	if err := c.reconcileConfigFileMount(ctx, capsuleID, ns, cc); err != nil {
+		return err
+	}
+	if err := c.reconcileCanaryEnvSecret(ctx, capsuleID, ns, cc); err != nil {
+		return err
+	}
+
+	d, err := createDeployment(ctx, capsuleID, ns, cc.RegistryAuth != nil, canaryName(capsuleID), cc)
+	if err != nil {
+		return err
+	}
+
+	// The canary pods keep the selector labels of the capsule, such that the
+	// capsule services route traffic to them as well.
+	track := map[string]string{labelRigTrack: trackCanary}
+	d.WithName(canaryName(capsuleID)).WithLabels(track)
+	d.Spec.Selector.WithMatchLabels(track)
+	d.Spec.Template.WithLabels(track)
+
+	if _, err := c.cs.AppsV1().
+		Deployments(ns).
+		Apply(ctx, d, applyOpts()); err != nil {
+		return fmt.Errorf("could not apply canary Deployment: %w", err)
+	}
+	return nil
+}
+
+// DeleteCanary implements cluster.ConfigGateway.
+func (c *Client) DeleteCanary(ctx context.Context, capsuleID string) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	return c.deleteCanary(ctx, capsuleID, projectID.String())
+}
+
+func (c *Client) reconcileCanaryEnvSecret(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
+	if !hasEnvSecret(cc) {
+		return c.deleteEnvSecret(ctx, canaryName(capsuleID), namespace)
+	}
+
+	s := acsv1.Secret(canaryName(capsuleID), namespace).
+		WithLabels(commonLabels(capsuleID, cc)).
+		WithStringData(cc.ContainerSettings.GetEnvironmentVariables())
+
+	if _, err := c.cs.CoreV1().
+		Secrets(namespace).
+		Apply(ctx, s, applyOpts()); err != nil {
+		return fmt.Errorf("could not apply canary Secret: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) deleteCanary(ctx context.Context, capsuleID, ns string) error {
+	if err := c.deleteEnvSecret(ctx, canaryName(capsuleID), ns); err != nil {
+		return err
+	}
+
+	err := c.cs.AppsV1().
+		Deployments(ns).
+		Delete(ctx, canaryName(capsuleID), metav1.DeleteOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not delete canary Deployment: %w", err)
+	}
+	return nil
+}
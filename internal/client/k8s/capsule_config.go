@@ -54,11 +54,24 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 		return nil
 	}
 
+	cc, err := c.toClusterCapsule(ctx, cfg, envs, nil)
+	if err != nil {
+		return err
+	}
+
+	return c.upsertCapsule(ctx, cfg.GetName(), cc)
+}
+
+// toClusterCapsule resolves the capsule config. The given files take
+// precedence over the files stored for the capsule.
+func (c *Client) toClusterCapsule(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, files []*v1.ConfigMap) (*cluster.Capsule, error) {
+	capsuleID := cfg.GetName()
+
 	var regAuth *cluster.RegistryAuth
 	if cfg.Spec.ImagePullSecret != nil {
 		s, err := c.GetSecret(ctx, capsuleID, cfg.Spec.ImagePullSecret.Name, cfg.Namespace)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		var out struct {
@@ -67,18 +80,18 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 			}
 		}
 		if err := json.Unmarshal(s.Data[".dockerconfigjson"], &out); err != nil {
-			return err
+			return nil, err
 		}
 
 		for host, a := range out.Auths {
 			auth, err := base64.StdEncoding.DecodeString(a.Auth)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			parts := strings.SplitN(string(auth), ":", 2)
 			if len(parts) != 2 {
-				return errors.InvalidArgumentErrorf("invalid .dockerconfigjson auth")
+				return nil, errors.InvalidArgumentErrorf("invalid .dockerconfigjson auth")
 			}
 
 			regAuth = &cluster.RegistryAuth{
@@ -103,11 +116,25 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 			netIf.Public.Enabled = true
 			switch {
 			case i.Public.Ingress != nil:
+				ing := &capsule.RoutingMethod_Ingress{
+					Host:       i.Public.Ingress.Host,
+					PathPrefix: i.Public.Ingress.PathPrefix,
+					Tls:        i.Public.Ingress.TLS != nil,
+				}
+				if tls := i.Public.Ingress.TLS; tls != nil && tls.SecretName != "" {
+					s, err := c.GetSecret(ctx, capsuleID, tls.SecretName, cfg.Namespace)
+					if err != nil {
+						return nil, err
+					}
+
+					ing.Certificate = &capsule.Certificate{
+						Certificate: s.Data[v1.TLSCertKey],
+						PrivateKey:  s.Data[v1.TLSPrivateKeyKey],
+					}
+				}
 				netIf.Public.Method = &capsule.RoutingMethod{
 					Kind: &capsule.RoutingMethod_Ingress_{
-						Ingress: &capsule.RoutingMethod_Ingress{
-							Host: i.Public.Ingress.Host,
-						},
+						Ingress: ing,
 					},
 				}
 			case i.Public.LoadBalancer != nil:
@@ -127,9 +154,9 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 	var cf []*capsule.ConfigFile
 	for _, f := range cfg.Spec.Files {
 		if f.ConfigMap != nil {
-			cm, err := c.GetFile(ctx, capsuleID, f.ConfigMap.Name, cfg.Namespace)
+			cm, err := c.getFile(ctx, capsuleID, f.ConfigMap.Name, cfg.Namespace, files)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
 			cf = append(cf, &capsule.ConfigFile{
@@ -139,18 +166,32 @@ func (c *Client) applyCapsuleConfig(ctx context.Context, capsuleID string) error
 		}
 	}
 
-	return c.upsertCapsule(ctx, cfg.GetName(), &cluster.Capsule{
+	return &cluster.Capsule{
 		CapsuleID: cfg.GetName(),
 		Image:     cfg.Spec.Image,
 		ContainerSettings: &capsule.ContainerSettings{
 			EnvironmentVariables: envs,
+			Probes:               probesToProto(cfg.Spec.Probes),
 		},
 		Network:      network,
 		Replicas:     uint32(cfg.Spec.Replicas),
+		Autoscaler:   autoscalerToProto(cfg.Spec.Autoscaler),
+		CronJob:      cronJobToProto(cfg.Spec.CronJob),
+		Volumes:      volumesToProto(cfg.Spec.Volumes),
 		Namespace:    cfg.GetNamespace(),
 		RegistryAuth: regAuth,
 		ConfigFiles:  cf,
-	})
+	}, nil
+}
+
+func (c *Client) getFile(ctx context.Context, capsuleID, name, namespace string, files []*v1.ConfigMap) (*v1.ConfigMap, error) {
+	for _, f := range files {
+		if f.Name == name {
+			return f, nil
+		}
+	}
+
+	return c.GetFile(ctx, capsuleID, name, namespace)
 }
 
 func (c *Client) GetCapsuleConfig(ctx context.Context, capsuleID string) (*v1alpha1.Capsule, error) {
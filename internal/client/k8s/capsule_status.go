@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig/gen/go/capsule"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// GetCapsuleStatus implements cluster.StatusGateway. The status is read from
+// the status of the Capsule resource, as written by the capsule controller.
+func (c *Client) GetCapsuleStatus(ctx context.Context, namespace, capsuleID string) (*capsule.Status, error) {
+	cr, err := c.getCapsule(ctx, namespace, capsuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &capsule.Status{
+		Name:          cr.Name,
+		Labels:        cr.Labels,
+		Replicas:      uint32(cr.Status.Replicas),
+		ReadyReplicas: uint32(cr.Status.ReadyReplicas),
+		Image:         cr.Status.Image,
+		UpToDate: cr.Status.ObservedGeneration == cr.Generation &&
+			!meta.IsStatusConditionTrue(cr.Status.Conditions, v1alpha1.CapsuleConditionProgressing),
+	}
+	for _, i := range cr.Status.Interfaces {
+		s.Interfaces = append(s.Interfaces, &capsule.InterfaceStatus{
+			Name:      i.Name,
+			Addresses: i.Addresses,
+		})
+	}
+
+	return s, nil
+}
+
+// ListInstanceStatuses implements cluster.StatusGateway. The instances are read
+// from the status of the Capsule resource, as written by the capsule
+// controller.
+func (c *Client) ListInstanceStatuses(ctx context.Context, namespace, capsuleID string) (iterator.Iterator[*capsule.Instance], uint64, error) {
+	cr, err := c.getCapsule(ctx, namespace, capsuleID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var is []*capsule.Instance
+	for _, i := range cr.Status.Instances {
+		instance := &capsule.Instance{
+			InstanceId:   i.Name,
+			BuildId:      i.Image,
+			State:        instanceStatusToState(i),
+			RestartCount: uint32(i.RestartCount),
+			CreatedAt:    timestamppb.New(i.CreatedAt.Time),
+			Ready:        i.Ready,
+		}
+		if i.StartedAt != nil {
+			instance.StartedAt = timestamppb.New(i.StartedAt.Time)
+		}
+		if i.FinishedAt != nil {
+			instance.FinishedAt = timestamppb.New(i.FinishedAt.Time)
+		}
+		is = append(is, instance)
+	}
+
+	return iterator.FromList(is), uint64(len(is)), nil
+}
+
+func (c *Client) getCapsule(ctx context.Context, namespace, capsuleID string) (*v1alpha1.Capsule, error) {
+	cr := &v1alpha1.Capsule{}
+	if err := c.cc.Get(ctx, types.NamespacedName{
+		Name:      capsuleID,
+		Namespace: namespace,
+	}, cr); err != nil {
+		if kerrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil, errors.NotFoundErrorf("capsule '%s' not found in the cluster", capsuleID)
+		}
+		return nil, fmt.Errorf("could not get capsule: %w", err)
+	}
+
+	return cr, nil
+}
+
+func instanceStatusToState(i v1alpha1.CapsuleInstanceStatus) capsule.State {
+	if i.CrashLooping {
+		return capsule.State_STATE_FAILED
+	}
+
+	switch i.Phase {
+	case v1.PodPending:
+		return capsule.State_STATE_PENDING
+	case v1.PodRunning:
+		return capsule.State_STATE_RUNNING
+	case v1.PodSucceeded:
+		return capsule.State_STATE_SUCCEEDED
+	case v1.PodFailed:
+		return capsule.State_STATE_FAILED
+	default:
+		return capsule.State_STATE_UNSPECIFIED
+	}
+}
@@ -0,0 +1,126 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/ptr"
+	"google.golang.org/protobuf/types/known/durationpb"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	acsbatchv1 "k8s.io/client-go/applyconfigurations/batch/v1"
+)
+
+func cronJobToProto(cj *v1alpha1.CapsuleCronJob) *capsule.CronJob {
+	if cj == nil {
+		return nil
+	}
+
+	pcj := &capsule.CronJob{
+		Schedule:     cj.Schedule,
+		HistoryLimit: uint32(cj.HistoryLimit),
+	}
+	if cj.TimeoutSeconds > 0 {
+		pcj.Timeout = durationpb.New(time.Duration(cj.TimeoutSeconds) * time.Second)
+	}
+
+	switch cj.ConcurrencyPolicy {
+	case batchv1.ForbidConcurrent:
+		pcj.ConcurrencyPolicy = capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_FORBID
+	case batchv1.ReplaceConcurrent:
+		pcj.ConcurrencyPolicy = capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_REPLACE
+	default:
+		pcj.ConcurrencyPolicy = capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_ALLOW
+	}
+
+	return pcj
+}
+
+func hasCronJob(cc *cluster.Capsule) bool {
+	return cc.CronJob != nil
+}
+
+func (c *Client) reconcileCronJob(ctx context.Context, capsuleID, namespace string, usePullSecret bool, cc *cluster.Capsule) error {
+	cj, err := createCronJob(ctx, capsuleID, namespace, usePullSecret, cc)
+	if err != nil {
+		return err
+	}
+
+	if _, err := c.cs.BatchV1().
+		CronJobs(namespace).
+		Apply(ctx, cj, applyOpts()); err != nil {
+		return fmt.Errorf("could not apply CronJob: %w", err)
+	}
+	return nil
+}
+
+// createCronJob returns a CronJob running the pods of the capsule Deployment
+// to completion.
+func createCronJob(
+	ctx context.Context,
+	capsuleID,
+	namespace string,
+	usePullSecret bool,
+	cc *cluster.Capsule,
+) (*acsbatchv1.CronJobApplyConfiguration, error) {
+	d, err := createDeployment(ctx, capsuleID, namespace, usePullSecret, capsuleID, cc)
+	if err != nil {
+		return nil, err
+	}
+
+	template := d.Spec.Template
+	template.Spec.WithRestartPolicy(v1.RestartPolicyNever)
+
+	job := acsbatchv1.JobSpec().
+		WithBackoffLimit(0).
+		WithTemplate(template)
+	if cc.CronJob.GetTimeout() != nil {
+		job.WithActiveDeadlineSeconds(int64(cc.CronJob.GetTimeout().AsDuration().Seconds()))
+	}
+
+	policy := batchv1.AllowConcurrent
+	switch cc.CronJob.GetConcurrencyPolicy() {
+	case capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_FORBID:
+		policy = batchv1.ForbidConcurrent
+	case capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_REPLACE:
+		policy = batchv1.ReplaceConcurrent
+	}
+
+	spec := acsbatchv1.CronJobSpec().
+		WithSchedule(cc.CronJob.GetSchedule()).
+		WithTimeZone("Etc/UTC").
+		WithConcurrencyPolicy(policy).
+		WithJobTemplate(acsbatchv1.JobTemplateSpec().
+			WithLabels(commonLabels(capsuleID, cc)).
+			WithSpec(job),
+		)
+	if h := int32(cc.CronJob.GetHistoryLimit()); h > 0 {
+		spec.WithSuccessfulJobsHistoryLimit(h).WithFailedJobsHistoryLimit(h)
+	}
+
+	return acsbatchv1.CronJob(capsuleID, namespace).
+		WithLabels(commonLabels(capsuleID, cc)).
+		WithSpec(spec), nil
+}
+
+func (c *Client) deleteCronJob(ctx context.Context, capsuleID, namespace string) error {
+	err := c.cs.BatchV1().
+		CronJobs(namespace).
+		Delete(ctx, capsuleID, metav1.DeleteOptions{
+			// Also delete the jobs and pods of the runs.
+			PropagationPolicy: ptr.New(metav1.DeletePropagationBackground),
+		})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not delete CronJob: %w", err)
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/rigdev/rig/pkg/auth"
+	v1 "k8s.io/api/core/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -32,9 +33,26 @@ func (c *Client) deleteCapsule(ctx context.Context, capsuleID string) error {
 	if err := c.deleteEnvSecret(ctx, capsuleID, ns); err != nil {
 		return err
 	}
+	if err := c.deleteAutoscaler(ctx, capsuleID, ns); err != nil {
+		return err
+	}
 	if err := c.deleteDeployment(ctx, capsuleID, ns); err != nil {
 		return err
 	}
+	// The volume claims of the StatefulSet are kept, so the data of the
+	// capsule isn't lost with it.
+	if err := c.deleteStatefulSet(ctx, capsuleID, ns); err != nil {
+		return err
+	}
+	if err := c.deleteCronJob(ctx, capsuleID, ns); err != nil {
+		return err
+	}
+	if err := c.deleteCanary(ctx, capsuleID, ns); err != nil {
+		return err
+	}
+	if err := c.deleteHooks(ctx, capsuleID, ns, ""); err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -76,7 +94,15 @@ func (c *Client) deleteLoadBalancer(ctx context.Context, capsuleID, namespace st
 }
 
 func (c *Client) deleteIngress(ctx context.Context, capsuleID, ns string) error {
-	err := c.cs.NetworkingV1().
+	err := c.cs.CoreV1().Secrets(ns).DeleteCollection(ctx, metav1.DeleteOptions{}, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", labelRigCapsuleID, capsuleID),
+		FieldSelector: fmt.Sprintf("type=%s", v1.SecretTypeTLS),
+	})
+	if err != nil {
+		return fmt.Errorf("could not delete TLS Secrets: %w", err)
+	}
+
+	err = c.cs.NetworkingV1().
 		Ingresses(ns).
 		Delete(ctx, capsuleID, metav1.DeleteOptions{})
 	if err != nil {
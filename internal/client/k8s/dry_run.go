@@ -0,0 +1,81 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	v1 "k8s.io/api/core/v1"
+)
+
+// DryRunCapsuleConfig implements cluster.ConfigGateway.
+func (c *Client) DryRunCapsuleConfig(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, files []*v1.ConfigMap) ([]interface{}, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ns := projectID.String()
+	capsuleID := cfg.GetName()
+
+	cc, err := c.toClusterCapsule(ctx, cfg, envs, files)
+	if err != nil {
+		return nil, err
+	}
+
+	// The pull secret is left out, as it contains the registry credentials.
+	var objs []interface{}
+	if hasInterfaces(cc) {
+		s, err := createProxyEnvSecret(ctx, capsuleID, ns, cc)
+		if err != nil {
+			return nil, err
+		}
+		objs = append(objs, s, createService(capsuleID, ns, cc))
+	}
+	if hasLoadBalancer(cc) {
+		objs = append(objs, createLoadBalancer(capsuleID, ns, cc))
+	}
+	if hasIngress(cc) {
+		objs = append(objs, createIngress(capsuleID, ns, c.cfg.Cluster.CertManager.ClusterIssuer, cc))
+	}
+	if hasEnvSecret(cc) {
+		objs = append(objs, createEnvSecret(capsuleID, ns, cc))
+	}
+
+	cms, err := createConfigFileMaps(ns, cc)
+	if err != nil {
+		return nil, err
+	}
+	for _, cm := range cms {
+		objs = append(objs, cm)
+	}
+
+	if hasCronJob(cc) {
+		cj, err := createCronJob(ctx, capsuleID, ns, cc.RegistryAuth != nil, cc)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(objs, cj), nil
+	}
+
+	if hasVolumes(cc) {
+		ss, err := createStatefulSet(ctx, capsuleID, ns, cc.RegistryAuth != nil, cc)
+		if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, ss)
+	} else {
+		d, err := createDeployment(ctx, capsuleID, ns, cc.RegistryAuth != nil, capsuleID, cc)
+		if err != nil {
+			return nil, err
+		}
+
+		objs = append(objs, d)
+	}
+	if hasAutoscaler(cc) {
+		objs = append(objs, createAutoscaler(capsuleID, ns, cc))
+	}
+
+	return objs, nil
+}
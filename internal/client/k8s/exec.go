@@ -0,0 +1,86 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/util/exec"
+)
+
+// Exec implements cluster.Gateway.
+func (c *Client) Exec(ctx context.Context, capsuleID, instanceID string, command []string, streams *cluster.ExecStreams) (int32, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	req := c.cs.CoreV1().
+		RESTClient().
+		Post().
+		Resource("pods").
+		Namespace(projectID.String()).
+		Name(instanceID).
+		SubResource("exec").
+		VersionedParams(&v1.PodExecOptions{
+			Container: capsuleID,
+			Command:   command,
+			Stdin:     streams.Stdin != nil,
+			Stdout:    true,
+			Stderr:    !streams.TTY,
+			TTY:       streams.TTY,
+		}, scheme.ParameterCodec)
+
+	e, err := remotecommand.NewSPDYExecutor(c.rc, "POST", req.URL())
+	if err != nil {
+		return 0, fmt.Errorf("could not create executor: %w", err)
+	}
+
+	opts := remotecommand.StreamOptions{
+		Stdin:  streams.Stdin,
+		Stdout: streams.Stdout,
+		Tty:    streams.TTY,
+	}
+	if !streams.TTY {
+		opts.Stderr = streams.Stderr
+	}
+	if streams.Resize != nil {
+		opts.TerminalSizeQueue = &terminalSizeQueue{ctx: ctx, c: streams.Resize}
+	}
+
+	err = e.StreamWithContext(ctx, opts)
+	if ee, ok := err.(exec.ExitError); ok {
+		return int32(ee.ExitStatus()), nil
+	} else if err != nil {
+		return 0, errors.UnavailableErrorf("could not exec in instance '%s': %v", instanceID, err)
+	}
+
+	return 0, nil
+}
+
+type terminalSizeQueue struct {
+	ctx context.Context
+	c   <-chan cluster.TerminalSize
+}
+
+// Next implements remotecommand.TerminalSizeQueue. Returning nil stops the
+// resizing.
+func (q *terminalSizeQueue) Next() *remotecommand.TerminalSize {
+	select {
+	case <-q.ctx.Done():
+		return nil
+	case s, ok := <-q.c:
+		if !ok {
+			return nil
+		}
+		return &remotecommand.TerminalSize{
+			Height: s.Height,
+			Width:  s.Width,
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	acsv1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+// hookLabels are the labels of the hook pods of a capsule. The pods don't have
+// the selector labels of the capsule, so they are not instances of it.
+func hookLabels(capsuleID, kind string) map[string]string {
+	return map[string]string{
+		labelManagedBy:    labelManagedByRig,
+		labelRigCapsuleID: capsuleID,
+		labelRigHook:      kind,
+	}
+}
+
+// StartHook implements cluster.ConfigGateway. The hook runs as a pod named by
+// the instance ID of the hook, with the main container named as the capsule,
+// such that Logs can read it.
+func (c *Client) StartHook(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, hook *cluster.Hook) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+	ns := projectID.String()
+	capsuleID := cfg.GetName()
+
+	if _, err := c.cs.CoreV1().
+		Pods(ns).
+		Get(ctx, hook.InstanceID, metav1.GetOptions{}); err == nil {
+		return nil
+	} else if !kerrors.IsNotFound(err) {
+		return fmt.Errorf("could not get hook Pod: %w", err)
+	}
+
+	if hook.Kind != cluster.HookKindTask {
+		if err := c.deleteHooks(ctx, capsuleID, ns, hook.Kind); err != nil {
+			return err
+		}
+	}
+
+	cc, err := c.toClusterCapsule(ctx, cfg, envs, nil)
+	if err != nil {
+		return err
+	}
+	// Hooks run to completion, so the health checks of the capsule do not
+	// apply to them.
+	cc.ContainerSettings.Probes = nil
+
+	if err := c.reconcilePullSecret(ctx, ns, cc.RegistryAuth); err != nil {
+		return err
+	}
+
+	if hasEnvSecret(cc) {
+		s := acsv1.Secret(hook.InstanceID, ns).
+			WithLabels(hookLabels(capsuleID, hook.Kind)).
+			WithStringData(cc.ContainerSettings.GetEnvironmentVariables())
+		if _, err := c.cs.CoreV1().
+			Secrets(ns).
+			Apply(ctx, s, applyOpts()); err != nil {
+			return fmt.Errorf("could not apply hook Secret: %w", err)
+		}
+	}
+
+	con := createContainer(capsuleID, hook.InstanceID, cc).
+		WithCommand(hook.Command).
+		WithArgs(hook.Args...)
+
+	p := acsv1.Pod(hook.InstanceID, ns).
+		WithLabels(hookLabels(capsuleID, hook.Kind)).
+		WithSpec(acsv1.PodSpec().
+			WithRestartPolicy(v1.RestartPolicyNever).
+			WithContainers(con).
+			WithVolumes(configFileVolumes(cc)...),
+		)
+
+	if cc.RegistryAuth != nil {
+		p.Spec.WithImagePullSecrets(acsv1.LocalObjectReference().
+			WithName(fmt.Sprintf("%s-pull", ns)),
+		)
+	}
+
+	if _, err := c.cs.CoreV1().
+		Pods(ns).
+		Apply(ctx, p, applyOpts()); err != nil {
+		return fmt.Errorf("could not apply hook Pod: %w", err)
+	}
+	return nil
+}
+
+// GetHookStatus implements cluster.ConfigGateway.
+func (c *Client) GetHookStatus(ctx context.Context, capsuleID, instanceID string) (bool, int32, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return false, 0, err
+	}
+
+	pod, err := c.cs.CoreV1().
+		Pods(projectID.String()).
+		Get(ctx, instanceID, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return false, 0, errors.NotFoundErrorf("hook '%s' not found", instanceID)
+	} else if err != nil {
+		return false, 0, fmt.Errorf("could not get hook Pod: %w", err)
+	}
+
+	if cs := podGetContainerStatus(*pod, capsuleID); cs != nil && cs.State.Terminated != nil {
+		return true, cs.State.Terminated.ExitCode, nil
+	}
+
+	return false, 0, nil
+}
+
+// DeleteHook implements cluster.ConfigGateway.
+func (c *Client) DeleteHook(ctx context.Context, capsuleID, instanceID string) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+	ns := projectID.String()
+
+	if err := c.deleteEnvSecret(ctx, instanceID, ns); err != nil {
+		return err
+	}
+
+	if err := c.cs.CoreV1().
+		Pods(ns).
+		Delete(ctx, instanceID, metav1.DeleteOptions{}); kerrors.IsNotFound(err) {
+		return errors.NotFoundErrorf("hook '%s' not found", instanceID)
+	} else if err != nil {
+		return fmt.Errorf("could not delete hook Pod: %w", err)
+	}
+
+	return nil
+}
+
+// deleteHooks deletes the hook pods of the capsule with the given kind, or
+// all hook pods if the kind is empty.
+func (c *Client) deleteHooks(ctx context.Context, capsuleID, ns, kind string) error {
+	selector := fmt.Sprintf("%s=%s,%s", labelRigCapsuleID, capsuleID, labelRigHook)
+	if kind != "" {
+		selector = labels.SelectorFromSet(hookLabels(capsuleID, kind)).String()
+	}
+
+	pl, err := c.cs.CoreV1().
+		Pods(ns).
+		List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return fmt.Errorf("could not list hook Pods: %w", err)
+	}
+
+	for _, p := range pl.Items {
+		if err := c.deleteEnvSecret(ctx, p.GetName(), ns); err != nil {
+			return err
+		}
+
+		if err := c.cs.CoreV1().
+			Pods(ns).
+			Delete(ctx, p.GetName(), metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete hook Pod: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -99,6 +99,7 @@ func podToInstance(pod v1.Pod, capsuleID string) (*capsule.Instance, error) {
 
 	if cs := podGetContainerStatus(pod, capsuleID); cs != nil {
 		i.RestartCount = uint32(cs.RestartCount)
+		i.Ready = cs.Ready
 
 		if cs.State.Running != nil {
 			i.StartedAt = timestamppb.New(cs.State.Running.StartedAt.Time)
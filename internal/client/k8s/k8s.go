@@ -6,25 +6,35 @@ import (
 	"os"
 	"path"
 
+	"github.com/rigdev/rig/internal/config"
 	"github.com/rigdev/rig/internal/gateway/cluster"
 	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
 	"go.uber.org/zap"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	metricsclient "k8s.io/metrics/pkg/client/clientset/versioned"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type Client struct {
 	logger *zap.Logger
+	cfg    config.Config
+	rc     *rest.Config
 	cs     *kubernetes.Clientset
 	mcs    *metricsclient.Clientset
+	cc     client.Client
 	rcc    repository.ClusterConfig
 }
 
-var _ cluster.Gateway = &Client{}
+var (
+	_ cluster.Gateway       = &Client{}
+	_ cluster.StatusGateway = &Client{}
+)
 
-func New(logger *zap.Logger, rcc repository.ClusterConfig) (*Client, error) {
+func New(logger *zap.Logger, cfg config.Config, rcc repository.ClusterConfig) (*Client, error) {
 	var (
 		restCfg *rest.Config
 		err     error
@@ -48,20 +58,27 @@ func New(logger *zap.Logger, rcc repository.ClusterConfig) (*Client, error) {
 		return nil, fmt.Errorf("could not create kubernetes metrics clientset: %w", err)
 	}
 
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return nil, fmt.Errorf("could not create scheme: %w", err)
+	}
+
+	cc, err := client.New(restCfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, fmt.Errorf("could not create kubernetes client: %w", err)
+	}
+
 	return &Client{
 		logger: logger,
+		cfg:    cfg,
+		rc:     restCfg,
 		cs:     cs,
 		mcs:    mcs,
+		cc:     cc,
 		rcc:    rcc,
 	}, nil
 }
 
-// CreateVolume implements cluster.Gateway.
-func (*Client) CreateVolume(ctx context.Context, id string) error {
-	// This is a noop as volumes are provisioned as part of a StatefulSet
-	return nil
-}
-
 func (c *Client) ImageExistsNatively(ctx context.Context, image string) (bool, string, error) {
 	return false, "", nil
 }
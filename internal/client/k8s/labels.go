@@ -8,6 +8,9 @@ const (
 	labelManagedBy    = "app.kubernetes.io/managed-by"
 	labelManagedByRig = "rig"
 	labelRigCapsuleID = "rig.dev/capsule-id"
+	labelRigTrack     = "rig.dev/track"
+	labelRigHook      = "rig.dev/hook"
+	trackCanary       = "canary"
 )
 
 func selectorLabels(capsuleID string) map[string]string {
@@ -1,6 +1,7 @@
 package k8s
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -10,6 +11,7 @@ import (
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/rigdev/rig/pkg/auth"
 	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/proxy"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	v1 "k8s.io/api/core/v1"
 )
@@ -44,6 +46,64 @@ func (c *Client) Logs(ctx context.Context, capsuleID string, instanceID string,
 	return p, nil
 }
 
+// AccessLogs implements cluster.Gateway. The access log is read from the proxy
+// container of the instance, where it is interleaved with the logs of the proxy
+// itself.
+func (c *Client) AccessLogs(ctx context.Context, capsuleID string, instanceID string, follow bool) (iterator.Iterator[*capsule.Log], error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := c.cs.CoreV1().
+		Pods(projectID.String()).
+		GetLogs(instanceID, &v1.PodLogOptions{
+			Container:  proxyContainerName,
+			Timestamps: true,
+			Follow:     follow,
+		})
+	rc, err := req.Stream(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get access log stream: %w", err)
+	}
+
+	p := iterator.NewProducer[*capsule.Log]()
+
+	go func() {
+		defer rc.Close()
+
+		s := bufio.NewScanner(rc)
+		for s.Scan() {
+			l := &capsule.Log{}
+			line := s.Bytes()
+			if index := bytes.IndexByte(line, ' '); index > 0 {
+				if ts, err := time.Parse(time.RFC3339Nano, string(line[:index])); err == nil {
+					l.Timestamp = timestamppb.New(ts)
+					line = line[index+1:]
+				}
+			}
+
+			if !proxy.IsAccessLog(line) {
+				continue
+			}
+
+			l.Message = &capsule.LogMessage{
+				Message: &capsule.LogMessage_AccessLog{
+					AccessLog: append(bytes.Clone(line), '\n'),
+				},
+			}
+			if err := p.Value(l); err != nil {
+				p.Error(err)
+				return
+			}
+		}
+
+		p.Error(s.Err())
+	}()
+
+	return p, nil
+}
+
 type logsWriter struct {
 	p *iterator.Producer[*capsule.Log]
 }
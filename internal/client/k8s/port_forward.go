@@ -0,0 +1,108 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// PortForward implements cluster.Gateway, using the portforward subresource
+// of the pod.
+func (c *Client) PortForward(ctx context.Context, capsuleID, instanceID string, port uint32) (cluster.Conn, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ns := projectID.String()
+
+	pod, err := c.cs.CoreV1().
+		Pods(ns).
+		Get(ctx, instanceID, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) || (err == nil && pod.GetLabels()[labelRigCapsuleID] != capsuleID) {
+		return nil, errors.NotFoundErrorf("instance '%s' not found", instanceID)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get Pod: %w", err)
+	}
+
+	req := c.cs.CoreV1().
+		RESTClient().
+		Post().
+		Resource("pods").
+		Namespace(ns).
+		Name(instanceID).
+		SubResource("portforward")
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.rc)
+	if err != nil {
+		return nil, fmt.Errorf("could not create round tripper: %w", err)
+	}
+
+	conn, _, err := spdy.
+		NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL()).
+		Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, errors.UnavailableErrorf("could not port-forward to instance '%s': %v", instanceID, err)
+	}
+
+	h := http.Header{}
+	h.Set(v1.StreamType, v1.StreamTypeError)
+	h.Set(v1.PortHeader, fmt.Sprint(port))
+	h.Set(v1.PortForwardRequestIDHeader, "0")
+	es, err := conn.CreateStream(h)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not create error stream: %w", err)
+	}
+	// The error stream is only read from.
+	es.Close()
+
+	h.Set(v1.StreamType, v1.StreamTypeData)
+	ds, err := conn.CreateStream(h)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not create data stream: %w", err)
+	}
+
+	pfc := &portForwardConn{Stream: ds, conn: conn}
+	go func() {
+		// Errors of the forwarding, e.g. if the port is closed, are sent on the
+		// error stream. They end the connection.
+		bs, err := io.ReadAll(es)
+		if err != nil || len(bs) == 0 {
+			return
+		}
+
+		c.logger.Debug("port-forward error", zap.String("instance_id", instanceID), zap.Uint32("port", port), zap.ByteString("error", bs))
+		pfc.Close()
+	}()
+
+	return pfc, nil
+}
+
+type portForwardConn struct {
+	httpstream.Stream
+	conn httpstream.Connection
+}
+
+// CloseWrite implements cluster.Conn. Closing a stream only closes the
+// sending side.
+func (c *portForwardConn) CloseWrite() error {
+	return c.Stream.Close()
+}
+
+func (c *portForwardConn) Close() error {
+	c.Stream.Reset()
+	return c.conn.Close()
+}
@@ -0,0 +1,114 @@
+package k8s
+
+import (
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	acsv1 "k8s.io/client-go/applyconfigurations/core/v1"
+)
+
+func probesToProto(ps *v1alpha1.CapsuleProbes) *capsule.Probes {
+	if ps == nil {
+		return nil
+	}
+
+	return &capsule.Probes{
+		Liveness:  probeToProto(ps.Liveness),
+		Readiness: probeToProto(ps.Readiness),
+		Startup:   probeToProto(ps.Startup),
+	}
+}
+
+func probeToProto(p *v1alpha1.CapsuleProbe) *capsule.Probe {
+	if p == nil {
+		return nil
+	}
+
+	cp := &capsule.Probe{
+		FailureThreshold: uint32(p.FailureThreshold),
+	}
+	if p.InitialDelaySeconds != 0 {
+		cp.InitialDelay = durationpb.New(time.Duration(p.InitialDelaySeconds) * time.Second)
+	}
+	if p.PeriodSeconds != 0 {
+		cp.Period = durationpb.New(time.Duration(p.PeriodSeconds) * time.Second)
+	}
+	if p.TimeoutSeconds != 0 {
+		cp.Timeout = durationpb.New(time.Duration(p.TimeoutSeconds) * time.Second)
+	}
+
+	switch {
+	case p.HTTP != nil:
+		cp.Kind = &capsule.Probe_Http{Http: &capsule.HttpProbe{
+			Port: uint32(p.HTTP.Port),
+			Path: p.HTTP.Path,
+		}}
+	case p.TCP != nil:
+		cp.Kind = &capsule.Probe_Tcp{Tcp: &capsule.TcpProbe{
+			Port: uint32(p.TCP.Port),
+		}}
+	case p.GRPC != nil:
+		cp.Kind = &capsule.Probe_Grpc{Grpc: &capsule.GrpcProbe{
+			Port:    uint32(p.GRPC.Port),
+			Service: p.GRPC.Service,
+		}}
+	case p.Exec != nil:
+		cp.Kind = &capsule.Probe_Exec{Exec: &capsule.ExecProbe{
+			Command: p.Exec.Command,
+		}}
+	}
+
+	return cp
+}
+
+// makeProbe returns the Kubernetes probe of p, or nil if p is not set.
+func makeProbe(p *capsule.Probe) *acsv1.ProbeApplyConfiguration {
+	if p == nil {
+		return nil
+	}
+
+	probe := acsv1.Probe()
+	switch v := p.GetKind().(type) {
+	case *capsule.Probe_Http:
+		path := v.Http.GetPath()
+		if path == "" {
+			path = "/"
+		}
+		probe.WithHTTPGet(acsv1.HTTPGetAction().
+			WithPort(intstr.FromInt(int(v.Http.GetPort()))).
+			WithPath(path),
+		)
+	case *capsule.Probe_Tcp:
+		probe.WithTCPSocket(acsv1.TCPSocketAction().
+			WithPort(intstr.FromInt(int(v.Tcp.GetPort()))),
+		)
+	case *capsule.Probe_Grpc:
+		grpc := acsv1.GRPCAction().WithPort(int32(v.Grpc.GetPort()))
+		if v.Grpc.GetService() != "" {
+			grpc.WithService(v.Grpc.GetService())
+		}
+		probe.WithGRPC(grpc)
+	case *capsule.Probe_Exec:
+		probe.WithExec(acsv1.ExecAction().WithCommand(v.Exec.GetCommand()...))
+	default:
+		return nil
+	}
+
+	if p.GetInitialDelay() != nil {
+		probe.WithInitialDelaySeconds(int32(p.GetInitialDelay().AsDuration().Seconds()))
+	}
+	if p.GetPeriod() != nil {
+		probe.WithPeriodSeconds(int32(p.GetPeriod().AsDuration().Seconds()))
+	}
+	if p.GetTimeout() != nil {
+		probe.WithTimeoutSeconds(int32(p.GetTimeout().AsDuration().Seconds()))
+	}
+	if p.GetFailureThreshold() != 0 {
+		probe.WithFailureThreshold(int32(p.GetFailureThreshold()))
+	}
+
+	return probe
+}
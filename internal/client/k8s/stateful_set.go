@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/ptr"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	acsappsv1 "k8s.io/client-go/applyconfigurations/apps/v1"
+	acsv1 "k8s.io/client-go/applyconfigurations/core/v1"
+	acsmetav1 "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// statefulSetDeleteTimeout is how long to wait for a StatefulSet to be deleted
+// before it is recreated with new volumes.
+const statefulSetDeleteTimeout = 30 * time.Second
+
+func volumesToProto(vs []v1alpha1.CapsuleVolume) []*capsule.Volume {
+	var pvs []*capsule.Volume
+	for _, v := range vs {
+		pvs = append(pvs, &capsule.Volume{
+			Name:         v.Name,
+			Path:         v.Path,
+			SizeBytes:    uint64(v.Size.Value()),
+			StorageClass: v.StorageClassName,
+		})
+	}
+	return pvs
+}
+
+func hasVolumes(cc *cluster.Capsule) bool {
+	return len(cc.Volumes) > 0
+}
+
+func (c *Client) reconcileStatefulSet(ctx context.Context, capsuleID, namespace string, usePullSecret bool, cc *cluster.Capsule) error {
+	ss, err := createStatefulSet(ctx, capsuleID, namespace, usePullSecret, cc)
+	if err != nil {
+		return err
+	}
+
+	if hasAutoscaler(cc) {
+		replicas, err := c.autoscaledReplicas(ctx, capsuleID, namespace, cc)
+		if err != nil {
+			return err
+		}
+		ss.Spec.WithReplicas(replicas)
+	}
+
+	existing, err := c.cs.AppsV1().
+		StatefulSets(namespace).
+		Get(ctx, capsuleID, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+	} else if err != nil {
+		return fmt.Errorf("could not get StatefulSet: %w", err)
+	} else if volumeClaimsChanged(existing, cc) {
+		if err := c.recreateStatefulSet(ctx, capsuleID, namespace); err != nil {
+			return err
+		}
+	}
+
+	if _, err := c.cs.AppsV1().
+		StatefulSets(namespace).
+		Apply(ctx, ss, applyOpts()); err != nil {
+		return fmt.Errorf("could not apply StatefulSet: %w", err)
+	}
+	return nil
+}
+
+// recreateStatefulSet deletes the StatefulSet, as its volume claim templates
+// can't be updated. The pods are orphaned, such that they keep running until
+// they are adopted and replaced by the new StatefulSet.
+func (c *Client) recreateStatefulSet(ctx context.Context, capsuleID, namespace string) error {
+	if err := c.cs.AppsV1().
+		StatefulSets(namespace).
+		Delete(ctx, capsuleID, metav1.DeleteOptions{
+			PropagationPolicy: ptr.New(metav1.DeletePropagationOrphan),
+		}); err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete StatefulSet: %w", err)
+	}
+
+	if err := wait.PollUntilContextTimeout(ctx, time.Second, statefulSetDeleteTimeout, true, func(ctx context.Context) (bool, error) {
+		_, err := c.cs.AppsV1().
+			StatefulSets(namespace).
+			Get(ctx, capsuleID, metav1.GetOptions{})
+		if kerrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	}); err != nil {
+		return fmt.Errorf("could not wait for StatefulSet to be deleted: %w", err)
+	}
+
+	return nil
+}
+
+// volumeClaimsChanged returns true if the volume claim templates of the
+// StatefulSet differ from the volumes of the capsule.
+func volumeClaimsChanged(ss *appsv1.StatefulSet, cc *cluster.Capsule) bool {
+	if len(ss.Spec.VolumeClaimTemplates) != len(cc.Volumes) {
+		return true
+	}
+
+	for i, pvc := range ss.Spec.VolumeClaimTemplates {
+		v := cc.Volumes[i]
+		if pvc.GetName() != v.GetName() {
+			return true
+		}
+
+		size := pvc.Spec.Resources.Requests[v1.ResourceStorage]
+		if !size.Equal(volumeSize(v)) {
+			return true
+		}
+
+		var sc string
+		if pvc.Spec.StorageClassName != nil {
+			sc = *pvc.Spec.StorageClassName
+		}
+		if v.GetStorageClass() != "" && sc != v.GetStorageClass() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func volumeSize(v *capsule.Volume) resource.Quantity {
+	return *resource.NewQuantity(int64(v.GetSizeBytes()), resource.BinarySI)
+}
+
+// createStatefulSet returns a StatefulSet running the pods of the capsule
+// Deployment, with a claim for each volume of the capsule per pod. The claims
+// are kept when pods are scaled down or the StatefulSet is deleted.
+func createStatefulSet(
+	ctx context.Context,
+	capsuleID,
+	namespace string,
+	usePullSecret bool,
+	cc *cluster.Capsule,
+) (*acsappsv1.StatefulSetApplyConfiguration, error) {
+	d, err := createDeployment(ctx, capsuleID, namespace, usePullSecret, capsuleID, cc)
+	if err != nil {
+		return nil, err
+	}
+
+	template := d.Spec.Template
+	claims := make([]*acsv1.PersistentVolumeClaimApplyConfiguration, len(cc.Volumes))
+	for i, v := range cc.Volumes {
+		// The first container is the capsule container.
+		template.Spec.Containers[0].WithVolumeMounts(acsv1.VolumeMount().
+			WithName(v.GetName()).
+			WithMountPath(v.GetPath()),
+		)
+
+		spec := acsv1.PersistentVolumeClaimSpec().
+			WithAccessModes(v1.ReadWriteOnce).
+			WithResources(acsv1.ResourceRequirements().
+				WithRequests(v1.ResourceList{v1.ResourceStorage: volumeSize(v)}),
+			)
+		if v.GetStorageClass() != "" {
+			spec.WithStorageClassName(v.GetStorageClass())
+		}
+
+		claims[i] = acsv1.PersistentVolumeClaim(v.GetName(), "").
+			WithLabels(commonLabels(capsuleID, cc)).
+			WithSpec(spec)
+	}
+
+	return acsappsv1.StatefulSet(capsuleID, namespace).
+		WithLabels(commonLabels(capsuleID, cc)).
+		WithSpec(acsappsv1.StatefulSetSpec().
+			WithReplicas(int32(cc.Replicas)).
+			WithServiceName(capsuleID).
+			WithSelector(acsmetav1.LabelSelector().
+				WithMatchLabels(selectorLabels(capsuleID)),
+			).
+			// Like the pods of a Deployment, the pods are started and
+			// stopped all at once.
+			WithPodManagementPolicy(appsv1.ParallelPodManagement).
+			WithPersistentVolumeClaimRetentionPolicy(acsappsv1.StatefulSetPersistentVolumeClaimRetentionPolicy().
+				WithWhenDeleted(appsv1.RetainPersistentVolumeClaimRetentionPolicyType).
+				WithWhenScaled(appsv1.RetainPersistentVolumeClaimRetentionPolicyType),
+			).
+			WithTemplate(template).
+			WithVolumeClaimTemplates(claims...),
+		), nil
+}
+
+func (c *Client) deleteStatefulSet(ctx context.Context, capsuleID, namespace string) error {
+	err := c.cs.AppsV1().
+		StatefulSets(namespace).
+		Delete(ctx, capsuleID, metav1.DeleteOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("could not delete StatefulSet: %w", err)
+	}
+	return nil
+}
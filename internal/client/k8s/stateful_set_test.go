@@ -0,0 +1,72 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/ptr"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestVolumeClaimsChanged(t *testing.T) {
+	t.Parallel()
+	ss := &appsv1.StatefulSet{
+		Spec: appsv1.StatefulSetSpec{
+			VolumeClaimTemplates: []v1.PersistentVolumeClaim{{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
+				Spec: v1.PersistentVolumeClaimSpec{
+					StorageClassName: ptr.New("standard"),
+					Resources: v1.ResourceRequirements{
+						Requests: v1.ResourceList{v1.ResourceStorage: resource.MustParse("1Gi")},
+					},
+				},
+			}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		volumes  []*capsule.Volume
+		expected bool
+	}{
+		{
+			name:    "same volume",
+			volumes: []*capsule.Volume{{Name: "data", Path: "/data", SizeBytes: 1 << 30}},
+		},
+		{
+			name:    "default storage class",
+			volumes: []*capsule.Volume{{Name: "data", Path: "/mnt", SizeBytes: 1 << 30, StorageClass: "standard"}},
+		},
+		{
+			name:     "resized volume",
+			volumes:  []*capsule.Volume{{Name: "data", Path: "/data", SizeBytes: 2 << 30}},
+			expected: true,
+		},
+		{
+			name: "added volume",
+			volumes: []*capsule.Volume{
+				{Name: "data", Path: "/data", SizeBytes: 1 << 30},
+				{Name: "cache", Path: "/cache", SizeBytes: 1 << 30},
+			},
+			expected: true,
+		},
+		{
+			name:     "removed volume",
+			expected: true,
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, test.expected, volumeClaimsChanged(ss, &cluster.Capsule{Volumes: test.volumes}))
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"google.golang.org/protobuf/encoding/protojson"
 	v1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -45,6 +46,13 @@ func (c *Client) upsertCapsule(ctx context.Context, capsuleID string, cc *cluste
 		return err
 	}
 
+	if hasCronJob(cc) {
+		// The runs of a cron job are not scaled and do not serve traffic.
+		cc.Network = nil
+		cc.Autoscaler = nil
+		cc.ContainerSettings.Probes = nil
+	}
+
 	if err := c.reconcileProxyEnvSecret(ctx, capsuleID, ns, cc); err != nil {
 		return err
 	}
@@ -63,9 +71,42 @@ func (c *Client) upsertCapsule(ctx context.Context, capsuleID string, cc *cluste
 	if err := c.reconcileConfigFileMount(ctx, capsuleID, ns, cc); err != nil {
 		return err
 	}
-	if err := c.reconcileDeployment(ctx, capsuleID, ns, cc.RegistryAuth != nil, cc); err != nil {
+	if err := c.reconcileAutoscaler(ctx, capsuleID, ns, cc); err != nil {
 		return err
 	}
+	switch {
+	case hasCronJob(cc):
+		if err := c.deleteDeployment(ctx, capsuleID, ns); err != nil {
+			return err
+		}
+		if err := c.deleteStatefulSet(ctx, capsuleID, ns); err != nil {
+			return err
+		}
+		if err := c.reconcileCronJob(ctx, capsuleID, ns, cc.RegistryAuth != nil, cc); err != nil {
+			return err
+		}
+	case hasVolumes(cc):
+		if err := c.deleteDeployment(ctx, capsuleID, ns); err != nil {
+			return err
+		}
+		if err := c.deleteCronJob(ctx, capsuleID, ns); err != nil {
+			return err
+		}
+		if err := c.reconcileStatefulSet(ctx, capsuleID, ns, cc.RegistryAuth != nil, cc); err != nil {
+			return err
+		}
+	default:
+		if err := c.deleteCronJob(ctx, capsuleID, ns); err != nil {
+			return err
+		}
+		// The volume claims of the StatefulSet are kept.
+		if err := c.deleteStatefulSet(ctx, capsuleID, ns); err != nil {
+			return err
+		}
+		if err := c.reconcileDeployment(ctx, capsuleID, ns, cc.RegistryAuth != nil, cc); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -129,7 +170,7 @@ func createProxyConfig(ctx context.Context, cc *cluster.Capsule) (map[string]str
 
 	cfg.TargetHost = "localhost"
 
-	pps, err := createProxyPorts(cc.Network.GetInterfaces())
+	pps, err := cluster.ProxyPorts(cc.Network.GetInterfaces())
 	if err != nil {
 		return nil, err
 	}
@@ -156,15 +197,11 @@ func (c *Client) reconcileProxyEnvSecret(
 		return c.deleteProxyEnvSecret(ctx, capsuleID, namespace)
 	}
 
-	cfg, err := createProxyConfig(ctx, cc)
+	s, err := createProxyEnvSecret(ctx, capsuleID, namespace, cc)
 	if err != nil {
 		return err
 	}
 
-	s := acsv1.Secret(fmt.Sprintf("%s-proxy", capsuleID), namespace).
-		WithLabels(commonLabels(capsuleID, cc)).
-		WithStringData(cfg)
-
 	_, err = c.cs.CoreV1().
 		Secrets(namespace).
 		Apply(ctx, s, applyOpts())
@@ -174,11 +211,32 @@ func (c *Client) reconcileProxyEnvSecret(
 	return nil
 }
 
+func createProxyEnvSecret(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) (*acsv1.SecretApplyConfiguration, error) {
+	cfg, err := createProxyConfig(ctx, cc)
+	if err != nil {
+		return nil, err
+	}
+
+	return acsv1.Secret(fmt.Sprintf("%s-proxy", capsuleID), namespace).
+		WithLabels(commonLabels(capsuleID, cc)).
+		WithStringData(cfg), nil
+}
+
 func (c *Client) reconcileLoadBalancer(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
 	if !hasLoadBalancer(cc) {
 		return c.deleteLoadBalancer(ctx, capsuleID, namespace)
 	}
 
+	s := createLoadBalancer(capsuleID, namespace, cc)
+	_, err := c.cs.CoreV1().Services(namespace).Apply(ctx, s, applyOpts())
+	if err != nil {
+		return fmt.Errorf("could not apply Service: %w", err)
+	}
+
+	return nil
+}
+
+func createLoadBalancer(capsuleID, namespace string, cc *cluster.Capsule) *acsv1.ServiceApplyConfiguration {
 	var ports []*acsv1.ServicePortApplyConfiguration
 	for _, inf := range cc.Network.GetInterfaces() {
 		pub := inf.GetPublic()
@@ -194,39 +252,103 @@ func (c *Client) reconcileLoadBalancer(ctx context.Context, capsuleID, namespace
 		}
 	}
 
-	s := acsv1.Service(fmt.Sprintf("%s-lb", capsuleID), namespace).
+	return acsv1.Service(fmt.Sprintf("%s-lb", capsuleID), namespace).
 		WithLabels(commonLabels(capsuleID, cc)).
 		WithSpec(acsv1.ServiceSpec().
 			WithSelector(selectorLabels(capsuleID)).
 			WithPorts(ports...).
 			WithType(v1.ServiceTypeLoadBalancer),
 		)
+}
 
-	_, err := c.cs.CoreV1().Services(namespace).Apply(ctx, s, applyOpts())
-	if err != nil {
-		return fmt.Errorf("could not apply Service: %w", err)
+func (c *Client) reconcileIngress(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
+	if !hasIngress(cc) {
+		return c.deleteIngress(ctx, capsuleID, namespace)
 	}
 
+	if err := c.reconcileTLSSecrets(ctx, capsuleID, namespace, cc); err != nil {
+		return err
+	}
+
+	ing := createIngress(capsuleID, namespace, c.cfg.Cluster.CertManager.ClusterIssuer, cc)
+	_, err := c.cs.NetworkingV1().Ingresses(namespace).Apply(ctx, ing, applyOpts())
+	if err != nil {
+		return fmt.Errorf("could not apply Ingress: %w", err)
+	}
 	return nil
 }
 
-func (c *Client) reconcileIngress(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
-	if !hasIngress(cc) {
-		return c.deleteIngress(ctx, capsuleID, namespace)
+// reconcileTLSSecrets applies the certificates of the interfaces as TLS
+// secrets, and deletes the TLS secrets of the capsule no longer used.
+func (c *Client) reconcileTLSSecrets(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
+	used := map[string]struct{}{}
+	for _, inf := range cc.Network.GetInterfaces() {
+		ing := inf.GetPublic().GetMethod().GetIngress()
+		if !inf.GetPublic().GetEnabled() || !ing.GetTls() || ing.GetCertificate() == nil {
+			continue
+		}
+
+		name := cluster.TLSSecretName(capsuleID, inf.GetName())
+		s := acsv1.Secret(name, namespace).
+			WithLabels(commonLabels(capsuleID, cc)).
+			WithType(v1.SecretTypeTLS).
+			WithData(map[string][]byte{
+				v1.TLSCertKey:       ing.GetCertificate().GetCertificate(),
+				v1.TLSPrivateKeyKey: ing.GetCertificate().GetPrivateKey(),
+			})
+		if _, err := c.cs.CoreV1().Secrets(namespace).Apply(ctx, s, applyOpts()); err != nil {
+			return fmt.Errorf("could not apply TLS Secret: %w", err)
+		}
+		used[name] = struct{}{}
 	}
 
-	var rules []*acsnetv1.IngressRuleApplyConfiguration
+	sl, err := c.cs.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", labelRigCapsuleID, capsuleID),
+		FieldSelector: fmt.Sprintf("type=%s", v1.SecretTypeTLS),
+	})
+	if err != nil {
+		return fmt.Errorf("could not list TLS Secrets: %w", err)
+	}
+
+	for _, s := range sl.Items {
+		if _, ok := used[s.GetName()]; ok {
+			continue
+		}
+
+		if err := c.cs.CoreV1().Secrets(namespace).
+			Delete(ctx, s.GetName(), metav1.DeleteOptions{}); err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete TLS Secret: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// createIngress creates the ingress of the capsule. The certificates of TLS
+// hosts without a certificate of their own are issued by clusterIssuer, if
+// set.
+func createIngress(capsuleID, namespace, clusterIssuer string, cc *cluster.Capsule) *acsnetv1.IngressApplyConfiguration {
+	var (
+		rules  []*acsnetv1.IngressRuleApplyConfiguration
+		tls    []*acsnetv1.IngressTLSApplyConfiguration
+		issued []string
+	)
 	for _, inf := range cc.Network.GetInterfaces() {
 		pub := inf.GetPublic()
 		if pub.GetEnabled() {
 			switch m := pub.GetMethod().GetKind().(type) {
 			case *capsule.RoutingMethod_Ingress_:
+				pathPrefix := m.Ingress.GetPathPrefix()
+				if pathPrefix == "" {
+					pathPrefix = "/"
+				}
+
 				rules = append(rules, acsnetv1.IngressRule().
 					WithHost(m.Ingress.GetHost()).
 					WithHTTP(acsnetv1.HTTPIngressRuleValue().
 						WithPaths(acsnetv1.HTTPIngressPath().
 							WithPathType(netv1.PathTypePrefix).
-							WithPath("/").
+							WithPath(pathPrefix).
 							WithBackend(acsnetv1.IngressBackend().
 								WithService(acsnetv1.IngressServiceBackend().
 									WithName(capsuleID).
@@ -238,21 +360,46 @@ func (c *Client) reconcileIngress(ctx context.Context, capsuleID, namespace stri
 						),
 					),
 				)
+
+				if !m.Ingress.GetTls() {
+					continue
+				}
+
+				if m.Ingress.GetCertificate() != nil {
+					tls = append(tls, acsnetv1.IngressTLS().
+						WithHosts(m.Ingress.GetHost()).
+						WithSecretName(cluster.TLSSecretName(capsuleID, inf.GetName())),
+					)
+				} else if clusterIssuer != "" {
+					issued = append(issued, m.Ingress.GetHost())
+				} else {
+					// Served with the default certificate of the ingress
+					// controller.
+					tls = append(tls, acsnetv1.IngressTLS().
+						WithHosts(m.Ingress.GetHost()),
+					)
+				}
 			}
 		}
 	}
 
 	ing := acsnetv1.Ingress(capsuleID, namespace).
-		WithLabels(commonLabels(capsuleID, cc)).
-		WithSpec(acsnetv1.IngressSpec().
-			WithRules(rules...),
-		)
+		WithLabels(commonLabels(capsuleID, cc))
 
-	_, err := c.cs.NetworkingV1().Ingresses(namespace).Apply(ctx, ing, applyOpts())
-	if err != nil {
-		return fmt.Errorf("could not apply Ingress: %w", err)
+	if len(issued) > 0 {
+		ing.WithAnnotations(map[string]string{
+			"cert-manager.io/cluster-issuer": clusterIssuer,
+		})
+		tls = append(tls, acsnetv1.IngressTLS().
+			WithHosts(issued...).
+			WithSecretName(fmt.Sprintf("%s-tls", capsuleID)),
+		)
 	}
-	return nil
+
+	return ing.WithSpec(acsnetv1.IngressSpec().
+		WithRules(rules...).
+		WithTLS(tls...),
+	)
 }
 
 func (c *Client) reconcileService(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
@@ -260,6 +407,15 @@ func (c *Client) reconcileService(ctx context.Context, capsuleID, namespace stri
 		return c.deleteService(ctx, capsuleID, namespace)
 	}
 
+	s := createService(capsuleID, namespace, cc)
+	_, err := c.cs.CoreV1().Services(namespace).Apply(ctx, s, applyOpts())
+	if err != nil {
+		return fmt.Errorf("could not apply Service: %w", err)
+	}
+	return nil
+}
+
+func createService(capsuleID, namespace string, cc *cluster.Capsule) *acsv1.ServiceApplyConfiguration {
 	infs := cc.Network.GetInterfaces()
 	ports := make([]*acsv1.ServicePortApplyConfiguration, len(infs))
 
@@ -270,19 +426,13 @@ func (c *Client) reconcileService(ctx context.Context, capsuleID, namespace stri
 			WithTargetPort(intstr.FromString(inf.GetName()))
 	}
 
-	s := acsv1.Service(capsuleID, namespace).
+	return acsv1.Service(capsuleID, namespace).
 		WithLabels(commonLabels(capsuleID, cc)).
 		WithSpec(acsv1.ServiceSpec().
 			WithSelector(selectorLabels(capsuleID)).
 			WithPorts(ports...).
 			WithType(v1.ServiceTypeClusterIP),
 		)
-
-	_, err := c.cs.CoreV1().Services(namespace).Apply(ctx, s, applyOpts())
-	if err != nil {
-		return fmt.Errorf("could not apply Service: %w", err)
-	}
-	return nil
 }
 
 func (c *Client) reconcileEnvSecret(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
@@ -290,27 +440,47 @@ func (c *Client) reconcileEnvSecret(ctx context.Context, capsuleID, namespace st
 		return c.deleteEnvSecret(ctx, capsuleID, namespace)
 	}
 
-	s := acsv1.Secret(capsuleID, namespace).
-		WithLabels(commonLabels(capsuleID, cc)).
-		WithStringData(cc.ContainerSettings.GetEnvironmentVariables())
-
 	_, err := c.cs.CoreV1().
 		Secrets(namespace).
-		Apply(ctx, s, applyOpts())
+		Apply(ctx, createEnvSecret(capsuleID, namespace, cc), applyOpts())
 	if err != nil {
 		return fmt.Errorf("could not apply Secret: %w", err)
 	}
 	return nil
 }
 
+func createEnvSecret(capsuleID, namespace string, cc *cluster.Capsule) *acsv1.SecretApplyConfiguration {
+	return acsv1.Secret(capsuleID, namespace).
+		WithLabels(commonLabels(capsuleID, cc)).
+		WithStringData(cc.ContainerSettings.GetEnvironmentVariables())
+}
+
 func (c *Client) reconcileConfigFileMount(ctx context.Context, capsuleID, namespace string, cc *cluster.Capsule) error {
 	if len(cc.ConfigFiles) == 0 {
 		return c.deleteConfigMap(ctx, capsuleID, namespace)
 	}
 
+	cms, err := createConfigFileMaps(namespace, cc)
+	if err != nil {
+		return err
+	}
+
+	for _, cm := range cms {
+		_, err := c.cs.CoreV1().
+			ConfigMaps(namespace).
+			Apply(ctx, cm, applyOpts())
+		if err != nil {
+			return fmt.Errorf("could not apply ConfigMap: %w", err)
+		}
+	}
+	return nil
+}
+
+func createConfigFileMaps(namespace string, cc *cluster.Capsule) ([]*acsv1.ConfigMapApplyConfiguration, error) {
+	var cms []*acsv1.ConfigMapApplyConfiguration
 	for _, cf := range cc.ConfigFiles {
 		if cf.GetPath() == "" {
-			return fmt.Errorf("config file mount path cannot be empty")
+			return nil, fmt.Errorf("config file mount path cannot be empty")
 		}
 
 		fileName := path.Base(cf.GetPath())
@@ -319,32 +489,37 @@ func (c *Client) reconcileConfigFileMount(ctx context.Context, capsuleID, namesp
 		}
 
 		cmName := fmt.Sprintf("cfg%s", strings.ReplaceAll(strings.ReplaceAll(cf.GetPath(), "/", "-"), ".", "-"))
-		cm := acsv1.ConfigMap(cmName, namespace).
-			WithBinaryData(file)
-
-		_, err := c.cs.CoreV1().
-			ConfigMaps(namespace).
-			Apply(ctx, cm, applyOpts())
-		if err != nil {
-			return fmt.Errorf("could not apply ConfigMap: %w", err)
-		}
+		cms = append(cms, acsv1.ConfigMap(cmName, namespace).
+			WithBinaryData(file))
 	}
-	return nil
+	return cms, nil
 }
 
 func (c *Client) reconcileDeployment(ctx context.Context, capsuleID, namespace string, usePullSecret bool, cc *cluster.Capsule) error {
-	cons := []*acsv1.ContainerApplyConfiguration{
-		createContainer(capsuleID, cc),
+	d, err := createDeployment(ctx, capsuleID, namespace, usePullSecret, capsuleID, cc)
+	if err != nil {
+		return err
 	}
 
-	if hasInterfaces(cc) {
-		con, err := createProxyContainer(capsuleID, cc)
+	if hasAutoscaler(cc) {
+		replicas, err := c.autoscaledReplicas(ctx, capsuleID, namespace, cc)
 		if err != nil {
 			return err
 		}
-		cons = append(cons, con)
+		d.Spec.WithReplicas(replicas)
 	}
 
+	if _, err := c.cs.AppsV1().
+		Deployments(namespace).
+		Apply(ctx, d, applyOpts()); err != nil {
+		return fmt.Errorf("could not apply Deployment: %w", err)
+	}
+	return nil
+}
+
+// configFileVolumes returns the volumes of the ConfigMaps holding the config
+// files of the capsule, as mounted by createContainer.
+func configFileVolumes(cc *cluster.Capsule) []*acsv1.VolumeApplyConfiguration {
 	var volumes []*acsv1.VolumeApplyConfiguration
 	if hasConfigFileMount(cc) {
 		for _, cf := range cc.ConfigFiles {
@@ -358,6 +533,30 @@ func (c *Client) reconcileDeployment(ctx context.Context, capsuleID, namespace s
 			volumes = append(volumes, vol)
 		}
 	}
+	return volumes
+}
+
+func createDeployment(
+	ctx context.Context,
+	capsuleID,
+	namespace string,
+	usePullSecret bool,
+	envSecret string,
+	cc *cluster.Capsule,
+) (*acsappsv1.DeploymentApplyConfiguration, error) {
+	cons := []*acsv1.ContainerApplyConfiguration{
+		createContainer(capsuleID, envSecret, cc),
+	}
+
+	if hasInterfaces(cc) {
+		con, err := createProxyContainer(capsuleID, cc)
+		if err != nil {
+			return nil, err
+		}
+		cons = append(cons, con)
+	}
+
+	volumes := configFileVolumes(cc)
 
 	d := acsappsv1.Deployment(capsuleID, namespace).
 		WithLabels(commonLabels(capsuleID, cc)).
@@ -384,7 +583,7 @@ func (c *Client) reconcileDeployment(ctx context.Context, capsuleID, namespace s
 	if hasInterfaces(cc) {
 		cfg, err := createProxyConfig(ctx, cc)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		h := hashSecretData(cfg)
@@ -401,13 +600,7 @@ func (c *Client) reconcileDeployment(ctx context.Context, capsuleID, namespace s
 		})
 	}
 
-	_, err := c.cs.AppsV1().
-		Deployments(namespace).
-		Apply(ctx, d, applyOpts())
-	if err != nil {
-		return fmt.Errorf("could not apply Deployment: %w", err)
-	}
-	return nil
+	return d, nil
 }
 
 func makeResources(cc *cluster.Capsule) *acsv1.ResourceRequirementsApplyConfiguration {
@@ -441,7 +634,7 @@ func fillResourceList(r *capsule.ResourceList, list v1.ResourceList) {
 	}
 }
 
-func createContainer(capsuleID string, cc *cluster.Capsule) *acsv1.ContainerApplyConfiguration {
+func createContainer(capsuleID, envSecret string, cc *cluster.Capsule) *acsv1.ContainerApplyConfiguration {
 	con := acsv1.Container().
 		WithName(capsuleID).
 		WithImage(cc.Image).
@@ -454,10 +647,21 @@ func createContainer(capsuleID string, cc *cluster.Capsule) *acsv1.ContainerAppl
 		con.WithCommand(cc.ContainerSettings.GetCommand())
 	}
 
+	probes := cc.ContainerSettings.GetProbes()
+	if p := makeProbe(probes.GetLiveness()); p != nil {
+		con.WithLivenessProbe(p)
+	}
+	if p := makeProbe(probes.GetReadiness()); p != nil {
+		con.WithReadinessProbe(p)
+	}
+	if p := makeProbe(probes.GetStartup()); p != nil {
+		con.WithStartupProbe(p)
+	}
+
 	if hasEnvSecret(cc) {
 		con.WithEnvFrom(acsv1.EnvFromSource().
 			WithSecretRef(acsv1.SecretEnvSource().
-				WithName(capsuleID),
+				WithName(envSecret),
 			),
 		)
 	}
@@ -511,7 +715,7 @@ func createProxyContainer(capsuleID string, cc *cluster.Capsule) (*acsv1.Contain
 		WithResources(acsv1.ResourceRequirements().WithRequests(rl))
 
 	infs := cc.Network.GetInterfaces()
-	pps, err := createProxyPorts(infs)
+	pps, err := cluster.ProxyPorts(infs)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +61,7 @@ func newDefault() Config {
 			Session:          defaultRepositoryStore(),
 			User:             defaultRepositoryStore(),
 			VerificationCode: defaultRepositoryStore(),
+			Webhook:          defaultRepositoryStore(),
 		},
 
 		OAuth: OAuth{
@@ -91,6 +91,7 @@ type Repository struct {
 	Session          RepositoryStore       `mapstructure:"session"`
 	User             RepositoryStore       `mapstructure:"user"`
 	VerificationCode RepositoryStore       `mapstructure:"verification_code"`
+	Webhook          RepositoryStore       `mapstructure:"webhook"`
 }
 
 type RepositoryStore struct {
@@ -120,6 +121,14 @@ type OAuthClientCredentials struct {
 type Cluster struct {
 	Type        ClusterType `mapstructure:"type"`
 	DevRegistry DevRegistry `mapstructure:"dev_registry"`
+	CertManager CertManager `mapstructure:"cert_manager"`
+}
+
+type CertManager struct {
+	// ClusterIssuer is the cert-manager ClusterIssuer issuing the
+	// certificates of TLS ingress without a certificate of their own. The
+	// certificates are not issued if empty.
+	ClusterIssuer string `mapstructure:"cluster_issuer"`
 }
 
 type DevRegistry struct {
@@ -18,14 +18,20 @@ package controller
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
+	"path"
 	"reflect"
+	"strconv"
+	"strings"
 
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	v1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -33,27 +39,44 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	"github.com/go-logr/logr"
+	apicapsule "github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/proxy"
+	"github.com/rigdev/rig/internal/build"
+	"github.com/rigdev/rig/internal/gateway/cluster"
 	rigdevv1alpha1 "github.com/rigdev/rig/pkg/api/v1alpha1"
 	"github.com/rigdev/rig/pkg/ptr"
+	"google.golang.org/protobuf/encoding/protojson"
 )
 
 // CapsuleReconciler reconciles a Capsule object
 type CapsuleReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// ClusterIssuer is the cert-manager ClusterIssuer issuing the
+	// certificates of TLS ingress without a secret of their own. The
+	// certificates are not issued if empty.
+	ClusterIssuer string
 }
 
 const (
 	labelRigDevCapsule = "rig.dev/capsule"
 	finalizer          = "rig.dev/finalizer"
+
+	annotationProxyConfigSHA = "rig.dev/proxy-config-sha"
+	annotationClusterIssuer  = "cert-manager.io/cluster-issuer"
+
+	proxyContainerName = "rig-proxy"
+	proxyConfigEnv     = "RIG_PROXY_CONFIG"
 )
 
 //+kubebuilder:rbac:groups=rig.dev,resources=capsules,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rig.dev,resources=capsules/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=rig.dev,resources=capsules/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
 
 // Reconcile compares the state specified by the Capsule object against the
 // actual cluster state, and then performs operations to make the cluster state
@@ -70,9 +93,29 @@ func (r *CapsuleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, fmt.Errorf("could not fetch Capsule: %w", err)
 	}
 
+	result, err := r.reconcileResources(ctx, req, log, capsule)
+	if serr := r.updateStatus(ctx, log, capsule, err); serr != nil && err == nil {
+		return ctrl.Result{}, serr
+	}
+
+	return result, err
+}
+
+func (r *CapsuleReconciler) reconcileResources(
+	ctx context.Context,
+	req ctrl.Request,
+	log logr.Logger,
+	capsule *rigdevv1alpha1.Capsule,
+) (ctrl.Result, error) {
+	if result, err := r.reconcileProxySecret(ctx, req, log, capsule); err != nil {
+		return result, err
+	}
 	if result, err := r.reconcileDeployment(ctx, req, log, capsule); err != nil {
 		return result, err
 	}
+	if result, err := r.reconcileHorizontalPodAutoscaler(ctx, req, log, capsule); err != nil {
+		return result, err
+	}
 	if result, err := r.reconcileService(ctx, req, log, capsule); err != nil {
 		return result, err
 	}
@@ -115,6 +158,11 @@ func (r *CapsuleReconciler) reconcileDeployment(
 		return ctrl.Result{}, errors.New("found existing deployment not owned by capsule")
 	}
 
+	if capsule.Spec.Autoscaler != nil && existingDeploy.Spec.Replicas != nil {
+		// The replicas are scaled by the autoscaler.
+		deploy.Spec.Replicas = existingDeploy.Spec.Replicas
+	}
+
 	if !reflect.DeepEqual(existingDeploy.Spec, deploy.Spec) {
 		log.Info("updating deployment")
 		if err := r.Update(ctx, deploy); err != nil {
@@ -129,12 +177,23 @@ func createDeployment(
 	capsule *rigdevv1alpha1.Capsule,
 	scheme *runtime.Scheme,
 ) (*appsv1.Deployment, error) {
+	var ports []v1.ContainerPort
+	for _, inf := range capsule.Spec.Interfaces {
+		ports = append(ports, v1.ContainerPort{
+			Name:          fmt.Sprintf("port-%d", inf.Port),
+			ContainerPort: inf.Port,
+		})
+	}
+
+	volumes, volumeMounts := createFileVolumes(capsule)
+
 	d := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      capsule.Name,
 			Namespace: capsule.Namespace,
 		},
 		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.New(capsule.Spec.Replicas),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					labelRigDevCapsule: capsule.Name,
@@ -151,6 +210,8 @@ func createDeployment(
 						{
 							Name:  capsule.Name,
 							Image: capsule.Spec.Image,
+							Args:  capsule.Spec.Args,
+							Ports: ports,
 							EnvFrom: []v1.EnvFromSource{
 								{
 									SecretRef: &v1.SecretEnvSource{
@@ -169,15 +230,49 @@ func createDeployment(
 									},
 								},
 							},
-							VolumeMounts: []v1.VolumeMount{},
+							VolumeMounts: volumeMounts,
 						},
 					},
-					Volumes: []v1.Volume{},
+					Volumes: volumes,
 				},
 			},
 		},
 	}
 
+	c := &d.Spec.Template.Spec.Containers[0]
+	if capsule.Spec.Command != "" {
+		c.Command = []string{capsule.Spec.Command}
+	}
+	if capsule.Spec.Resources != nil {
+		c.Resources = *capsule.Spec.Resources
+	}
+	if capsule.Spec.ImagePullSecret != nil {
+		d.Spec.Template.Spec.ImagePullSecrets = []v1.LocalObjectReference{*capsule.Spec.ImagePullSecret}
+	}
+
+	if ps := capsule.Spec.Probes; ps != nil {
+		c.LivenessProbe = createProbe(ps.Liveness)
+		c.ReadinessProbe = createProbe(ps.Readiness)
+		c.StartupProbe = createProbe(ps.Startup)
+	}
+
+	if len(capsule.Spec.Interfaces) > 0 {
+		con, err := createProxyContainer(capsule)
+		if err != nil {
+			return nil, err
+		}
+		d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, con)
+
+		// Restart the instances when the proxy config changes.
+		cfg, err := createProxyConfig(capsule)
+		if err != nil {
+			return nil, err
+		}
+		d.Spec.Template.Annotations = map[string]string{
+			annotationProxyConfigSHA: fmt.Sprintf("%x", sha256.Sum256([]byte(cfg))),
+		}
+	}
+
 	if err := controllerutil.SetControllerReference(capsule, d, scheme); err != nil {
 		return nil, fmt.Errorf("could not set owner reference on deployment: %w", err)
 	}
@@ -185,6 +280,367 @@ func createDeployment(
 	return d, nil
 }
 
+// createFileVolumes returns the volumes of the files of the capsule, and how
+// to mount them in the capsule container.
+func createFileVolumes(capsule *rigdevv1alpha1.Capsule) ([]v1.Volume, []v1.VolumeMount) {
+	var (
+		volumes []v1.Volume
+		mounts  []v1.VolumeMount
+	)
+	for _, f := range capsule.Spec.Files {
+		name := fmt.Sprintf("cfg%s", strings.ReplaceAll(strings.ReplaceAll(f.Path, "/", "-"), ".", "-"))
+		fileName := path.Base(f.Path)
+
+		var vs v1.VolumeSource
+		switch {
+		case f.ConfigMap != nil:
+			vs.ConfigMap = &v1.ConfigMapVolumeSource{
+				LocalObjectReference: v1.LocalObjectReference{
+					Name: f.ConfigMap.Name,
+				},
+				Items: []v1.KeyToPath{{Key: f.ConfigMap.Key, Path: fileName}},
+			}
+		case f.Secret != nil:
+			vs.Secret = &v1.SecretVolumeSource{
+				SecretName: f.Secret.Name,
+				Items:      []v1.KeyToPath{{Key: f.Secret.Key, Path: fileName}},
+			}
+		default:
+			continue
+		}
+
+		volumes = append(volumes, v1.Volume{
+			Name:         name,
+			VolumeSource: vs,
+		})
+		mounts = append(mounts, v1.VolumeMount{
+			Name:      name,
+			MountPath: f.Path,
+			SubPath:   fileName,
+			ReadOnly:  true,
+		})
+	}
+
+	return volumes, mounts
+}
+
+// proxyPorts returns the ports the rig-proxy sidecar listens on for each
+// interface of the capsule.
+func proxyPorts(capsule *rigdevv1alpha1.Capsule) ([]uint32, error) {
+	infs := make([]*apicapsule.Interface, len(capsule.Spec.Interfaces))
+	for i, inf := range capsule.Spec.Interfaces {
+		infs[i] = &apicapsule.Interface{
+			Name: inf.Name,
+			Port: uint32(inf.Port),
+		}
+	}
+
+	return cluster.ProxyPorts(infs)
+}
+
+// createProxyConfig returns the config of the rig-proxy sidecar, forwarding the
+// traffic of the interfaces to the capsule container.
+func createProxyConfig(capsule *rigdevv1alpha1.Capsule) (string, error) {
+	pps, err := proxyPorts(capsule)
+	if err != nil {
+		return "", err
+	}
+
+	cfg := &proxy.Config{
+		TargetHost: "localhost",
+		// Rig creates the capsules of a project in the namespace of the
+		// project.
+		ProjectId: capsule.Namespace,
+	}
+	for i, inf := range capsule.Spec.Interfaces {
+		cfg.Interfaces = append(cfg.Interfaces, &proxy.Interface{
+			SourcePort: pps[i],
+			TargetPort: uint32(inf.Port),
+			Layer:      proxy.Layer_LAYER_4,
+		})
+	}
+
+	bs, err := protojson.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.QuoteToASCII(string(bs)), nil
+}
+
+func createProxyContainer(capsule *rigdevv1alpha1.Capsule) (v1.Container, error) {
+	pps, err := proxyPorts(capsule)
+	if err != nil {
+		return v1.Container{}, err
+	}
+
+	// The services of the capsule route to the ports named by the interfaces.
+	var ports []v1.ContainerPort
+	for i, inf := range capsule.Spec.Interfaces {
+		ports = append(ports, v1.ContainerPort{
+			Name:          inf.Name,
+			ContainerPort: int32(pps[i]),
+		})
+	}
+
+	return v1.Container{
+		Name:    proxyContainerName,
+		Image:   fmt.Sprint("ghcr.io/rigdev/rig:", build.Version()),
+		Command: []string{"rig-proxy"},
+		Ports:   ports,
+		EnvFrom: []v1.EnvFromSource{
+			{
+				SecretRef: &v1.SecretEnvSource{
+					LocalObjectReference: v1.LocalObjectReference{
+						Name: proxySecretName(capsule),
+					},
+				},
+			},
+		},
+		Resources: v1.ResourceRequirements{
+			Requests: v1.ResourceList{
+				v1.ResourceCPU:    resource.MustParse("100m"),
+				v1.ResourceMemory: resource.MustParse("128Mi"),
+			},
+		},
+	}, nil
+}
+
+func proxySecretName(capsule *rigdevv1alpha1.Capsule) string {
+	return fmt.Sprintf("%s-proxy", capsule.Name)
+}
+
+func (r *CapsuleReconciler) reconcileProxySecret(
+	ctx context.Context,
+	req ctrl.Request,
+	log logr.Logger,
+	capsule *rigdevv1alpha1.Capsule,
+) (ctrl.Result, error) {
+	secret, err := createProxySecret(capsule, r.Scheme)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	nsName := types.NamespacedName{
+		Name:      proxySecretName(capsule),
+		Namespace: req.NamespacedName.Namespace,
+	}
+	existingSecret := &v1.Secret{}
+	if err := r.Get(ctx, nsName, existingSecret); err != nil {
+		if kerrors.IsNotFound(err) {
+			if len(capsule.Spec.Interfaces) == 0 {
+				return ctrl.Result{}, nil
+			}
+
+			log.Info("creating proxy secret")
+			if err := r.Create(ctx, secret); err != nil {
+				return ctrl.Result{}, fmt.Errorf("could not create proxy secret: %w", err)
+			}
+			existingSecret = secret
+		} else {
+			return ctrl.Result{}, fmt.Errorf("could not fetch proxy secret: %w", err)
+		}
+	}
+
+	if !IsOwnedBy(capsule, existingSecret) {
+		if len(capsule.Spec.Interfaces) == 0 {
+			log.Info("Found existing proxy secret not owned by capsule. Will not delete it.")
+		} else {
+			log.Info("Found existing proxy secret not owned by capsule. Will not update it.")
+			return ctrl.Result{}, errors.New("found existing proxy secret not owned by capsule")
+		}
+	} else {
+		if len(capsule.Spec.Interfaces) == 0 {
+			log.Info("deleting proxy secret")
+			if err := r.Delete(ctx, existingSecret); err != nil {
+				return ctrl.Result{}, fmt.Errorf("could not delete proxy secret: %w", err)
+			}
+		} else {
+			if !reflect.DeepEqual(existingSecret.Data, secret.Data) {
+				log.Info("updating proxy secret")
+				if err := r.Update(ctx, secret); err != nil {
+					return ctrl.Result{}, fmt.Errorf("could not update proxy secret: %w", err)
+				}
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func createProxySecret(
+	capsule *rigdevv1alpha1.Capsule,
+	scheme *runtime.Scheme,
+) (*v1.Secret, error) {
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      proxySecretName(capsule),
+			Namespace: capsule.Namespace,
+		},
+	}
+
+	if len(capsule.Spec.Interfaces) > 0 {
+		cfg, err := createProxyConfig(capsule)
+		if err != nil {
+			return nil, err
+		}
+		secret.Data = map[string][]byte{proxyConfigEnv: []byte(cfg)}
+	}
+
+	if err := controllerutil.SetControllerReference(capsule, secret, scheme); err != nil {
+		return nil, fmt.Errorf("could not set owner reference on proxy secret: %w", err)
+	}
+
+	return secret, nil
+}
+
+func createProbe(p *rigdevv1alpha1.CapsuleProbe) *v1.Probe {
+	if p == nil {
+		return nil
+	}
+
+	probe := &v1.Probe{
+		InitialDelaySeconds: p.InitialDelaySeconds,
+		PeriodSeconds:       p.PeriodSeconds,
+		TimeoutSeconds:      p.TimeoutSeconds,
+		FailureThreshold:    p.FailureThreshold,
+	}
+
+	switch {
+	case p.HTTP != nil:
+		path := p.HTTP.Path
+		if path == "" {
+			path = "/"
+		}
+		probe.HTTPGet = &v1.HTTPGetAction{
+			Port: intstr.FromInt(int(p.HTTP.Port)),
+			Path: path,
+		}
+	case p.TCP != nil:
+		probe.TCPSocket = &v1.TCPSocketAction{
+			Port: intstr.FromInt(int(p.TCP.Port)),
+		}
+	case p.GRPC != nil:
+		probe.GRPC = &v1.GRPCAction{
+			Port: p.GRPC.Port,
+		}
+		if p.GRPC.Service != "" {
+			probe.GRPC.Service = ptr.New(p.GRPC.Service)
+		}
+	case p.Exec != nil:
+		probe.Exec = &v1.ExecAction{
+			Command: p.Exec.Command,
+		}
+	default:
+		return nil
+	}
+
+	return probe
+}
+
+func (r *CapsuleReconciler) reconcileHorizontalPodAutoscaler(
+	ctx context.Context,
+	req ctrl.Request,
+	log logr.Logger,
+	capsule *rigdevv1alpha1.Capsule,
+) (ctrl.Result, error) {
+	hpa, err := createHorizontalPodAutoscaler(capsule, r.Scheme)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	existingHPA := &autoscalingv2.HorizontalPodAutoscaler{}
+	if err := r.Get(ctx, req.NamespacedName, existingHPA); err != nil {
+		if kerrors.IsNotFound(err) {
+			if capsule.Spec.Autoscaler == nil {
+				return ctrl.Result{}, nil
+			}
+
+			log.Info("creating horizontal pod autoscaler")
+			if err := r.Create(ctx, hpa); err != nil {
+				return ctrl.Result{}, fmt.Errorf("could not create horizontal pod autoscaler: %w", err)
+			}
+			existingHPA = hpa
+		} else {
+			return ctrl.Result{}, fmt.Errorf("could not fetch horizontal pod autoscaler: %w", err)
+		}
+	}
+
+	if !IsOwnedBy(capsule, existingHPA) {
+		if capsule.Spec.Autoscaler == nil {
+			log.Info("Found existing horizontal pod autoscaler not owned by capsule. Will not delete it.")
+		} else {
+			log.Info("Found existing horizontal pod autoscaler not owned by capsule. Will not update it.")
+			return ctrl.Result{}, errors.New("found existing horizontal pod autoscaler not owned by capsule")
+		}
+	} else {
+		if capsule.Spec.Autoscaler == nil {
+			log.Info("deleting horizontal pod autoscaler")
+			if err := r.Delete(ctx, existingHPA); err != nil {
+				return ctrl.Result{}, fmt.Errorf("could not delete horizontal pod autoscaler: %w", err)
+			}
+		} else {
+			if !reflect.DeepEqual(existingHPA.Spec, hpa.Spec) {
+				log.Info("updating horizontal pod autoscaler")
+				if err := r.Update(ctx, hpa); err != nil {
+					return ctrl.Result{}, fmt.Errorf("could not update horizontal pod autoscaler: %w", err)
+				}
+			}
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+func createHorizontalPodAutoscaler(
+	capsule *rigdevv1alpha1.Capsule,
+	scheme *runtime.Scheme,
+) (*autoscalingv2.HorizontalPodAutoscaler, error) {
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      capsule.Name,
+			Namespace: capsule.Namespace,
+		},
+		Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+			ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+				APIVersion: appsv1.SchemeGroupVersion.String(),
+				Kind:       "Deployment",
+				Name:       capsule.Name,
+			},
+		},
+	}
+
+	if a := capsule.Spec.Autoscaler; a != nil {
+		hpa.Spec.MinReplicas = ptr.New(a.MinReplicas)
+		hpa.Spec.MaxReplicas = a.MaxReplicas
+		if a.CPUUtilization > 0 {
+			hpa.Spec.Metrics = append(hpa.Spec.Metrics, resourceMetric(v1.ResourceCPU, a.CPUUtilization))
+		}
+		if a.MemoryUtilization > 0 {
+			hpa.Spec.Metrics = append(hpa.Spec.Metrics, resourceMetric(v1.ResourceMemory, a.MemoryUtilization))
+		}
+	}
+
+	if err := controllerutil.SetControllerReference(capsule, hpa, scheme); err != nil {
+		return nil, fmt.Errorf("could not set owner reference on horizontal pod autoscaler: %w", err)
+	}
+
+	return hpa, nil
+}
+
+func resourceMetric(name v1.ResourceName, utilization int32) autoscalingv2.MetricSpec {
+	return autoscalingv2.MetricSpec{
+		Type: autoscalingv2.ResourceMetricSourceType,
+		Resource: &autoscalingv2.ResourceMetricSource{
+			Name: name,
+			Target: autoscalingv2.MetricTarget{
+				Type:               autoscalingv2.UtilizationMetricType,
+				AverageUtilization: ptr.New(utilization),
+			},
+		},
+	}
+}
+
 func (r *CapsuleReconciler) reconcileService(
 	ctx context.Context,
 	req ctrl.Request,
@@ -276,7 +732,7 @@ func (r *CapsuleReconciler) reconcileIngress(
 	log logr.Logger,
 	capsule *rigdevv1alpha1.Capsule,
 ) (ctrl.Result, error) {
-	ing, err := createIngress(capsule, r.Scheme)
+	ing, err := createIngress(capsule, r.ClusterIssuer, r.Scheme)
 	if err != nil {
 		return ctrl.Result{}, err
 	}
@@ -307,7 +763,8 @@ func (r *CapsuleReconciler) reconcileIngress(
 		}
 	} else {
 		if capsuleHasIngress(capsule) {
-			if !reflect.DeepEqual(existingIng.Spec, ing.Spec) {
+			if !reflect.DeepEqual(existingIng.Spec, ing.Spec) ||
+				existingIng.Annotations[annotationClusterIssuer] != ing.Annotations[annotationClusterIssuer] {
 				log.Info("updating ingress")
 				if err := r.Update(ctx, ing); err != nil {
 					return ctrl.Result{}, fmt.Errorf("could not update ingress: %w", err)
@@ -335,6 +792,7 @@ func capsuleHasIngress(capsule *rigdevv1alpha1.Capsule) bool {
 
 func createIngress(
 	capsule *rigdevv1alpha1.Capsule,
+	clusterIssuer string,
 	scheme *runtime.Scheme,
 ) (*netv1.Ingress, error) {
 	ing := &netv1.Ingress{
@@ -344,9 +802,14 @@ func createIngress(
 		},
 	}
 
+	var issued []string
 	for _, inf := range capsule.Spec.Interfaces {
 		if inf.Public != nil && inf.Public.Ingress != nil {
-			// TODO: setup TLS
+			pathPrefix := inf.Public.Ingress.PathPrefix
+			if pathPrefix == "" {
+				pathPrefix = "/"
+			}
+
 			ing.Spec.Rules = append(ing.Spec.Rules, netv1.IngressRule{
 				Host: inf.Public.Ingress.Host,
 				IngressRuleValue: netv1.IngressRuleValue{
@@ -354,7 +817,7 @@ func createIngress(
 						Paths: []netv1.HTTPIngressPath{
 							{
 								PathType: ptr.New(netv1.PathTypePrefix),
-								Path:     "/",
+								Path:     pathPrefix,
 								Backend: netv1.IngressBackend{
 									Service: &netv1.IngressServiceBackend{
 										Name: capsule.Name,
@@ -368,7 +831,35 @@ func createIngress(
 					},
 				},
 			})
+
+			tls := inf.Public.Ingress.TLS
+			switch {
+			case tls == nil:
+			case tls.SecretName != "":
+				ing.Spec.TLS = append(ing.Spec.TLS, netv1.IngressTLS{
+					Hosts:      []string{inf.Public.Ingress.Host},
+					SecretName: tls.SecretName,
+				})
+			case clusterIssuer != "":
+				issued = append(issued, inf.Public.Ingress.Host)
+			default:
+				// Served with the default certificate of the ingress
+				// controller.
+				ing.Spec.TLS = append(ing.Spec.TLS, netv1.IngressTLS{
+					Hosts: []string{inf.Public.Ingress.Host},
+				})
+			}
+		}
+	}
+
+	if len(issued) > 0 {
+		ing.Annotations = map[string]string{
+			annotationClusterIssuer: clusterIssuer,
 		}
+		ing.Spec.TLS = append(ing.Spec.TLS, netv1.IngressTLS{
+			Hosts:      issued,
+			SecretName: fmt.Sprintf("%s-tls", capsule.Name),
+		})
 	}
 
 	if err := controllerutil.SetControllerReference(capsule, ing, scheme); err != nil {
@@ -483,5 +974,11 @@ func createLoadBalancer(
 func (r *CapsuleReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&rigdevv1alpha1.Capsule{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}).
+		Owns(&v1.Service{}).
+		Owns(&v1.Secret{}).
+		Owns(&netv1.Ingress{}).
+		Watches(&v1.Pod{}, handler.EnqueueRequestsFromMapFunc(podToCapsule)).
 		Complete(r)
 }
@@ -0,0 +1,287 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	netv1 "k8s.io/api/networking/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	rigdevv1alpha1 "github.com/rigdev/rig/pkg/api/v1alpha1"
+)
+
+// updateStatus writes the observed state of the resources of the capsule to
+// its status. reconcileErr is the error, if any, from reconciling the
+// resources, which marks the capsule as degraded.
+func (r *CapsuleReconciler) updateStatus(
+	ctx context.Context,
+	log logr.Logger,
+	capsule *rigdevv1alpha1.Capsule,
+	reconcileErr error,
+) error {
+	status := rigdevv1alpha1.CapsuleStatus{
+		ObservedGeneration: capsule.Generation,
+		Conditions:         append([]metav1.Condition(nil), capsule.Status.Conditions...),
+		Image:              capsule.Status.Image,
+		Replicas:           capsule.Spec.Replicas,
+	}
+
+	nsName := types.NamespacedName{Name: capsule.Name, Namespace: capsule.Namespace}
+
+	progressing := true
+	deploy := &appsv1.Deployment{}
+	if err := r.Get(ctx, nsName, deploy); err != nil {
+		if !kerrors.IsNotFound(err) {
+			return fmt.Errorf("could not fetch deployment: %w", err)
+		}
+	} else if IsOwnedBy(capsule, deploy) {
+		if deploy.Spec.Replicas != nil {
+			// The replicas may be scaled by the autoscaler.
+			status.Replicas = *deploy.Spec.Replicas
+		}
+		status.ReadyReplicas = deploy.Status.ReadyReplicas
+
+		progressing = deploy.Status.ObservedGeneration < deploy.Generation ||
+			deploy.Status.UpdatedReplicas < status.Replicas ||
+			deploy.Status.Replicas > deploy.Status.UpdatedReplicas
+		if !progressing {
+			status.Image = deploy.Spec.Template.Spec.Containers[0].Image
+		}
+	}
+
+	instances, err := r.instanceStatuses(ctx, capsule)
+	if err != nil {
+		return err
+	}
+	status.Instances = instances
+
+	interfaces, err := r.interfaceStatuses(ctx, capsule)
+	if err != nil {
+		return err
+	}
+	status.Interfaces = interfaces
+
+	crashLooping := 0
+	for _, i := range instances {
+		if i.CrashLooping {
+			crashLooping++
+		}
+	}
+
+	gen := capsule.Generation
+	switch {
+	case reconcileErr != nil:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "ReconcileFailed",
+			Message:            reconcileErr.Error(),
+		})
+	case crashLooping > 0:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionDegraded,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "InstancesCrashLooping",
+			Message:            fmt.Sprintf("%d instances are crash looping", crashLooping),
+		})
+	default:
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionDegraded,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "AsExpected",
+		})
+	}
+
+	if progressing {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionProgressing,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "RolloutInProgress",
+			Message:            "waiting for instances to be updated",
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionProgressing,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "RolloutComplete",
+		})
+	}
+
+	if !progressing && status.ReadyReplicas >= status.Replicas {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionReady,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: gen,
+			Reason:             "InstancesReady",
+		})
+	} else {
+		meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+			Type:               rigdevv1alpha1.CapsuleConditionReady,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: gen,
+			Reason:             "InstancesNotReady",
+			Message:            fmt.Sprintf("%d of %d instances are ready", status.ReadyReplicas, status.Replicas),
+		})
+	}
+
+	if reflect.DeepEqual(capsule.Status, status) {
+		return nil
+	}
+
+	log.Info("updating capsule status")
+	capsule.Status = status
+	if err := r.Status().Update(ctx, capsule); err != nil {
+		return fmt.Errorf("could not update capsule status: %w", err)
+	}
+
+	return nil
+}
+
+func (r *CapsuleReconciler) instanceStatuses(
+	ctx context.Context,
+	capsule *rigdevv1alpha1.Capsule,
+) ([]rigdevv1alpha1.CapsuleInstanceStatus, error) {
+	pods := &v1.PodList{}
+	if err := r.List(ctx, pods,
+		client.InNamespace(capsule.Namespace),
+		client.MatchingLabels{labelRigDevCapsule: capsule.Name},
+	); err != nil {
+		return nil, fmt.Errorf("could not list pods: %w", err)
+	}
+
+	sort.Slice(pods.Items, func(i, j int) bool {
+		return pods.Items[i].Name < pods.Items[j].Name
+	})
+
+	var instances []rigdevv1alpha1.CapsuleInstanceStatus
+	for _, pod := range pods.Items {
+		i := rigdevv1alpha1.CapsuleInstanceStatus{
+			Name:      pod.Name,
+			Phase:     pod.Status.Phase,
+			CreatedAt: pod.CreationTimestamp,
+		}
+
+		for _, c := range pod.Spec.Containers {
+			if c.Name == capsule.Name {
+				i.Image = c.Image
+			}
+		}
+
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != capsule.Name {
+				continue
+			}
+
+			i.Ready = cs.Ready
+			i.RestartCount = cs.RestartCount
+			i.CrashLooping = cs.State.Waiting != nil && cs.State.Waiting.Reason == "CrashLoopBackOff"
+			if cs.State.Running != nil {
+				i.StartedAt = cs.State.Running.StartedAt.DeepCopy()
+			}
+			if cs.State.Terminated != nil {
+				i.FinishedAt = cs.State.Terminated.FinishedAt.DeepCopy()
+			}
+		}
+
+		instances = append(instances, i)
+	}
+
+	return instances, nil
+}
+
+func (r *CapsuleReconciler) interfaceStatuses(
+	ctx context.Context,
+	capsule *rigdevv1alpha1.Capsule,
+) ([]rigdevv1alpha1.CapsuleInterfaceStatus, error) {
+	var (
+		ingressAddrs []string
+		lbAddrs      []string
+	)
+
+	if capsuleHasIngress(capsule) {
+		ing := &netv1.Ingress{}
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      capsule.Name,
+			Namespace: capsule.Namespace,
+		}, ing); err != nil && !kerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("could not fetch ingress: %w", err)
+		}
+		for _, i := range ing.Status.LoadBalancer.Ingress {
+			ingressAddrs = appendAddress(ingressAddrs, i.Hostname, i.IP)
+		}
+	}
+
+	if capsuleHasLoadBalancer(capsule) {
+		svc := &v1.Service{}
+		if err := r.Get(ctx, types.NamespacedName{
+			Name:      fmt.Sprintf("%s-lb", capsule.Name),
+			Namespace: capsule.Namespace,
+		}, svc); err != nil && !kerrors.IsNotFound(err) {
+			return nil, fmt.Errorf("could not fetch loadbalancer service: %w", err)
+		}
+		for _, i := range svc.Status.LoadBalancer.Ingress {
+			lbAddrs = appendAddress(lbAddrs, i.Hostname, i.IP)
+		}
+	}
+
+	var interfaces []rigdevv1alpha1.CapsuleInterfaceStatus
+	for _, inf := range capsule.Spec.Interfaces {
+		if inf.Public == nil {
+			continue
+		}
+
+		s := rigdevv1alpha1.CapsuleInterfaceStatus{Name: inf.Name}
+		switch {
+		case inf.Public.Ingress != nil:
+			s.Addresses = ingressAddrs
+		case inf.Public.LoadBalancer != nil:
+			for _, addr := range lbAddrs {
+				s.Addresses = append(s.Addresses, fmt.Sprintf("%s:%d", addr, inf.Public.LoadBalancer.Port))
+			}
+		}
+		interfaces = append(interfaces, s)
+	}
+
+	return interfaces, nil
+}
+
+func appendAddress(addrs []string, hostname, ip string) []string {
+	if hostname != "" {
+		return append(addrs, hostname)
+	}
+	if ip != "" {
+		return append(addrs, ip)
+	}
+	return addrs
+}
+
+// podToCapsule enqueues the capsule of a pod, so the instances of the capsule
+// status follow the pods.
+func podToCapsule(ctx context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[labelRigDevCapsule]
+	if !ok {
+		return nil
+	}
+
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{
+			Name:      name,
+			Namespace: obj.GetNamespace(),
+		},
+	}}
+}
@@ -19,6 +19,25 @@ type ConfigGateway interface {
 	UpdateCapsuleConfig(ctx context.Context, cfg *v1alpha1.Capsule) error
 	ListCapsuleConfigs(ctx context.Context, pagination *model.Pagination) (iterator.Iterator[*v1alpha1.Capsule], int64, error)
 	DeleteCapsuleConfig(ctx context.Context, capsuleID string) error
+	// DryRunCapsuleConfig returns the cluster objects that would be applied for
+	// cfg, without applying them. The given files are used in place of the
+	// stored files with the same name.
+	DryRunCapsuleConfig(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, files []*v1.ConfigMap) ([]interface{}, error)
+
+	// UpsertCanary runs cfg as canary instances next to the stable instances of
+	// the capsule. The canary instances share the network of the capsule.
+	UpsertCanary(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string) error
+	// DeleteCanary removes all canary instances of the capsule.
+	DeleteCanary(ctx context.Context, capsuleID string) error
+
+	// StartHook runs the hook in a container with the image, environment
+	// variables and config files of cfg. Starting a hook that already exists
+	// does nothing.
+	StartHook(ctx context.Context, cfg *v1alpha1.Capsule, envs map[string]string, hook *Hook) error
+	// GetHookStatus returns if the hook instance has exited, and its exit code.
+	GetHookStatus(ctx context.Context, capsuleID, instanceID string) (bool, int32, error)
+	// DeleteHook removes the hook instance, stopping it if still running.
+	DeleteHook(ctx context.Context, capsuleID, instanceID string) error
 
 	SetEnvironmentVariables(ctx context.Context, capsuleID string, envs map[string]string) error
 	GetEnvironmentVariables(ctx context.Context, capsuleID string) (map[string]string, error)
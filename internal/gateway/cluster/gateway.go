@@ -2,6 +2,8 @@ package cluster
 
 import (
 	"context"
+	"fmt"
+	"io"
 
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/rigdev/rig/gen/go/proxy"
@@ -16,7 +18,9 @@ type Capsule struct {
 	ContainerSettings *capsule.ContainerSettings
 	Ports             []uint32
 	Replicas          uint32
-	Volumes           map[string]string
+	Autoscaler        *capsule.Autoscaler
+	CronJob           *capsule.CronJob
+	Volumes           []*capsule.Volume
 	Network           *capsule.Network
 	ConfigFiles       []*capsule.ConfigFile
 	Namespace         string
@@ -25,6 +29,46 @@ type Capsule struct {
 	RegistryAuth      *RegistryAuth
 }
 
+// HookKindTask is the kind of hooks run on demand. Several tasks of a capsule
+// may run at once, so starting a task does not remove the earlier tasks.
+const HookKindTask = "task"
+
+// Hook is a command run to completion with the image of a capsule.
+type Hook struct {
+	// InstanceID is the name of the instance running the hook.
+	InstanceID string
+	// Kind groups the hooks of a capsule. Starting a hook removes the earlier
+	// hooks of the capsule with the same kind, except for HookKindTask.
+	Kind    string
+	Command string
+	Args    []string
+}
+
+// TerminalSize is the size of a terminal, in characters.
+type TerminalSize struct {
+	Height uint16
+	Width  uint16
+}
+
+// ExecStreams are the input and output of a command executed in an instance.
+type ExecStreams struct {
+	// Stdin is the input of the command, or nil if it takes no input.
+	Stdin  io.Reader
+	Stdout io.Writer
+	// Stderr is not used if the command runs in a TTY.
+	Stderr io.Writer
+	TTY    bool
+	// Resize receives the size of the TTY whenever it changes.
+	Resize <-chan TerminalSize
+}
+
+// Conn is a connection forwarded to a port of an instance.
+type Conn interface {
+	io.ReadWriteCloser
+	// CloseWrite closes the sending side of the connection.
+	CloseWrite() error
+}
+
 type RegistryAuth struct {
 	Host           string
 	RegistrySecret *registry.Secret
@@ -35,15 +79,30 @@ type Gateway interface {
 	RestartInstance(ctx context.Context, capsuleID, instanceID string) error
 
 	Logs(ctx context.Context, capsuleID, instanceID string, follow bool) (iterator.Iterator[*capsule.Log], error)
+	// AccessLogs reads the access log of the requests to the instance, written
+	// by the proxy of the capsule.
+	AccessLogs(ctx context.Context, capsuleID, instanceID string, follow bool) (iterator.Iterator[*capsule.Log], error)
+	// Exec runs the command in the instance until it exits, and returns its
+	// exit code.
+	Exec(ctx context.Context, capsuleID, instanceID string, command []string, streams *ExecStreams) (int32, error)
+	// PortForward opens a connection to the port of the instance.
+	PortForward(ctx context.Context, capsuleID, instanceID string, port uint32) (Conn, error)
 
 	ListCapsuleMetrics(ctx context.Context) (iterator.Iterator[*capsule.InstanceMetrics], error)
 
-	CreateVolume(ctx context.Context, id string) error
-
 	// ImageExistsNatively checks if the image exists natively in the cluster. The repo digest is returned if found.
 	ImageExistsNatively(ctx context.Context, image string) (bool, string, error)
 }
 
+// TLSSecretName returns the name of the TLS secret of the certificate of an
+// interface of the capsule.
+func TLSSecretName(capsuleID, interfaceName string) string {
+	return fmt.Sprintf("%s-tls-%s", capsuleID, interfaceName)
+}
+
+// CreateProxyConfig creates the config of the rig-proxy sidecar. The
+// middlewares of an interface are listed innermost first, as rig-proxy wraps
+// each middleware around the ones before it.
 func CreateProxyConfig(ctx context.Context, cn *capsule.Network, jm *proxy.JWTMethod) (*proxy.Config, error) {
 	projectID, err := auth.GetProjectID(ctx)
 	if err != nil {
@@ -64,6 +123,8 @@ func CreateProxyConfig(ctx context.Context, cn *capsule.Network, jm *proxy.JWTMe
 		switch v := i.GetPublic().GetMethod().GetKind().(type) {
 		case *capsule.RoutingMethod_LoadBalancer_:
 			e.SourcePort = v.LoadBalancer.GetPort()
+		case *capsule.RoutingMethod_Ingress_:
+			e.TrustForwardedFor = true
 		}
 
 		if i.GetLogging().GetEnabled() {
@@ -75,6 +136,18 @@ func CreateProxyConfig(ctx context.Context, cn *capsule.Network, jm *proxy.JWTMe
 			})
 		}
 
+		// Rate limiting is applied after authentication, so clients can be
+		// told apart by their subject.
+		if i.GetRateLimit().GetEnabled() {
+			e.Layer = proxy.Layer_LAYER_7
+			e.Middlewares = append(e.Middlewares, &capsule.Middleware{
+				Kind: &capsule.Middleware_RateLimit{
+					RateLimit: i.GetRateLimit(),
+				},
+			})
+			pc.MetricsPort = ProxyMetricsPort(cn.GetInterfaces())
+		}
+
 		if i.GetAuthentication().GetEnabled() {
 			e.Layer = proxy.Layer_LAYER_7
 			e.Middlewares = append(e.Middlewares, &capsule.Middleware{
@@ -84,6 +157,28 @@ func CreateProxyConfig(ctx context.Context, cn *capsule.Network, jm *proxy.JWTMe
 			})
 		}
 
+		// Headers are rewritten before authentication, so rewrites can't
+		// forge the headers set by authentication.
+		if i.GetHeaders().GetEnabled() {
+			e.Layer = proxy.Layer_LAYER_7
+			e.Middlewares = append(e.Middlewares, &capsule.Middleware{
+				Kind: &capsule.Middleware_Headers{
+					Headers: i.GetHeaders(),
+				},
+			})
+		}
+
+		// CORS comes first, so preflight requests aren't rejected by the other
+		// middlewares and their rejections have the CORS headers.
+		if i.GetCors().GetEnabled() {
+			e.Layer = proxy.Layer_LAYER_7
+			e.Middlewares = append(e.Middlewares, &capsule.Middleware{
+				Kind: &capsule.Middleware_Cors{
+					Cors: i.GetCors(),
+				},
+			})
+		}
+
 		pc.Interfaces = append(pc.Interfaces, e)
 	}
 
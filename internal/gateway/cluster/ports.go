@@ -0,0 +1,61 @@
+package cluster
+
+import (
+	"errors"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+const (
+	dynamicPortOffset uint32 = 49152
+	dynamicPortMax    uint32 = 65535
+)
+
+// ProxyPorts returns a port for each interface, in the dynamic port range, for
+// the rig-proxy sidecar to listen on. The ports differ from the ports of the
+// interfaces, as the sidecar shares the network of the instance.
+func ProxyPorts(infs []*capsule.Interface) ([]uint32, error) {
+	existingPort := map[uint32]struct{}{}
+	for _, inf := range infs {
+		existingPort[inf.GetPort()] = struct{}{}
+	}
+
+	proxyPorts := make([]uint32, len(infs))
+
+	o := dynamicPortOffset
+	for i := range infs {
+		var pp uint32
+		for {
+			pp = o
+			if pp > dynamicPortMax {
+				return nil, errors.New("could not find an avaiable port in the dynamic range")
+			}
+			if _, ok := existingPort[pp]; !ok {
+				o++
+				break
+			}
+			o++
+		}
+		proxyPorts[i] = pp
+	}
+
+	return proxyPorts, nil
+}
+
+// ProxyMetricsPort returns a port, in the dynamic port range, for the rig-proxy
+// sidecar to serve its metrics on. It is taken from the top of the range so it
+// doesn't collide with the ports of ProxyPorts.
+func ProxyMetricsPort(infs []*capsule.Interface) uint32 {
+	existingPort := map[uint32]struct{}{}
+	for _, inf := range infs {
+		existingPort[inf.GetPort()] = struct{}{}
+	}
+
+	p := dynamicPortMax
+	for {
+		if _, ok := existingPort[p]; !ok {
+			return p
+		}
+		p--
+	}
+}
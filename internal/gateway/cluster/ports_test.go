@@ -1,4 +1,4 @@
-package k8s
+package cluster
 
 import (
 	"strconv"
@@ -8,7 +8,7 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCreateProxyPorts(t *testing.T) {
+func TestProxyPorts(t *testing.T) {
 	t.Parallel()
 	tests := []struct {
 		in       []*capsule.Interface
@@ -37,7 +37,7 @@ func TestCreateProxyPorts(t *testing.T) {
 		t.Run(strconv.Itoa(i), func(t *testing.T) {
 			t.Parallel()
 
-			actual, err := createProxyPorts(test.in)
+			actual, err := ProxyPorts(test.in)
 			assert.Nil(t, err)
 			assert.Equal(t, test.expected, actual)
 		})
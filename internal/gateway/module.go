@@ -49,7 +49,7 @@ func NewCluster(p clusterParams) (cluster.Gateway, cluster.ConfigGateway, cluste
 		if p.K8SClient == nil {
 			return nil, nil, nil, fmt.Errorf("no k8s client provided")
 		}
-		return p.K8SClient, p.K8SClient, nil, nil
+		return p.K8SClient, p.K8SClient, p.K8SClient, nil
 	default:
 		return nil, nil, nil, fmt.Errorf("invalid cluster gateway '%v'", p.Cfg.Cluster.Type)
 	}
@@ -5,11 +5,33 @@ import (
 
 	"github.com/bufbuild/connect-go"
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	service_capsule "github.com/rigdev/rig/internal/service/capsule"
 )
 
 func (h *Handler) Deploy(ctx context.Context, req *connect.Request[capsule.DeployRequest]) (*connect.Response[capsule.DeployResponse], error) {
+	if req.Msg.GetDryRun() {
+		rc, objs, err := h.cs.DeployDryRun(ctx, req.Msg.GetCapsuleId(), req.Msg.GetChanges())
+		if err != nil {
+			return nil, err
+		}
 
-	rolloutID, err := h.cs.Deploy(ctx, req.Msg.GetCapsuleId(), req.Msg.GetChanges())
+		return &connect.Response[capsule.DeployResponse]{
+			Msg: &capsule.DeployResponse{
+				ResolvedConfig:    rc,
+				KubernetesObjects: objs,
+			},
+		}, nil
+	}
+
+	opts := service_capsule.DeployOptions{
+		OverrideDeployWindow: req.Msg.GetOverrideDeployWindow(),
+		Queue:                req.Msg.GetQueue(),
+	}
+	if req.Msg.GetScheduleAt() != nil {
+		opts.ScheduleAt = req.Msg.GetScheduleAt().AsTime()
+	}
+
+	rolloutID, err := h.cs.Deploy(ctx, req.Msg.GetCapsuleId(), req.Msg.GetChanges(), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,125 @@
+package capsule
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/errors"
+)
+
+func (h *Handler) Exec(ctx context.Context, stream *connect.BidiStream[capsule.ExecRequest, capsule.ExecResponse]) error {
+	req, err := stream.Receive()
+	if err != nil {
+		return err
+	}
+
+	start := req.GetStart()
+	if start == nil {
+		return errors.InvalidArgumentErrorf("the first request must start the command")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	streams := &cluster.ExecStreams{
+		Stdout: &execWriter{mu: &mu, stream: stream},
+		Stderr: &execWriter{mu: &mu, stream: stream, stderr: true},
+		TTY:    start.GetTty(),
+	}
+
+	var stdin *io.PipeWriter
+	if start.GetInteractive() {
+		r, w := io.Pipe()
+		// Unblocks writing the remaining input once the command has exited.
+		defer r.Close()
+		streams.Stdin = r
+		stdin = w
+	}
+
+	resize := make(chan cluster.TerminalSize)
+	if start.GetTty() {
+		streams.Resize = resize
+	}
+
+	go func() {
+		defer close(resize)
+		for {
+			req, err := stream.Receive()
+			if err != nil {
+				if stdin != nil {
+					if err == io.EOF {
+						err = nil
+					}
+					stdin.CloseWithError(err)
+				}
+				return
+			}
+
+			switch v := req.GetRequest().(type) {
+			case *capsule.ExecRequest_Stdin:
+				if stdin != nil {
+					if _, err := stdin.Write(v.Stdin); err != nil {
+						return
+					}
+				}
+			case *capsule.ExecRequest_CloseStdin:
+				if stdin != nil && v.CloseStdin {
+					stdin.Close()
+				}
+			case *capsule.ExecRequest_Resize_:
+				select {
+				case resize <- cluster.TerminalSize{
+					Height: uint16(v.Resize.GetHeight()),
+					Width:  uint16(v.Resize.GetWidth()),
+				}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	code, err := h.cs.Exec(ctx, start.GetCapsuleId(), start.GetInstanceId(), append([]string{start.GetCommand()}, start.GetArgs()...), streams)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	return stream.Send(&capsule.ExecResponse{
+		Response: &capsule.ExecResponse_ExitCode{ExitCode: code},
+	})
+}
+
+// execWriter sends the output of a command. The writers of a command share
+// the mutex, as the stream can't be sent on concurrently.
+type execWriter struct {
+	mu     *sync.Mutex
+	stream *connect.BidiStream[capsule.ExecRequest, capsule.ExecResponse]
+	stderr bool
+}
+
+func (w *execWriter) Write(bs []byte) (int, error) {
+	// The buffer may no longer be referenced when returning -> dup.
+	out := bytes.Clone(bs)
+	res := &capsule.ExecResponse{
+		Response: &capsule.ExecResponse_Stdout{Stdout: out},
+	}
+	if w.stderr {
+		res.Response = &capsule.ExecResponse_Stderr{Stderr: out}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.stream.Send(res); err != nil {
+		return 0, err
+	}
+
+	return len(bs), nil
+}
@@ -9,7 +9,12 @@ import (
 )
 
 func (h *Handler) Logs(ctx context.Context, req *connect.Request[capsule.LogsRequest], stream *connect.ServerStream[capsule.LogsResponse]) error {
-	it, err := h.cs.Logs(ctx, req.Msg.GetCapsuleId(), req.Msg.GetInstanceId(), req.Msg.GetFollow())
+	logs := h.cs.Logs
+	if req.Msg.GetAccessLogs() {
+		logs = h.cs.AccessLogs
+	}
+
+	it, err := logs(ctx, req.Msg.GetCapsuleId(), req.Msg.GetInstanceId(), req.Msg.GetFollow())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,16 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+func (h *Handler) PauseRollout(ctx context.Context, req *connect.Request[capsule.PauseRolloutRequest]) (*connect.Response[capsule.PauseRolloutResponse], error) {
+	if err := h.cs.PauseRollout(ctx, req.Msg.GetCapsuleId(), req.Msg.GetRolloutId()); err != nil {
+		return nil, err
+	}
+
+	return &connect.Response[capsule.PauseRolloutResponse]{}, nil
+}
@@ -0,0 +1,68 @@
+package capsule
+
+import (
+	"context"
+	"io"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/errors"
+)
+
+func (h *Handler) PortForward(ctx context.Context, stream *connect.BidiStream[capsule.PortForwardRequest, capsule.PortForwardResponse]) error {
+	req, err := stream.Receive()
+	if err != nil {
+		return err
+	}
+
+	start := req.GetStart()
+	if start == nil {
+		return errors.InvalidArgumentErrorf("the first request must start the connection")
+	}
+
+	conn, err := h.cs.PortForward(ctx, start.GetCapsuleId(), start.GetInstanceId(), start.GetPort(), start.GetInterface())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	go func() {
+		for {
+			req, err := stream.Receive()
+			if err == io.EOF {
+				conn.CloseWrite()
+				return
+			} else if err != nil {
+				conn.Close()
+				return
+			}
+
+			if _, err := conn.Write(req.GetData()); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := conn.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&capsule.PortForwardResponse{
+				Data: buf[:n],
+			}); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			// The connection is closed when the client goes away.
+			if ctx.Err() != nil {
+				return nil
+			}
+			return errors.UnavailableErrorf("connection closed: %v", err)
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+func (h *Handler) PromoteRollout(ctx context.Context, req *connect.Request[capsule.PromoteRolloutRequest]) (*connect.Response[capsule.PromoteRolloutResponse], error) {
+	if err := h.cs.PromoteRollout(ctx, req.Msg.GetCapsuleId(), req.Msg.GetRolloutId(), req.Msg.GetFull()); err != nil {
+		return nil, err
+	}
+
+	return &connect.Response[capsule.PromoteRolloutResponse]{}, nil
+}
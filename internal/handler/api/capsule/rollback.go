@@ -0,0 +1,21 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+func (h *Handler) Rollback(ctx context.Context, req *connect.Request[capsule.RollbackRequest]) (*connect.Response[capsule.RollbackResponse], error) {
+	rolloutID, err := h.cs.Rollback(ctx, req.Msg.GetCapsuleId(), req.Msg.GetRolloutId())
+	if err != nil {
+		return nil, err
+	}
+
+	return &connect.Response[capsule.RollbackResponse]{
+		Msg: &capsule.RollbackResponse{
+			RolloutId: rolloutID,
+		},
+	}, nil
+}
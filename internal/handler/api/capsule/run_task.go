@@ -0,0 +1,31 @@
+package capsule
+
+import (
+	"context"
+	"io"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+func (h *Handler) RunTask(ctx context.Context, req *connect.Request[capsule.RunTaskRequest], stream *connect.ServerStream[capsule.RunTaskResponse]) error {
+	it, err := h.cs.RunTask(ctx, req.Msg.GetCapsuleId(), req.Msg.GetCommand(), req.Msg.GetArgs())
+	if err != nil {
+		return err
+	}
+
+	defer it.Close()
+
+	for {
+		res, err := it.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
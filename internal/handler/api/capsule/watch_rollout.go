@@ -0,0 +1,31 @@
+package capsule
+
+import (
+	"context"
+	"io"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+)
+
+func (h *Handler) WatchRollout(ctx context.Context, req *connect.Request[capsule.WatchRolloutRequest], stream *connect.ServerStream[capsule.WatchRolloutResponse]) error {
+	it, err := h.cs.WatchRollout(ctx, req.Msg.GetCapsuleId(), req.Msg.GetRolloutId(), req.Msg.GetEventsOffset())
+	if err != nil {
+		return err
+	}
+
+	defer it.Close()
+
+	for {
+		res, err := it.Next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := stream.Send(res); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+)
+
+func (h *Handler) Create(ctx context.Context, req *connect.Request[webhook.CreateRequest]) (*connect.Response[webhook.CreateResponse], error) {
+	w, err := h.ws.Create(ctx, req.Msg.GetInitializers())
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&webhook.CreateResponse{
+		Webhook: w,
+	}), nil
+}
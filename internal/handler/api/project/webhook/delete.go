@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (h *Handler) Delete(ctx context.Context, req *connect.Request[webhook.DeleteRequest]) (*connect.Response[webhook.DeleteResponse], error) {
+	webhookID, err := uuid.Parse(req.Msg.GetWebhookId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.ws.Delete(ctx, webhookID); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&webhook.DeleteResponse{}), nil
+}
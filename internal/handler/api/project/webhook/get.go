@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (h *Handler) Get(ctx context.Context, req *connect.Request[webhook.GetRequest]) (*connect.Response[webhook.GetResponse], error) {
+	webhookID, err := uuid.Parse(req.Msg.GetWebhookId())
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := h.ws.Get(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&webhook.GetResponse{
+		Webhook: w,
+	}), nil
+}
@@ -0,0 +1,27 @@
+package webhook
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook/webhookconnect"
+	"github.com/rigdev/rig/internal/service/webhook"
+)
+
+type Handler struct {
+	ws *webhook.Service
+}
+
+func New(ws *webhook.Service) *Handler {
+	return &Handler{
+		ws: ws,
+	}
+}
+
+func (h *Handler) ServiceName() string {
+	return webhookconnect.ServiceName
+}
+
+func (h *Handler) Build(opts ...connect.HandlerOption) (string, http.Handler) {
+	return webhookconnect.NewServiceHandler(h, opts...)
+}
@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/iterator"
+)
+
+func (h *Handler) List(ctx context.Context, req *connect.Request[webhook.ListRequest]) (*connect.Response[webhook.ListResponse], error) {
+	it, total, err := h.ws.List(ctx, req.Msg.GetPagination())
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := iterator.Collect(it)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&webhook.ListResponse{
+		Webhooks: ws,
+		Total:    total,
+	}), nil
+}
@@ -0,0 +1,32 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (h *Handler) ListDeliveries(ctx context.Context, req *connect.Request[webhook.ListDeliveriesRequest]) (*connect.Response[webhook.ListDeliveriesResponse], error) {
+	webhookID, err := uuid.Parse(req.Msg.GetWebhookId())
+	if err != nil {
+		return nil, err
+	}
+
+	it, total, err := h.ws.ListDeliveries(ctx, webhookID, req.Msg.GetPagination())
+	if err != nil {
+		return nil, err
+	}
+
+	ds, err := iterator.Collect(it)
+	if err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&webhook.ListDeliveriesResponse{
+		Deliveries: ds,
+		Total:      total,
+	}), nil
+}
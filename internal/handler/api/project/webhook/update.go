@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (h *Handler) Update(ctx context.Context, req *connect.Request[webhook.UpdateRequest]) (*connect.Response[webhook.UpdateResponse], error) {
+	webhookID, err := uuid.Parse(req.Msg.GetWebhookId())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := h.ws.Update(ctx, webhookID, req.Msg.GetUpdates()); err != nil {
+		return nil, err
+	}
+
+	return connect.NewResponse(&webhook.UpdateResponse{}), nil
+}
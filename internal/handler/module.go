@@ -8,6 +8,7 @@ import (
 	"github.com/rigdev/rig/internal/handler/api/group"
 	"github.com/rigdev/rig/internal/handler/api/project"
 	project_settings "github.com/rigdev/rig/internal/handler/api/project/settings"
+	project_webhook "github.com/rigdev/rig/internal/handler/api/project/webhook"
 	"github.com/rigdev/rig/internal/handler/api/service_account"
 	"github.com/rigdev/rig/internal/handler/api/status_http"
 	"github.com/rigdev/rig/internal/handler/api/storage"
@@ -30,6 +31,7 @@ var Module = fx.Module(
 		asGRPCHandler(group.New),
 		asGRPCHandler(project.New),
 		asGRPCHandler(project_settings.New),
+		asGRPCHandler(project_webhook.New),
 		asGRPCHandler(service_account.New),
 		asGRPCHandler(storage.New),
 		asGRPCHandler(user.New),
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/rigdev/rig-go-api/model"
@@ -19,9 +20,13 @@ type Capsule interface {
 	CreateRollout(ctx context.Context, capsuleID string, rc *capsule.RolloutConfig, rs *rollout.Status) (uint64, error)
 	ListRollouts(ctx context.Context, pagination *model.Pagination, capsuleID string) (iterator.Iterator[*capsule.Rollout], uint64, error)
 	UpdateRolloutStatus(ctx context.Context, capsuleID string, rolloutID uint64, version uint64, rs *rollout.Status) error
+	UpdateRollout(ctx context.Context, capsuleID string, rolloutID uint64, version uint64, rc *capsule.RolloutConfig, rs *rollout.Status) error
 	GetRollout(ctx context.Context, capsuleID string, rolloutID uint64) (*capsule.RolloutConfig, *rollout.Status, uint64, error)
 	GetCurrentRollout(ctx context.Context, capsuleID string) (uint64, *capsule.RolloutConfig, *rollout.Status, uint64, error)
 	ActiveRollouts(ctx context.Context, pagination *model.Pagination) (iterator.Iterator[repo_capsule.ActiveRollout], error)
+	// LeaseRollout acquires or renews the lease of a rollout for the owner.
+	// Fails with FailedPrecondition if another owner holds an unexpired lease.
+	LeaseRollout(ctx context.Context, capsuleID string, rolloutID uint64, owner string, expiresAt time.Time) error
 
 	CreateEvent(ctx context.Context, capsuleID string, e *capsule.Event) error
 	ListEvents(ctx context.Context, pagination *model.Pagination, capsuleID string, rolloutID uint64) (iterator.Iterator[*capsule.Event], uint64, error)
@@ -11,4 +11,6 @@ type ActiveRollout struct {
 	CapsuleID   string
 	RolloutID   uint64
 	ScheduledAt time.Time
+	// Zero if the rollout has never been leased.
+	LeaseExpiresAt time.Time
 }
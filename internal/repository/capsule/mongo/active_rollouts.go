@@ -42,6 +42,9 @@ func (m *MongoRepository) ActiveRollouts(ctx context.Context, pagination *model.
 			if r.ScheduledAt != nil {
 				ar.ScheduledAt = *r.ScheduledAt
 			}
+			if r.LeaseExpiresAt != nil {
+				ar.LeaseExpiresAt = *r.LeaseExpiresAt
+			}
 
 			if err := it.Value(ar); err != nil {
 				it.Error(err)
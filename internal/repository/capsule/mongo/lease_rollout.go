@@ -0,0 +1,62 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoRepository) LeaseRollout(ctx context.Context, capsuleID string, rolloutID uint64, owner string, expiresAt time.Time) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	r, err := m.RolloutCol.UpdateOne(
+		ctx,
+		bson.M{
+			"project_id": projectID,
+			"capsule_id": capsuleID,
+			"rollout_id": rolloutID,
+			"$or": bson.A{
+				bson.M{"lease_owner": owner},
+				bson.M{"lease_expires_at": bson.M{"$exists": false}},
+				bson.M{"lease_expires_at": bson.M{"$lt": time.Now()}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"lease_owner":      owner,
+				"lease_expires_at": expiresAt,
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		c, err := m.RolloutCol.CountDocuments(
+			ctx,
+			bson.M{
+				"project_id": projectID,
+				"capsule_id": capsuleID,
+				"rollout_id": rolloutID,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		if c == 0 {
+			return errors.NotFoundErrorf("rollout not found")
+		}
+
+		return errors.FailedPreconditionErrorf("rollout is leased by another job")
+	}
+
+	return nil
+}
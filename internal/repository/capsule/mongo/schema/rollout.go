@@ -19,6 +19,9 @@ type Rollout struct {
 	ScheduledAt *time.Time `bson:"scheduled_at,omitempty" json:"scheduled_at,omitempty"`
 	Config      []byte     `bson:"config,omitempty" json:"config,omitempty"`
 	Status      []byte     `bson:"status,omitempty" json:"status,omitempty"`
+	// The job executing the rollout, and until when it is allowed to.
+	LeaseOwner     string     `bson:"lease_owner,omitempty" json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `bson:"lease_expires_at,omitempty" json:"lease_expires_at,omitempty"`
 }
 
 type CapsuleMetric struct {
@@ -116,6 +119,21 @@ func RolloutStatusFromProto(version uint64, rs *rollout.Status) (bson.M, error)
 	}, nil
 }
 
+func RolloutUpdateFromProto(version uint64, rc *capsule.RolloutConfig, rs *rollout.Status) (bson.M, error) {
+	u, err := RolloutStatusFromProto(version, rs)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := proto.Marshal(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	u["$set"].(bson.M)["config"] = bs
+	return u, nil
+}
+
 func MetricFromProto(projectID uuid.UUID, p *capsule.InstanceMetrics) (CapsuleMetric, error) {
 	bs, err := proto.Marshal(p)
 	if err != nil {
@@ -14,6 +14,8 @@ type ClusterConfig interface {
 	CreateCapsuleConfig(ctx context.Context, p *v1alpha1.Capsule) error
 	UpdateCapsuleConfig(ctx context.Context, p *v1alpha1.Capsule) error
 	ListCapsuleConfigs(ctx context.Context, pagination *model.Pagination) (iterator.Iterator[*v1alpha1.Capsule], int64, error)
+	// ListAllCapsuleConfigs lists the capsule configs of all projects.
+	ListAllCapsuleConfigs(ctx context.Context) (iterator.Iterator[*v1alpha1.Capsule], error)
 	DeleteCapsuleConfig(ctx context.Context, capsuleID string) error
 
 	SetEnvironmentVariables(ctx context.Context, capsuleID string, envs map[string]string) error
@@ -0,0 +1,43 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig/internal/repository/cluster_config/mongo/schema"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/iterator"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoRepository) ListAllCapsuleConfigs(ctx context.Context) (iterator.Iterator[*v1alpha1.Capsule], error) {
+	cursor, err := m.CapsuleConfigCol.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+
+	it := iterator.NewProducer[*v1alpha1.Capsule]()
+	go func() {
+		defer it.Done()
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var r schema.CapsuleConfig
+			if err := cursor.Decode(&r); err != nil {
+				it.Error(err)
+				return
+			}
+
+			e, err := r.ToAPI()
+			if err != nil {
+				it.Error(err)
+				return
+			}
+
+			if err := it.Value(e); err != nil {
+				it.Error(err)
+				return
+			}
+		}
+	}()
+
+	return it, nil
+}
@@ -16,6 +16,7 @@ import (
 	storage_mongo "github.com/rigdev/rig/internal/repository/storage/mongo"
 	user_mongo "github.com/rigdev/rig/internal/repository/user/mongo"
 	verification_code_mongo "github.com/rigdev/rig/internal/repository/verification_code/mongo"
+	webhook_mongo "github.com/rigdev/rig/internal/repository/webhook/mongo"
 	"github.com/uptrace/bun"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/fx"
@@ -36,6 +37,7 @@ var Module = fx.Module(
 		NewStorage,
 		NewSecret,
 		NewClusterConfig,
+		NewWebhook,
 	),
 )
 
@@ -192,6 +194,19 @@ func NewClusterConfig(p params) (ClusterConfig, error) {
 	}
 }
 
+func NewWebhook(p params) (Webhook, error) {
+	s := p.Cfg.Repository.Webhook.Store
+	switch s {
+	case storeTypeMongoDB:
+		if p.MongoClient == nil {
+			return nil, errNoMongoDBClient
+		}
+		return webhook_mongo.NewRepository(p.MongoClient)
+	default:
+		return nil, errInvalidStore("webhook", s)
+	}
+}
+
 var errNoMongoDBClient = errors.New("no mongo client configured, RIG_CLIENT_MONGO_HOST environment variable missing")
 
 func errInvalidStore(name, actual string) error {
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+type Webhook interface {
+	Create(ctx context.Context, w *webhook.Webhook) error
+	Get(ctx context.Context, webhookID uuid.UUID) (*webhook.Webhook, error)
+	List(ctx context.Context, pagination *model.Pagination) (iterator.Iterator[*webhook.Webhook], uint64, error)
+	Update(ctx context.Context, w *webhook.Webhook) error
+	Delete(ctx context.Context, webhookID uuid.UUID) error
+
+	CreateDelivery(ctx context.Context, d *webhook.Delivery) error
+	UpdateDelivery(ctx context.Context, d *webhook.Delivery) error
+	ListDeliveries(ctx context.Context, webhookID uuid.UUID, pagination *model.Pagination) (iterator.Iterator[*webhook.Delivery], uint64, error)
+	// ClaimDelivery returns a pending delivery of any project that is due at
+	// now, and postpones its next attempt until the given time, such that no
+	// other server attempts it in the meantime.
+	ClaimDelivery(ctx context.Context, now, until time.Time) (uuid.UUID, *webhook.Delivery, error)
+
+	BuildIndexes(ctx context.Context) error
+}
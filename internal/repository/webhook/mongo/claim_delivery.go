@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"context"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (m *MongoRepository) ClaimDelivery(ctx context.Context, now, until time.Time) (uuid.UUID, *webhook.Delivery, error) {
+	var d schema.Delivery
+	if err := m.DeliveryCol.FindOneAndUpdate(
+		ctx,
+		bson.M{
+			"state":           webhook.DeliveryState_DELIVERY_STATE_PENDING,
+			"next_attempt_at": bson.M{"$lte": now},
+		},
+		bson.M{"$set": bson.M{"next_attempt_at": until}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}),
+	).Decode(&d); err == mongo.ErrNoDocuments {
+		return uuid.Nil, nil, errors.NotFoundErrorf("no pending deliveries")
+	} else if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	p, err := d.ToProto()
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	return d.ProjectID, p, nil
+}
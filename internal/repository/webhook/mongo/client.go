@@ -0,0 +1,63 @@
+package mongo
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MongoRepository struct {
+	WebhookCol  *mongo.Collection
+	DeliveryCol *mongo.Collection
+}
+
+func (r *MongoRepository) BuildIndexes(ctx context.Context) error {
+	webhookIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "project_id", Value: 1},
+			{Key: "webhook_id", Value: 1},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+	if _, err := r.WebhookCol.Indexes().CreateOne(ctx, webhookIndexModel); err != nil {
+		return err
+	}
+
+	deliveryIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "project_id", Value: 1},
+			{Key: "webhook_id", Value: 1},
+		},
+		Options: options.Index(),
+	}
+	if _, err := r.DeliveryCol.Indexes().CreateOne(ctx, deliveryIndexModel); err != nil {
+		return err
+	}
+
+	deliveryPendingIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "state", Value: 1},
+			{Key: "next_attempt_at", Value: 1},
+		},
+		Options: options.Index(),
+	}
+	if _, err := r.DeliveryCol.Indexes().CreateOne(ctx, deliveryPendingIndexModel); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func NewRepository(c *mongo.Client) (*MongoRepository, error) {
+	repo := &MongoRepository{
+		WebhookCol:  c.Database("rig").Collection("webhooks"),
+		DeliveryCol: c.Database("rig").Collection("webhook_deliveries"),
+	}
+
+	if err := repo.BuildIndexes(context.Background()); err != nil {
+		return nil, err
+	}
+	return repo, nil
+}
@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+)
+
+func (m *MongoRepository) Create(ctx context.Context, w *webhook.Webhook) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	d, err := schema.WebhookFromProto(projectID, w)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.WebhookCol.InsertOne(ctx, d); err != nil {
+		return err
+	}
+
+	return nil
+}
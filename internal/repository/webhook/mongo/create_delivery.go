@@ -0,0 +1,27 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+)
+
+func (m *MongoRepository) CreateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	sd, err := schema.DeliveryFromProto(projectID, d)
+	if err != nil {
+		return err
+	}
+
+	if _, err := m.DeliveryCol.InsertOne(ctx, sd); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Delete deletes the webhook and its delivery log.
+func (m *MongoRepository) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"project_id": projectID, "webhook_id": webhookID}
+	res, err := m.WebhookCol.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	if res.DeletedCount == 0 {
+		return errors.NotFoundErrorf("webhook not found")
+	}
+
+	if _, err := m.DeliveryCol.DeleteMany(ctx, filter); err != nil {
+		return err
+	}
+
+	return nil
+}
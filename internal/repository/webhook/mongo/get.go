@@ -0,0 +1,29 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (m *MongoRepository) Get(ctx context.Context, webhookID uuid.UUID) (*webhook.Webhook, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var w schema.Webhook
+	if err := m.WebhookCol.FindOne(ctx, bson.M{"project_id": projectID, "webhook_id": webhookID}).Decode(&w); err == mongo.ErrNoDocuments {
+		return nil, errors.NotFoundErrorf("webhook not found")
+	} else if err != nil {
+		return nil, err
+	}
+
+	return w.ToProto()
+}
@@ -0,0 +1,58 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/internal/client/mongo"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoRepository) List(ctx context.Context, pagination *model.Pagination) (iterator.Iterator[*webhook.Webhook], uint64, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := bson.M{"project_id": projectID}
+
+	count, err := m.WebhookCol.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := m.WebhookCol.Find(ctx, filter, mongo.SortOptions(pagination))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	it := iterator.NewProducer[*webhook.Webhook]()
+	go func() {
+		defer it.Done()
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var w schema.Webhook
+			if err := cursor.Decode(&w); err != nil {
+				it.Error(err)
+				return
+			}
+
+			p, err := w.ToProto()
+			if err != nil {
+				it.Error(err)
+				return
+			}
+
+			if err := it.Value(p); err != nil {
+				it.Error(err)
+				return
+			}
+		}
+	}()
+
+	return it, uint64(count), nil
+}
@@ -0,0 +1,59 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/internal/client/mongo"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoRepository) ListDeliveries(ctx context.Context, webhookID uuid.UUID, pagination *model.Pagination) (iterator.Iterator[*webhook.Delivery], uint64, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	filter := bson.M{"project_id": projectID, "webhook_id": webhookID}
+
+	count, err := m.DeliveryCol.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cursor, err := m.DeliveryCol.Find(ctx, filter, mongo.SortOptions(pagination))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	it := iterator.NewProducer[*webhook.Delivery]()
+	go func() {
+		defer it.Done()
+		defer cursor.Close(ctx)
+		for cursor.Next(ctx) {
+			var d schema.Delivery
+			if err := cursor.Decode(&d); err != nil {
+				it.Error(err)
+				return
+			}
+
+			p, err := d.ToProto()
+			if err != nil {
+				it.Error(err)
+				return
+			}
+
+			if err := it.Value(p); err != nil {
+				it.Error(err)
+				return
+			}
+		}
+	}()
+
+	return it, uint64(count), nil
+}
@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/uuid"
+	"google.golang.org/protobuf/proto"
+)
+
+type Webhook struct {
+	WebhookID uuid.UUID `bson:"webhook_id" json:"webhook_id"`
+	ProjectID uuid.UUID `bson:"project_id" json:"project_id"`
+	Data      []byte    `bson:"data,omitempty" json:"data,omitempty"`
+}
+
+func (w Webhook) ToProto() (*webhook.Webhook, error) {
+	p := &webhook.Webhook{}
+	if err := proto.Unmarshal(w.Data, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func WebhookFromProto(projectID uuid.UUID, w *webhook.Webhook) (Webhook, error) {
+	bs, err := proto.Marshal(w)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	return Webhook{
+		WebhookID: uuid.UUID(w.GetWebhookId()),
+		ProjectID: projectID,
+		Data:      bs,
+	}, nil
+}
+
+type Delivery struct {
+	DeliveryID    uuid.UUID             `bson:"delivery_id" json:"delivery_id"`
+	WebhookID     uuid.UUID             `bson:"webhook_id" json:"webhook_id"`
+	ProjectID     uuid.UUID             `bson:"project_id" json:"project_id"`
+	State         webhook.DeliveryState `bson:"state" json:"state"`
+	NextAttemptAt time.Time             `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	Data          []byte                `bson:"data,omitempty" json:"data,omitempty"`
+}
+
+func (d Delivery) ToProto() (*webhook.Delivery, error) {
+	p := &webhook.Delivery{}
+	if err := proto.Unmarshal(d.Data, p); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func DeliveryFromProto(projectID uuid.UUID, d *webhook.Delivery) (Delivery, error) {
+	bs, err := proto.Marshal(d)
+	if err != nil {
+		return Delivery{}, err
+	}
+
+	r := Delivery{
+		DeliveryID: uuid.UUID(d.GetDeliveryId()),
+		WebhookID:  uuid.UUID(d.GetWebhookId()),
+		ProjectID:  projectID,
+		State:      d.GetState(),
+		Data:       bs,
+	}
+	if d.GetNextAttemptAt() != nil {
+		r.NextAttemptAt = d.GetNextAttemptAt().AsTime()
+	}
+
+	return r, nil
+}
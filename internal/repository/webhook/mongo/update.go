@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoRepository) Update(ctx context.Context, w *webhook.Webhook) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	d, err := schema.WebhookFromProto(projectID, w)
+	if err != nil {
+		return err
+	}
+
+	r, err := m.WebhookCol.UpdateOne(
+		ctx,
+		bson.M{"project_id": projectID, "webhook_id": d.WebhookID},
+		bson.M{"$set": d},
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return errors.NotFoundErrorf("webhook not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package mongo
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository/webhook/mongo/schema"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (m *MongoRepository) UpdateDelivery(ctx context.Context, d *webhook.Delivery) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	sd, err := schema.DeliveryFromProto(projectID, d)
+	if err != nil {
+		return err
+	}
+
+	r, err := m.DeliveryCol.UpdateOne(
+		ctx,
+		bson.M{"project_id": projectID, "delivery_id": sd.DeliveryID},
+		bson.M{"$set": sd},
+	)
+	if err != nil {
+		return err
+	}
+
+	if r.MatchedCount == 0 {
+		return errors.NotFoundErrorf("delivery not found")
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"io"
+
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+const (
+	// PermissionCapsuleExec allows executing commands in the instances of
+	// capsules, and running tasks.
+	PermissionCapsuleExec = "capsule-exec"
+	// PermissionCapsulePortForward allows connecting to the ports of the
+	// instances of capsules.
+	PermissionCapsulePortForward = "capsule-port-forward"
+)
+
+// HasPermission returns if the subject of the claims has the permission. A
+// permission is granted to the users of Rig by being members of the group of
+// the Rig project named as the permission. Service accounts have no
+// permissions.
+func (s *Service) HasPermission(ctx context.Context, c auth.Claims, permission string) (bool, error) {
+	if c.GetSubjectType() != auth.SubjectTypeUser || c.GetProjectID() != auth.RigProjectID {
+		return false, nil
+	}
+
+	ctx = auth.WithProjectID(ctx, auth.RigProjectID)
+	g, err := s.rg.GetByName(ctx, permission)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	it, _, err := s.rg.ListGroupsForUser(ctx, c.GetSubject(), &model.Pagination{})
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+
+	for {
+		groupID, err := it.Next()
+		if err == io.EOF {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		if groupID == uuid.UUID(g.GetGroupId()) {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/group"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_HasPermission(t *testing.T) {
+	userID := uuid.New()
+	groupID := uuid.New()
+
+	tests := []struct {
+		name   string
+		claims RigClaims
+		groups []uuid.UUID
+		exists bool
+		has    bool
+	}{
+		{
+			name:   "member of the permission group",
+			claims: RigClaims{ProjectID: auth.RigProjectID, Subject: userID, SubjectType: auth.SubjectTypeUser},
+			groups: []uuid.UUID{uuid.New(), groupID},
+			exists: true,
+			has:    true,
+		},
+		{
+			name:   "not a member of the permission group",
+			claims: RigClaims{ProjectID: auth.RigProjectID, Subject: userID, SubjectType: auth.SubjectTypeUser},
+			groups: []uuid.UUID{uuid.New()},
+			exists: true,
+		},
+		{
+			name:   "no permission group",
+			claims: RigClaims{ProjectID: auth.RigProjectID, Subject: userID, SubjectType: auth.SubjectTypeUser},
+		},
+		{
+			name:   "service account",
+			claims: RigClaims{ProjectID: auth.RigProjectID, Subject: userID, SubjectType: auth.SubjectTypeServiceAccount},
+		},
+		{
+			name:   "project user",
+			claims: RigClaims{ProjectID: uuid.New(), Subject: userID, SubjectType: auth.SubjectTypeUser},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rg := repository.NewMockGroup(t)
+			if tt.claims.SubjectType == auth.SubjectTypeUser && tt.claims.ProjectID == auth.RigProjectID {
+				if tt.exists {
+					rg.EXPECT().GetByName(mock.Anything, PermissionCapsuleExec).Return(&group.Group{GroupId: groupID.String()}, nil)
+					rg.EXPECT().ListGroupsForUser(mock.Anything, userID, mock.Anything).Return(iterator.FromList(tt.groups), uint64(len(tt.groups)), nil)
+				} else {
+					rg.EXPECT().GetByName(mock.Anything, PermissionCapsuleExec).Return(nil, errors.NotFoundErrorf("group not found"))
+				}
+			}
+
+			s := &Service{rg: rg}
+			has, err := s.HasPermission(context.Background(), tt.claims, PermissionCapsuleExec)
+			require.NoError(t, err)
+			require.Equal(t, tt.has, has)
+		})
+	}
+}
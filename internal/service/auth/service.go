@@ -34,6 +34,7 @@ type Service struct {
 	sr  repository.Session
 	rsa repository.ServiceAccount
 	rs  repository.Secret
+	rg  repository.Group
 	us  user.Service
 	ps  project.Service
 	// cert       *x509.Certificate
@@ -54,6 +55,7 @@ type newServiceParams struct {
 	SessionRepo        repository.Session
 	ServiceAccountRepo repository.ServiceAccount
 	SecretRepo         repository.Secret
+	GroupRepo          repository.Group
 	UserService        user.Service
 	ProjService        project.Service
 	Vcr                repository.VerificationCode
@@ -67,6 +69,7 @@ func NewService(p newServiceParams) (*Service, error) {
 		sr:     p.SessionRepo,
 		rsa:    p.ServiceAccountRepo,
 		rs:     p.SecretRepo,
+		rg:     p.GroupRepo,
 		us:     p.UserService,
 		ps:     p.ProjService,
 		vcr:    p.Vcr,
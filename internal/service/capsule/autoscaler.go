@@ -0,0 +1,45 @@
+package capsule
+
+import (
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+)
+
+func validateAutoscaler(a *capsule.Autoscaler) error {
+	if a.GetMinReplicas() == 0 {
+		return errors.InvalidArgumentErrorf("autoscaler min replicas must be at least 1")
+	}
+
+	if a.GetMaxReplicas() < a.GetMinReplicas() {
+		return errors.InvalidArgumentErrorf("autoscaler max replicas cannot be less than min replicas")
+	}
+
+	if a.GetCpuTarget() == 0 && a.GetMemoryTarget() == 0 {
+		return errors.InvalidArgumentErrorf("autoscaler must have a cpu or memory target")
+	}
+
+	return nil
+}
+
+// rolloutReplicas returns the number of instances the rollout waits for. If
+// the rollout is autoscaled, that is the min replicas of the autoscaler.
+func rolloutReplicas(rc *capsule.RolloutConfig) uint32 {
+	if a := rc.GetAutoscaler(); a != nil {
+		return a.GetMinReplicas()
+	}
+	return rc.GetReplicas()
+}
+
+func capsuleAutoscaler(a *capsule.Autoscaler) *v1alpha1.CapsuleAutoscaler {
+	if a == nil {
+		return nil
+	}
+
+	return &v1alpha1.CapsuleAutoscaler{
+		MinReplicas:       int32(a.GetMinReplicas()),
+		MaxReplicas:       int32(a.GetMaxReplicas()),
+		CPUUtilization:    int32(a.GetCpuTarget()),
+		MemoryUtilization: int32(a.GetMemoryTarget()),
+	}
+}
@@ -0,0 +1,273 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Service) PauseRollout(ctx context.Context, capsuleID string, rolloutID uint64) error {
+	rc, rs, version, err := s.cr.GetRollout(ctx, capsuleID, rolloutID)
+	if err != nil {
+		return err
+	}
+
+	if isRolloutTerminated(rs) {
+		return errors.FailedPreconditionErrorf("rollout already completed")
+	}
+
+	step, ok := canaryStep(rc, rs)
+	if !ok {
+		return errors.FailedPreconditionErrorf("rollout has no canary steps left")
+	}
+
+	cs := rs.GetStatus().GetCanary()
+	if cs == nil {
+		return errors.FailedPreconditionErrorf("rollout has not started the canary steps")
+	}
+
+	if cs.GetPaused() {
+		return nil
+	}
+
+	cs.Paused = true
+	rs.Status.Message = fmt.Sprintf("rollout paused at canary step %d (%d%%)", cs.GetStep()+1, step.GetWeight())
+	if err := s.cr.UpdateRolloutStatus(ctx, capsuleID, rolloutID, version, rs); err != nil {
+		return err
+	}
+
+	return s.CreateEvent(ctx, capsuleID, rolloutID, "rollout paused", canaryEventData(cs))
+}
+
+func (s *Service) PromoteRollout(ctx context.Context, capsuleID string, rolloutID uint64, full bool) error {
+	rc, rs, version, err := s.cr.GetRollout(ctx, capsuleID, rolloutID)
+	if err != nil {
+		return err
+	}
+
+	if isRolloutTerminated(rs) {
+		return errors.FailedPreconditionErrorf("rollout already completed")
+	}
+
+	if _, ok := canaryStep(rc, rs); !ok {
+		return errors.FailedPreconditionErrorf("rollout has no canary steps left")
+	}
+
+	cs := rs.GetStatus().GetCanary()
+	if cs == nil {
+		return errors.FailedPreconditionErrorf("rollout has not started the canary steps")
+	}
+
+	cs.Paused = false
+	cs.StepStartedAt = nil
+	if full {
+		cs.Step = uint32(len(rc.GetStrategy().GetCanary().GetSteps()))
+	} else {
+		cs.Step++
+	}
+
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_DEPLOYING
+	rs.Status.Message = "rollout promoted"
+	rs.ScheduledAt = timestamppb.Now()
+	if err := s.cr.UpdateRolloutStatus(ctx, capsuleID, rolloutID, version, rs); err != nil {
+		return err
+	}
+
+	// Continue the rollout right away, unless its job is still running, which
+	// then continues it.
+	if err := s.queueRolloutJob(ctx, capsuleID, rolloutID, rs.GetScheduledAt().AsTime(), ""); err != nil {
+		return err
+	}
+
+	return s.CreateEvent(ctx, capsuleID, rolloutID, "rollout promoted", canaryEventData(cs))
+}
+
+// abortCanary removes the canary instances and restores the stable instances
+// to the replica count they had before the rollout.
+func (s *Service) abortCanary(ctx context.Context, capsuleID string, rs *rollout.Status) error {
+	if err := s.ccg.DeleteCanary(ctx, capsuleID); err != nil {
+		return err
+	}
+
+	cfg, err := s.ccg.GetCapsuleConfig(ctx, capsuleID)
+	if err != nil {
+		return err
+	}
+
+	cfg.Spec.Replicas = int32(rs.GetStableReplicas())
+	return s.ccg.UpdateCapsuleConfig(ctx, cfg)
+}
+
+func (j *rolloutJob) deployCanary(
+	ctx context.Context,
+	stable, canary *v1alpha1.Capsule,
+	envs map[string]string,
+	step *capsule.CanaryStep,
+	rc *capsule.RolloutConfig,
+	rs *rollout.Status,
+) error {
+	cs := rs.GetStatus().GetCanary()
+	if cs == nil {
+		cs = &capsule.CanaryStatus{}
+		rs.Status.Canary = cs
+		rs.StableReplicas = uint32(stable.Spec.Replicas)
+	}
+
+	n := canaryReplicas(rolloutReplicas(rc), step.GetWeight())
+	canary.Spec.Replicas = int32(n)
+	// The canary instances are not autoscaled.
+	canary.Spec.Autoscaler = nil
+	stable.Spec.Replicas = int32(rolloutReplicas(rc) - n)
+
+	if err := j.s.ccg.UpsertCanary(ctx, canary, envs); err != nil {
+		return err
+	}
+
+	if err := j.s.ccg.UpdateCapsuleConfig(ctx, stable); err != nil {
+		return err
+	}
+
+	cs.Weight = step.GetWeight()
+	cs.StepStartedAt = nil
+	if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, fmt.Sprintf("canary step %d: moved %d%% of instances to the new build", cs.GetStep()+1, cs.GetWeight()), canaryEventData(cs)); err != nil {
+		return err
+	}
+
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_OBSERVING
+	rs.Status.Message = "waiting for canary instances"
+	return nil
+}
+
+func (j *rolloutJob) observeCanary(
+	ctx context.Context,
+	cfg *v1alpha1.Capsule,
+	step *capsule.CanaryStep,
+	rc *capsule.RolloutConfig,
+	rs *rollout.Status,
+) error {
+	cs := rs.GetStatus().GetCanary()
+
+	it, _, err := j.s.listInstances(ctx, cfg.GetName())
+	if err != nil {
+		return err
+	}
+
+	c := 0
+	for {
+		i, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if i.GetBuildId() != rc.GetBuildId() {
+			continue
+		}
+
+		if i.GetState() != capsule.State_STATE_RUNNING {
+			return errors.UnavailableErrorf("canary instance '%s' is not running", i.GetInstanceId())
+		}
+
+		if !i.GetReady() {
+			return errors.UnavailableErrorf("canary instance '%s' is not ready", i.GetInstanceId())
+		}
+
+		c++
+	}
+
+	if n := canaryReplicas(rolloutReplicas(rc), step.GetWeight()); c < int(n) {
+		return errors.UnavailableErrorf("only %v canary instances running, should be '%v'", c, n)
+	}
+
+	if cs.GetStepStartedAt() == nil {
+		cs.StepStartedAt = timestamppb.Now()
+	}
+
+	if cs.GetPaused() {
+		rs.Status.Message = fmt.Sprintf("rollout paused at canary step %d (%d%%)", cs.GetStep()+1, cs.GetWeight())
+		return nil
+	}
+
+	if step.GetObserve() == nil {
+		cs.Paused = true
+		rs.Status.Message = fmt.Sprintf("canary step %d (%d%%) waiting to be promoted", cs.GetStep()+1, cs.GetWeight())
+		return j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, "canary step waiting to be promoted", canaryEventData(cs))
+	}
+
+	if time.Since(cs.GetStepStartedAt().AsTime()) < step.GetObserve().AsDuration() {
+		rs.Status.Message = fmt.Sprintf("observing canary step %d (%d%%)", cs.GetStep()+1, cs.GetWeight())
+		return nil
+	}
+
+	if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, fmt.Sprintf("canary step %d completed", cs.GetStep()+1), canaryEventData(cs)); err != nil {
+		return err
+	}
+
+	cs.Step++
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_DEPLOYING
+	rs.Status.Message = "deploying next canary step"
+	return nil
+}
+
+// canaryStep returns the current canary step of the rollout, if the rollout
+// uses a canary strategy and not all steps are completed.
+func canaryStep(rc *capsule.RolloutConfig, rs *rollout.Status) (*capsule.CanaryStep, bool) {
+	steps := rc.GetStrategy().GetCanary().GetSteps()
+	i := int(rs.GetStatus().GetCanary().GetStep())
+	if i >= len(steps) {
+		return nil, false
+	}
+
+	return steps[i], true
+}
+
+func canaryReplicas(replicas, weight uint32) uint32 {
+	n := (replicas*weight + 99) / 100
+	if n == 0 {
+		n = 1
+	}
+	if n > replicas {
+		n = replicas
+	}
+	return n
+}
+
+func canaryEventData(cs *capsule.CanaryStatus) *capsule.EventData {
+	return &capsule.EventData{
+		Kind: &capsule.EventData_Canary{
+			Canary: &capsule.CanaryEvent{
+				Step:   cs.GetStep(),
+				Weight: cs.GetWeight(),
+			},
+		},
+	}
+}
+
+func validateStrategy(rs *capsule.RolloutStrategy) error {
+	var weight uint32
+	for i, s := range rs.GetCanary().GetSteps() {
+		if s.GetWeight() == 0 || s.GetWeight() >= 100 {
+			return errors.InvalidArgumentErrorf("canary step %d: weight must be between 1 and 99", i+1)
+		}
+
+		if s.GetWeight() <= weight {
+			return errors.InvalidArgumentErrorf("canary step %d: weight must be larger than the previous step", i+1)
+		}
+
+		if s.GetObserve() != nil && s.GetObserve().AsDuration() <= 0 {
+			return errors.InvalidArgumentErrorf("canary step %d: observe duration must be positive", i+1)
+		}
+
+		weight = s.GetWeight()
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/internal/repository"
+	service_auth "github.com/rigdev/rig/internal/service/auth"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func canaryStrategy(steps ...*capsule.CanaryStep) *capsule.RolloutStrategy {
+	return &capsule.RolloutStrategy{
+		Kind: &capsule.RolloutStrategy_Canary_{
+			Canary: &capsule.RolloutStrategy_Canary{Steps: steps},
+		},
+	}
+}
+
+func canaryRolloutStatus(state capsule.RolloutState, cs *capsule.CanaryStatus) *rollout.Status {
+	rs := rolloutStatus(state)
+	rs.Status.Canary = cs
+	return rs
+}
+
+func Test_CanaryReplicas(t *testing.T) {
+	tests := []struct {
+		replicas uint32
+		weight   uint32
+		expected uint32
+	}{
+		{replicas: 10, weight: 10, expected: 1},
+		{replicas: 10, weight: 25, expected: 3},
+		{replicas: 3, weight: 50, expected: 2},
+		{replicas: 1, weight: 10, expected: 1},
+		{replicas: 4, weight: 99, expected: 4},
+		{replicas: 0, weight: 50, expected: 0},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, canaryReplicas(tt.replicas, tt.weight), "%d replicas at %d%%", tt.replicas, tt.weight)
+	}
+}
+
+func Test_ValidateStrategy(t *testing.T) {
+	tests := []struct {
+		name  string
+		rs    *capsule.RolloutStrategy
+		valid bool
+	}{
+		{
+			name:  "no strategy",
+			valid: true,
+		},
+		{
+			name: "valid",
+			rs: canaryStrategy(
+				&capsule.CanaryStep{Weight: 10, Observe: durationpb.New(time.Minute)},
+				&capsule.CanaryStep{Weight: 50},
+			),
+			valid: true,
+		},
+		{
+			name: "zero weight",
+			rs:   canaryStrategy(&capsule.CanaryStep{Weight: 0}),
+		},
+		{
+			name: "all instances",
+			rs:   canaryStrategy(&capsule.CanaryStep{Weight: 100}),
+		},
+		{
+			name: "decreasing weight",
+			rs:   canaryStrategy(&capsule.CanaryStep{Weight: 50}, &capsule.CanaryStep{Weight: 50}),
+		},
+		{
+			name: "negative observe",
+			rs:   canaryStrategy(&capsule.CanaryStep{Weight: 10, Observe: durationpb.New(-time.Minute)}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStrategy(tt.rs)
+			if tt.valid {
+				require.NoError(t, err)
+			} else {
+				require.True(t, errors.IsInvalidArgument(err))
+			}
+		})
+	}
+}
+
+func Test_ObserveCanary(t *testing.T) {
+	instances := func(canary int) []*capsule.Instance {
+		is := []*capsule.Instance{
+			{InstanceId: "stable-1", BuildId: "old", State: capsule.State_STATE_RUNNING, Ready: true},
+			{InstanceId: "stable-2", BuildId: "old", State: capsule.State_STATE_RUNNING, Ready: true},
+		}
+		for i := 0; i < canary; i++ {
+			is = append(is, &capsule.Instance{InstanceId: uuid.New().String(), BuildId: "new", State: capsule.State_STATE_RUNNING, Ready: true})
+		}
+		return is
+	}
+
+	tests := []struct {
+		name      string
+		instances []*capsule.Instance
+		step      *capsule.CanaryStep
+		cs        *capsule.CanaryStatus
+		event     bool
+		err       func(error) bool
+		state     capsule.RolloutState
+		expected  *capsule.CanaryStatus
+	}{
+		{
+			name:      "canary instances not running",
+			instances: instances(1),
+			step:      &capsule.CanaryStep{Weight: 50},
+			cs:        &capsule.CanaryStatus{Weight: 50},
+			err:       errors.IsUnavailable,
+			state:     capsule.RolloutState_ROLLOUT_STATE_OBSERVING,
+			expected:  &capsule.CanaryStatus{Weight: 50},
+		},
+		{
+			name:      "observing",
+			instances: instances(2),
+			step:      &capsule.CanaryStep{Weight: 50, Observe: durationpb.New(time.Hour)},
+			cs:        &capsule.CanaryStatus{Weight: 50, StepStartedAt: timestamppb.New(time.Now().Add(-time.Minute))},
+			state:     capsule.RolloutState_ROLLOUT_STATE_OBSERVING,
+			expected:  &capsule.CanaryStatus{Weight: 50},
+		},
+		{
+			name:      "observed",
+			instances: instances(2),
+			step:      &capsule.CanaryStep{Weight: 50, Observe: durationpb.New(time.Minute)},
+			cs:        &capsule.CanaryStatus{Weight: 50, StepStartedAt: timestamppb.New(time.Now().Add(-time.Hour))},
+			event:     true,
+			state:     capsule.RolloutState_ROLLOUT_STATE_DEPLOYING,
+			expected:  &capsule.CanaryStatus{Step: 1, Weight: 50},
+		},
+		{
+			name:      "waiting to be promoted",
+			instances: instances(2),
+			step:      &capsule.CanaryStep{Weight: 50},
+			cs:        &capsule.CanaryStatus{Weight: 50},
+			event:     true,
+			state:     capsule.RolloutState_ROLLOUT_STATE_OBSERVING,
+			expected:  &capsule.CanaryStatus{Weight: 50, Paused: true},
+		},
+		{
+			name:      "paused",
+			instances: instances(2),
+			step:      &capsule.CanaryStep{Weight: 50, Observe: durationpb.New(time.Minute)},
+			cs:        &capsule.CanaryStatus{Weight: 50, Paused: true, StepStartedAt: timestamppb.New(time.Now().Add(-time.Hour))},
+			state:     capsule.RolloutState_ROLLOUT_STATE_OBSERVING,
+			expected:  &capsule.CanaryStatus{Weight: 50, Paused: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.WithProjectID(context.Background(), uuid.New())
+			capsuleID := uuid.New().String()
+
+			cg := cluster.NewMockGateway(t)
+			cg.EXPECT().ListInstances(mock.Anything, capsuleID).Return(iterator.FromList(tt.instances), uint64(len(tt.instances)), nil)
+
+			cr := repository.NewMockCapsule(t)
+			if tt.event {
+				cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).Return(nil)
+			}
+
+			j := &rolloutJob{
+				s: &Service{
+					cg:     cg,
+					cr:     cr,
+					as:     &service_auth.Service{},
+					rw:     newRolloutWatchers(),
+					logger: zaptest.NewLogger(t),
+				},
+				capsuleID: capsuleID,
+				rolloutID: 3,
+			}
+
+			rc := &capsule.RolloutConfig{
+				BuildId:  "new",
+				Replicas: 4,
+				Strategy: canaryStrategy(tt.step),
+			}
+			cfg := &v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}
+			rs := canaryRolloutStatus(capsule.RolloutState_ROLLOUT_STATE_OBSERVING, tt.cs)
+
+			err := j.observeCanary(ctx, cfg, tt.step, rc, rs)
+			if tt.err != nil {
+				require.True(t, tt.err(err), err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, tt.state, rs.GetStatus().GetState())
+
+			cs := rs.GetStatus().GetCanary()
+			require.Equal(t, tt.err == nil, cs.GetStepStartedAt() != nil)
+			cs.StepStartedAt = nil
+			require.Equal(t, tt.expected.GetStep(), cs.GetStep())
+			require.Equal(t, tt.expected.GetWeight(), cs.GetWeight())
+			require.Equal(t, tt.expected.GetPaused(), cs.GetPaused())
+		})
+	}
+}
+
+func Test_AbortCanary(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().DeleteCanary(mock.Anything, capsuleID).Return(nil)
+	ccg.EXPECT().GetCapsuleConfig(mock.Anything, capsuleID).Return(&v1alpha1.Capsule{
+		ObjectMeta: v1.ObjectMeta{Name: capsuleID},
+		Spec:       v1alpha1.CapsuleSpec{Replicas: 2},
+	}, nil)
+	ccg.EXPECT().UpdateCapsuleConfig(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, cfg *v1alpha1.Capsule) error {
+		require.Equal(t, int32(4), cfg.Spec.Replicas)
+		return nil
+	})
+
+	s := &Service{
+		ccg:    ccg,
+		logger: zaptest.NewLogger(t),
+	}
+
+	// The stable instances are scaled back up to before the rollout.
+	rs := canaryRolloutStatus(capsule.RolloutState_ROLLOUT_STATE_OBSERVING, &capsule.CanaryStatus{Weight: 50})
+	rs.StableReplicas = 4
+	require.NoError(t, s.abortCanary(ctx, capsuleID, rs))
+}
+
+func Test_PauseRollout(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+	rc := &capsule.RolloutConfig{
+		Strategy: canaryStrategy(&capsule.CanaryStep{Weight: 10, Observe: durationpb.New(time.Hour)}),
+	}
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).Return(rc, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DEPLOYING), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(2)).Return(rc, canaryRolloutStatus(capsule.RolloutState_ROLLOUT_STATE_OBSERVING, &capsule.CanaryStatus{Weight: 10}), 2, nil)
+	cr.EXPECT().UpdateRolloutStatus(mock.Anything, capsuleID, uint64(2), uint64(2), mock.Anything).RunAndReturn(func(_ context.Context, _ string, _ uint64, _ uint64, rs *rollout.Status) error {
+		require.True(t, rs.GetStatus().GetCanary().GetPaused())
+		return nil
+	})
+	cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).Return(nil)
+
+	s := &Service{
+		cr:     cr,
+		as:     &service_auth.Service{},
+		rw:     newRolloutWatchers(),
+		logger: zaptest.NewLogger(t),
+	}
+
+	// The canary steps haven't started.
+	require.True(t, errors.IsFailedPrecondition(s.PauseRollout(ctx, capsuleID, 1)))
+	require.NoError(t, s.PauseRollout(ctx, capsuleID, 2))
+}
+
+func Test_PromoteRollout(t *testing.T) {
+	rc := &capsule.RolloutConfig{
+		Strategy: canaryStrategy(&capsule.CanaryStep{Weight: 10}, &capsule.CanaryStep{Weight: 50}),
+	}
+
+	tests := []struct {
+		name string
+		full bool
+		step uint32
+	}{
+		{
+			name: "next step",
+			step: 1,
+		},
+		{
+			name: "full",
+			full: true,
+			step: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.WithProjectID(context.Background(), uuid.New())
+			capsuleID := uuid.New().String()
+
+			cr := repository.NewMockCapsule(t)
+			cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).Return(rc, canaryRolloutStatus(capsule.RolloutState_ROLLOUT_STATE_OBSERVING, &capsule.CanaryStatus{Weight: 10, Paused: true}), 2, nil)
+			cr.EXPECT().UpdateRolloutStatus(mock.Anything, capsuleID, uint64(1), uint64(2), mock.Anything).RunAndReturn(func(_ context.Context, _ string, _ uint64, _ uint64, rs *rollout.Status) error {
+				require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_DEPLOYING, rs.GetStatus().GetState())
+				require.Equal(t, tt.step, rs.GetStatus().GetCanary().GetStep())
+				require.False(t, rs.GetStatus().GetCanary().GetPaused())
+				return nil
+			})
+			cr.EXPECT().LeaseRollout(mock.Anything, capsuleID, uint64(1), mock.Anything, mock.Anything).Return(nil)
+			cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).Return(nil)
+
+			s := &Service{
+				cr:     cr,
+				as:     &service_auth.Service{},
+				q:      NewQueue[Job](),
+				rw:     newRolloutWatchers(),
+				logger: zaptest.NewLogger(t),
+			}
+
+			require.NoError(t, s.PromoteRollout(ctx, capsuleID, 1, tt.full))
+
+			// The rollout continues right away.
+			require.Equal(t, 1, s.q.is.Len())
+			require.Equal(t, uint64(1), s.q.is.Peek().t.(*rolloutJob).rolloutID)
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package capsule
+
+import (
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// defaultCronJobHistoryLimit is the number of finished runs kept of a cron job
+// without a history limit.
+const defaultCronJobHistoryLimit = 3
+
+func validateCronJob(cj *capsule.CronJob) error {
+	if _, err := cron.ParseStandard(cj.GetSchedule()); err != nil {
+		return errors.InvalidArgumentErrorf("invalid cron job schedule: %v", err)
+	}
+
+	if cj.GetTimeout() != nil && cj.GetTimeout().AsDuration() <= 0 {
+		return errors.InvalidArgumentErrorf("cron job timeout must be positive")
+	}
+
+	return nil
+}
+
+// validateWorkload checks that the rollout config does not combine a cron job
+// with settings only used by long-running instances, and that capsules with
+// volumes are not rolled out in canary steps.
+func validateWorkload(rc *capsule.RolloutConfig) error {
+	if len(rc.GetVolumes()) > 0 {
+		if rc.GetCronJob() != nil {
+			return errors.InvalidArgumentErrorf("a cron job cannot have volumes")
+		}
+
+		if len(rc.GetStrategy().GetCanary().GetSteps()) > 0 {
+			return errors.InvalidArgumentErrorf("a capsule with volumes cannot be rolled out in canary steps")
+		}
+	}
+
+	if rc.GetCronJob() == nil {
+		return nil
+	}
+
+	if len(rc.GetStrategy().GetCanary().GetSteps()) > 0 {
+		return errors.InvalidArgumentErrorf("a cron job cannot be rolled out in canary steps")
+	}
+
+	if rc.GetAutoscaler() != nil {
+		return errors.InvalidArgumentErrorf("a cron job cannot be autoscaled")
+	}
+
+	return nil
+}
+
+func capsuleCronJob(cj *capsule.CronJob) *v1alpha1.CapsuleCronJob {
+	if cj == nil {
+		return nil
+	}
+
+	ccj := &v1alpha1.CapsuleCronJob{
+		Schedule:     cj.GetSchedule(),
+		HistoryLimit: int32(cj.GetHistoryLimit()),
+	}
+	if ccj.HistoryLimit == 0 {
+		ccj.HistoryLimit = defaultCronJobHistoryLimit
+	}
+	if cj.GetTimeout() != nil {
+		ccj.TimeoutSeconds = int64(cj.GetTimeout().AsDuration().Seconds())
+	}
+
+	switch cj.GetConcurrencyPolicy() {
+	case capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_FORBID:
+		ccj.ConcurrencyPolicy = batchv1.ForbidConcurrent
+	case capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_REPLACE:
+		ccj.ConcurrencyPolicy = batchv1.ReplaceConcurrent
+	default:
+		ccj.ConcurrencyPolicy = batchv1.AllowConcurrent
+	}
+
+	return ccj
+}
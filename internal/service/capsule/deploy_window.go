@@ -0,0 +1,38 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// holdForDeployWindow postpones the rollout until the next deploy window of
+// the project, returning true if the rollout is held.
+func (j *rolloutJob) holdForDeployWindow(ctx context.Context, rs *rollout.Status) (bool, error) {
+	set, err := j.s.ps.GetProjectSettings(ctx)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	next, err := utils.NextDeployWindow(set.GetDeployWindows(), now)
+	if err != nil {
+		return false, err
+	}
+
+	if !next.After(now) {
+		return false, nil
+	}
+
+	rs.ScheduledAt = timestamppb.New(next)
+	rs.Status.ScheduledAt = rs.ScheduledAt
+	rs.Status.Message = fmt.Sprintf("rollout held until the next deploy window at %v", next.Format(time.RFC3339))
+	return true, nil
+}
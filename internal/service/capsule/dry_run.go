@@ -0,0 +1,62 @@
+package capsule
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+// DeployDryRun returns the config of the rollout the changes would create, and
+// the objects that would be applied to the cluster. Nothing is persisted. The
+// changes are applied to the newest rollout that has started, also while it
+// is in progress, so a dry-run shows what a deploy queued behind it would do.
+func (s *Service) DeployDryRun(ctx context.Context, capsuleID string, cs []*capsule.Change) (*capsule.RolloutConfig, []string, error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cfg, err := s.ccg.GetCapsuleConfig(ctx, capsuleID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rc := &capsule.RolloutConfig{
+		Replicas: 1,
+	}
+	if _, pRC, _, _, err := s.latestStartedRollout(ctx, capsuleID); errors.IsNotFound(err) {
+	} else if err != nil {
+		return nil, nil, err
+	} else {
+		rc = pRC
+	}
+
+	if err := s.changeRolloutConfig(ctx, capsuleID, rc, cs); err != nil {
+		return nil, nil, err
+	}
+
+	files := configFileMaps(projectID, rc)
+	applyRolloutConfig(cfg, rc)
+	cfg.APIVersion = v1alpha1.GroupVersion.String()
+	cfg.Kind = "Capsule"
+
+	objs, err := s.ccg.DryRunCapsuleConfig(ctx, cfg, rolloutEnvironmentVariables(projectID, rc), files)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out []string
+	for _, o := range append([]interface{}{cfg}, objs...) {
+		bs, err := yaml.Marshal(o)
+		if err != nil {
+			return nil, nil, err
+		}
+		out = append(out, string(bs))
+	}
+
+	return rc, out, nil
+}
@@ -0,0 +1,63 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/internal/repository"
+	service_auth "github.com/rigdev/rig/internal/service/auth"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_DeployDryRun_RolloutInProgress(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().GetCapsuleConfig(mock.Anything, capsuleID).Return(&v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}, nil)
+	ccg.EXPECT().DryRunCapsuleConfig(mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+
+	cr := repository.NewMockCapsule(t)
+	rollouts := newTestRollouts(cr, capsuleID)
+
+	// Rollout 1 is done, rollout 2 is in progress and rollout 3 is scheduled
+	// for later.
+	rollouts.rcs = []*capsule.RolloutConfig{
+		{BuildId: "nginx:1", Replicas: 1},
+		{BuildId: "nginx:2", Replicas: 2},
+		{BuildId: "nginx:3", Replicas: 3},
+	}
+	rollouts.rss = []*rollout.Status{
+		rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE),
+		rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DEPLOYING),
+		{Status: &capsule.RolloutStatus{
+			State:       capsule.RolloutState_ROLLOUT_STATE_PENDING,
+			ScheduledAt: timestamppb.New(time.Now().Add(time.Hour)),
+		}},
+	}
+
+	s := &Service{
+		cr:     cr,
+		ccg:    ccg,
+		as:     &service_auth.Service{},
+		logger: zaptest.NewLogger(t),
+	}
+
+	// The dry-run is based on the rollout in progress, not the one waiting to
+	// start.
+	rc, _, err := s.DeployDryRun(ctx, capsuleID, []*capsule.Change{{Field: &capsule.Change_BuildId{BuildId: "nginx:4"}}})
+	require.NoError(t, err)
+	require.Equal(t, "nginx:4", rc.GetBuildId())
+	require.Equal(t, uint32(2), rc.GetReplicas())
+}
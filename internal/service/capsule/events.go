@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
 	"github.com/rigdev/rig-go-api/model"
 	"github.com/rigdev/rig/pkg/iterator"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -21,7 +22,23 @@ func (s *Service) CreateEvent(ctx context.Context, capsuleID string, rolloutID u
 	} else {
 		e.CreatedBy = a
 	}
-	return s.cr.CreateEvent(ctx, capsuleID, e)
+	if err := s.cr.CreateEvent(ctx, capsuleID, e); err != nil {
+		return err
+	}
+
+	s.notifyRollout(ctx, capsuleID, rolloutID)
+
+	if t := webhookEventType(ed); t != webhook.EventType_EVENT_TYPE_UNSPECIFIED {
+		s.emitWebhook(ctx, &webhook.Event{
+			Type:      t,
+			CapsuleId: capsuleID,
+			RolloutId: rolloutID,
+			Message:   message,
+			CreatedAt: e.GetCreatedAt(),
+		})
+	}
+
+	return nil
 }
 
 func (s *Service) ListEvents(ctx context.Context, capsuleID string, rolloutID uint64, pagination *model.Pagination) (iterator.Iterator[*capsule.Event], uint64, error) {
@@ -0,0 +1,151 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	hookPreDeploy  = "pre-deploy"
+	hookPostDeploy = "post-deploy"
+)
+
+func hookInstanceID(capsuleID string, rolloutID uint64, kind string) string {
+	return fmt.Sprintf("%s-hook-%d-%s", capsuleID, rolloutID, kind)
+}
+
+// hookOrNil returns nil for a hook without a command, which removes the hook.
+func hookOrNil(h *capsule.Hook) *capsule.Hook {
+	if h.GetCommand() == "" {
+		return nil
+	}
+
+	return h
+}
+
+// preDeploy runs the pre-deploy hook of the rollout with the new build, and
+// returns true once the rollout can move on to DEPLOYING. The rollout fails
+// if the hook exits with a non-zero code.
+func (j *rolloutJob) preDeploy(ctx context.Context, cfg *v1alpha1.Capsule, rc *capsule.RolloutConfig, rs *rollout.Status) (bool, error) {
+	// Automatic rollbacks restore a known state as fast as possible, like
+	// they skip the canary steps.
+	hook := rc.GetHooks().GetPreDeploy()
+	if hook == nil || rc.GetRollbackOf() != 0 {
+		return true, nil
+	}
+
+	if _, err := j.s.cr.GetBuild(ctx, j.capsuleID, rc.GetBuildId()); errors.IsNotFound(err) {
+		return false, errors.AbortedErrorf("build not available")
+	} else if err != nil {
+		return false, err
+	}
+
+	hcfg := cfg.DeepCopy()
+	applyRolloutConfig(hcfg, rc)
+	if err := j.setPullSecret(ctx, hcfg, rc.GetBuildId()); err != nil {
+		return false, err
+	}
+
+	// The hook mounts the config files of the rollout, which are otherwise
+	// only stored when deploying.
+	exited, code, err := j.runHook(ctx, hcfg, configFileMaps(j.projectID, rc), rc, rs, hookPreDeploy, hook)
+	if err != nil || !exited {
+		return false, err
+	}
+
+	if code != 0 {
+		rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_FAILED
+		rs.Status.Message = fmt.Sprintf("pre-deploy hook failed with exit code %d", code)
+		rs.ScheduledAt = nil
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// postDeploy runs the post-deploy hook of the rollout, and returns true once
+// the rollout can move on to DONE. The instances already run the new build,
+// so a failing hook does not fail the rollout.
+func (j *rolloutJob) postDeploy(ctx context.Context, cfg *v1alpha1.Capsule, rc *capsule.RolloutConfig, rs *rollout.Status) (bool, error) {
+	hook := rc.GetHooks().GetPostDeploy()
+	if hook == nil || rc.GetRollbackOf() != 0 {
+		return true, nil
+	}
+
+	exited, code, err := j.runHook(ctx, cfg.DeepCopy(), nil, rc, rs, hookPostDeploy, hook)
+	if err != nil || !exited {
+		return false, err
+	}
+
+	if code != 0 {
+		msg := fmt.Sprintf("post-deploy hook failed with exit code %d", code)
+		if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, msg, &capsule.EventData{Kind: &capsule.EventData_Error{Error: &capsule.ErrorEvent{}}}); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// runHook starts the hook if it is not running already, and returns true and
+// the exit code once the hook has exited. The hook runs with the config files
+// of cfg, and files are stored before the hook is started.
+func (j *rolloutJob) runHook(
+	ctx context.Context,
+	cfg *v1alpha1.Capsule,
+	files []*v1.ConfigMap,
+	rc *capsule.RolloutConfig,
+	rs *rollout.Status,
+	kind string,
+	hook *capsule.Hook,
+) (bool, int32, error) {
+	instanceID := hookInstanceID(j.capsuleID, j.rolloutID, kind)
+	exited, code, err := j.s.ccg.GetHookStatus(ctx, j.capsuleID, instanceID)
+	if errors.IsNotFound(err) {
+		envs, err := j.environmentVariables(ctx, rc, rs)
+		if err != nil {
+			return false, 0, err
+		}
+
+		for _, cm := range files {
+			if err := j.s.ccg.SetFile(ctx, j.capsuleID, cm); err != nil {
+				return false, 0, err
+			}
+		}
+
+		if err := j.s.ccg.StartHook(ctx, cfg, envs, &cluster.Hook{
+			InstanceID: instanceID,
+			Kind:       kind,
+			Command:    hook.GetCommand(),
+			Args:       hook.GetArgs(),
+		}); err != nil {
+			return false, 0, err
+		}
+
+		rs.Status.Message = fmt.Sprintf("running %s hook", kind)
+		return false, 0, j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, fmt.Sprintf("running %s hook in instance %s", kind, instanceID), &capsule.EventData{
+			Kind: &capsule.EventData_Hook{Hook: &capsule.HookEvent{InstanceId: instanceID}},
+		})
+	} else if err != nil {
+		return false, 0, err
+	}
+
+	if !exited {
+		return false, 0, nil
+	}
+
+	if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, fmt.Sprintf("%s hook exited with code %d", kind, code), &capsule.EventData{
+		Kind: &capsule.EventData_Hook{Hook: &capsule.HookEvent{InstanceId: instanceID, Exited: true, ExitCode: code}},
+	}); err != nil {
+		return false, 0, err
+	}
+
+	return true, code, nil
+}
@@ -0,0 +1,135 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/internal/repository"
+	service_auth "github.com/rigdev/rig/internal/service/auth"
+	"github.com/rigdev/rig/internal/service/project"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	v1 "k8s.io/api/core/v1"
+)
+
+func Test_PreDeployHook_SkippedOnRollback(t *testing.T) {
+	j := &rolloutJob{
+		s: &Service{
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: uuid.New(),
+		capsuleID: uuid.New().String(),
+		rolloutID: 3,
+	}
+
+	rc := &capsule.RolloutConfig{
+		RollbackOf: 2,
+		Hooks: &capsule.RolloutHooks{
+			PreDeploy: &capsule.Hook{Command: "./migrate", Args: []string{"up"}},
+		},
+	}
+
+	// No gateway is set, so the hook must not be started.
+	done, err := j.preDeploy(context.Background(), &v1alpha1.Capsule{}, rc, &rollout.Status{Status: &capsule.RolloutStatus{}})
+	require.NoError(t, err)
+	require.True(t, done)
+}
+
+func Test_PreDeployHook_ConfigFiles(t *testing.T) {
+	projectID := uuid.New()
+	capsuleID := uuid.New().String()
+	instanceID := hookInstanceID(capsuleID, 3, hookPreDeploy)
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, "nginx:1").Return(&capsule.Build{BuildId: "nginx:1"}, nil)
+	cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).Return(nil)
+
+	ps := project.NewMockService(t)
+	ps.EXPECT().GetProjectDockerSecret(mock.Anything, "docker.io").Return(nil, errors.NotFoundErrorf("secret not found"))
+
+	var stored []string
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().DeleteSecret(mock.Anything, capsuleID, mock.Anything, projectID.String()).Return(nil)
+	ccg.EXPECT().GetHookStatus(mock.Anything, capsuleID, instanceID).Return(false, 0, errors.NotFoundErrorf("hook not found"))
+	ccg.EXPECT().SetFile(mock.Anything, capsuleID, mock.Anything).RunAndReturn(func(_ context.Context, _ string, cm *v1.ConfigMap) error {
+		stored = append(stored, cm.GetName())
+		return nil
+	})
+	ccg.EXPECT().StartHook(mock.Anything, mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, cfg *v1alpha1.Capsule, _ map[string]string, _ *cluster.Hook) error {
+		// The files are stored before the hook mounts them.
+		require.Equal(t, []string{configFileName("/etc/app/config.yaml")}, stored)
+		require.Equal(t, []v1alpha1.File{{
+			Path: "/etc/app/config.yaml",
+			ConfigMap: &v1alpha1.FileContentRef{
+				Name: configFileName("/etc/app/config.yaml"),
+				Key:  "content",
+			},
+		}}, cfg.Spec.Files)
+		return nil
+	})
+
+	j := &rolloutJob{
+		s: &Service{
+			cr:     cr,
+			ccg:    ccg,
+			ps:     ps,
+			as:     &service_auth.Service{},
+			rw:     newRolloutWatchers(),
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: projectID,
+		capsuleID: capsuleID,
+		rolloutID: 3,
+	}
+
+	rc := &capsule.RolloutConfig{
+		BuildId: "nginx:1",
+		ConfigFiles: []*capsule.ConfigFile{
+			{Path: "/etc/app/config.yaml", Content: []byte("debug: true")},
+		},
+		Hooks: &capsule.RolloutHooks{
+			PreDeploy: &capsule.Hook{Command: "./migrate", Args: []string{"up"}},
+		},
+	}
+
+	ctx := auth.WithProjectID(context.Background(), projectID)
+	done, err := j.preDeploy(ctx, &v1alpha1.Capsule{}, rc, &rollout.Status{Status: &capsule.RolloutStatus{}})
+	require.NoError(t, err)
+	require.False(t, done)
+}
+
+func Test_PostDeployHook_Running(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().GetHookStatus(mock.Anything, capsuleID, hookInstanceID(capsuleID, 3, hookPostDeploy)).Return(false, 0, nil)
+
+	j := &rolloutJob{
+		s: &Service{
+			ccg:    ccg,
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: uuid.New(),
+		capsuleID: capsuleID,
+		rolloutID: 3,
+	}
+
+	rc := &capsule.RolloutConfig{
+		Hooks: &capsule.RolloutHooks{
+			PostDeploy: &capsule.Hook{Command: "./notify"},
+		},
+	}
+
+	// The rollout waits for the hook to exit.
+	done, err := j.postDeploy(context.Background(), &v1alpha1.Capsule{}, rc, &rollout.Status{Status: &capsule.RolloutStatus{}})
+	require.NoError(t, err)
+	require.False(t, done)
+}
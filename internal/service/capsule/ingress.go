@@ -0,0 +1,376 @@
+package capsule
+
+import (
+	"context"
+	"crypto/tls"
+	"path"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setNetwork validates the ingress of the interfaces and sets the network of
+// the rollout. Supplied certificates are kept in the network until the rollout
+// is created, where storeCertificates replaces them with the ID of the stored
+// certificate.
+func (s *Service) setNetwork(rc *capsule.RolloutConfig, n *capsule.Network) error {
+	var issued, supplied bool
+	for _, inf := range n.GetInterfaces() {
+		if err := validateRateLimit(inf.GetName(), inf.GetRateLimit()); err != nil {
+			return err
+		}
+
+		ing := inf.GetPublic().GetMethod().GetIngress()
+		if ing == nil {
+			continue
+		}
+
+		if p := ing.GetPathPrefix(); p != "" && !path.IsAbs(p) {
+			return errors.InvalidArgumentErrorf("path prefix of interface '%s' must be an absolute path", inf.GetName())
+		}
+
+		cert := ing.GetCertificate()
+		if cert == nil {
+			issued = issued || ing.GetTls()
+			continue
+		}
+
+		if !ing.GetTls() {
+			return errors.InvalidArgumentErrorf("interface '%s' has a certificate but TLS is disabled", inf.GetName())
+		}
+		supplied = true
+
+		if len(cert.GetCertificate()) == 0 && len(cert.GetPrivateKey()) == 0 {
+			if cert.GetSecretId() == "" {
+				return errors.InvalidArgumentErrorf("missing certificate of interface '%s'", inf.GetName())
+			}
+			if _, err := uuid.Parse(cert.GetSecretId()); err != nil {
+				return errors.InvalidArgumentErrorf("invalid certificate of interface '%s': %v", inf.GetName(), err)
+			}
+			// The certificate is already stored.
+			continue
+		}
+
+		if _, err := tls.X509KeyPair(cert.GetCertificate(), cert.GetPrivateKey()); err != nil {
+			return errors.InvalidArgumentErrorf("invalid certificate of interface '%s': %v", inf.GetName(), err)
+		}
+	}
+
+	// cert-manager issues certificates for every host of an ingress, which
+	// would replace the supplied certificates.
+	if issued && supplied && s.cfg.Cluster.CertManager.ClusterIssuer != "" {
+		return errors.InvalidArgumentErrorf("interfaces with and without a certificate can't be mixed when certificates are issued by the cluster")
+	}
+
+	rc.Network = proto.Clone(n).(*capsule.Network)
+	return nil
+}
+
+func validateRateLimit(interfaceName string, rl *capsule.RateLimit) error {
+	if !rl.GetEnabled() {
+		return nil
+	}
+
+	if rl.GetKey() == capsule.RateLimit_KEY_HEADER && rl.GetHeader() == "" {
+		return errors.InvalidArgumentErrorf("rate limit of interface '%s' is keyed by a header, but the header is missing", interfaceName)
+	}
+
+	limits := []*capsule.Limit{rl.GetDefault()}
+	for _, h := range rl.GetHttp() {
+		limits = append(limits, h.GetLimit())
+	}
+	for _, l := range rl.GetGrpc().GetMethods() {
+		limits = append(limits, l)
+	}
+	for _, l := range limits {
+		if l.GetRequestsPerSecond() < 0 {
+			return errors.InvalidArgumentErrorf("rate limit of interface '%s' must not be negative", interfaceName)
+		}
+	}
+
+	return nil
+}
+
+// storeCertificates stores the supplied certificates of the rollout, in its
+// network and in its changes, and replaces them with the ID of the stored
+// certificate. The changes are copied, so the caller's changes are left as is.
+// The IDs of the stored certificates are returned, so they can be deleted if
+// the rollout isn't persisted after all.
+func (s *Service) storeCertificates(ctx context.Context, rc *capsule.RolloutConfig) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	stored := map[string]string{}
+	store := func(n *capsule.Network) error {
+		for _, inf := range n.GetInterfaces() {
+			cert := inf.GetPublic().GetMethod().GetIngress().GetCertificate()
+			if len(cert.GetCertificate()) == 0 && len(cert.GetPrivateKey()) == 0 {
+				continue
+			}
+
+			bs, err := proto.Marshal(cert)
+			if err != nil {
+				return err
+			}
+
+			// The same certificate is both in the network and in the change
+			// that set it.
+			if id, ok := stored[string(bs)]; ok {
+				cert.Certificate = nil
+				cert.PrivateKey = nil
+				cert.SecretId = id
+				continue
+			}
+
+			secretID := uuid.New()
+			if err := s.sr.Create(ctx, secretID, bs); err != nil {
+				return err
+			}
+			ids = append(ids, secretID)
+			stored[string(bs)] = secretID.String()
+
+			cert.Certificate = nil
+			cert.PrivateKey = nil
+			cert.SecretId = secretID.String()
+		}
+		return nil
+	}
+
+	if err := store(rc.GetNetwork()); err != nil {
+		s.deleteCertificates(ctx, ids)
+		return nil, err
+	}
+
+	changes := make([]*capsule.Change, len(rc.GetChanges()))
+	for i, c := range rc.GetChanges() {
+		if c.GetNetwork() == nil {
+			changes[i] = c
+			continue
+		}
+
+		changes[i] = proto.Clone(c).(*capsule.Change)
+		if err := store(changes[i].GetNetwork()); err != nil {
+			s.deleteCertificates(ctx, ids)
+			return nil, err
+		}
+	}
+	rc.Changes = changes
+
+	return ids, nil
+}
+
+// deleteCertificates deletes stored certificates. Errors are logged, as the
+// certificates are only deleted to clean up.
+func (s *Service) deleteCertificates(ctx context.Context, ids []uuid.UUID) {
+	for _, id := range ids {
+		if err := s.sr.Delete(ctx, id); err != nil && !errors.IsNotFound(err) {
+			s.logger.Warn("could not delete certificate", zap.Stringer("secret_id", id), zap.Error(err))
+		}
+	}
+}
+
+// certificateIDs returns the IDs of the stored certificates of the network.
+func certificateIDs(n *capsule.Network) map[string]struct{} {
+	ids := map[string]struct{}{}
+	for _, inf := range n.GetInterfaces() {
+		if id := inf.GetPublic().GetMethod().GetIngress().GetCertificate().GetSecretId(); id != "" {
+			ids[id] = struct{}{}
+		}
+	}
+	return ids
+}
+
+// carryCertificates replaces the certificates of the interfaces of the rollout
+// with the certificates of the interfaces of the same name in `from`. It is
+// used by rollbacks, as the certificates of older rollouts are deleted once
+// they are replaced.
+func carryCertificates(rc, from *capsule.RolloutConfig) {
+	current := map[string]*capsule.Certificate{}
+	for _, inf := range from.GetNetwork().GetInterfaces() {
+		if cert := inf.GetPublic().GetMethod().GetIngress().GetCertificate(); cert.GetSecretId() != "" {
+			current[inf.GetName()] = cert
+		}
+	}
+
+	for _, inf := range rc.GetNetwork().GetInterfaces() {
+		ing := inf.GetPublic().GetMethod().GetIngress()
+		if ing.GetCertificate() == nil {
+			continue
+		}
+		if cert, ok := current[inf.GetName()]; ok {
+			ing.Certificate = proto.Clone(cert).(*capsule.Certificate)
+		}
+	}
+}
+
+// validateCertificates checks that the stored certificates of the rollout
+// still exist.
+func (s *Service) validateCertificates(ctx context.Context, rc *capsule.RolloutConfig) error {
+	for _, inf := range rc.GetNetwork().GetInterfaces() {
+		id := inf.GetPublic().GetMethod().GetIngress().GetCertificate().GetSecretId()
+		if id == "" {
+			continue
+		}
+
+		secretID, err := uuid.Parse(id)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.sr.Get(ctx, secretID); errors.IsNotFound(err) {
+			return errors.FailedPreconditionErrorf("the certificate of interface '%s' no longer exists", inf.GetName())
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteUnusedCertificates deletes the stored certificates no longer used
+// once the rollout is done: the certificates set by the changes of the rollout
+// but not in its network, and the certificates of the rollouts since the
+// previous successful rollout, which the rollout replaced. Certificates of
+// rollouts not done yet, like queued rollouts, are kept.
+func (j *rolloutJob) deleteUnusedCertificates(ctx context.Context, rc *capsule.RolloutConfig) {
+	unused := map[string]struct{}{}
+	used := certificateIDs(rc.GetNetwork())
+	collect := func(ids map[string]struct{}, rc *capsule.RolloutConfig) {
+		for id := range certificateIDs(rc.GetNetwork()) {
+			ids[id] = struct{}{}
+		}
+		for _, c := range rc.GetChanges() {
+			for id := range certificateIDs(c.GetNetwork()) {
+				ids[id] = struct{}{}
+			}
+		}
+	}
+
+	collect(unused, rc)
+	for id := j.rolloutID - 1; id > 0; id-- {
+		prc, prs, _, err := j.s.cr.GetRollout(ctx, j.capsuleID, id)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			j.s.logger.Warn("could not get rollout", zap.Uint64("rollout_id", id), zap.Error(err))
+			return
+		}
+
+		if !isRolloutTerminated(prs) {
+			collect(used, prc)
+			continue
+		}
+
+		collect(unused, prc)
+		if prs.GetStatus().GetState() == capsule.RolloutState_ROLLOUT_STATE_DONE {
+			break
+		}
+	}
+
+	for id := j.rolloutID + 1; ; id++ {
+		nrc, _, _, err := j.s.cr.GetRollout(ctx, j.capsuleID, id)
+		if errors.IsNotFound(err) {
+			break
+		} else if err != nil {
+			j.s.logger.Warn("could not get rollout", zap.Uint64("rollout_id", id), zap.Error(err))
+			return
+		}
+
+		collect(used, nrc)
+	}
+
+	var ids []uuid.UUID
+	for id := range unused {
+		if _, ok := used[id]; ok {
+			continue
+		}
+
+		secretID, err := uuid.Parse(id)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, secretID)
+	}
+	j.s.deleteCertificates(ctx, ids)
+}
+
+// setCertificates stores the certificates of the interfaces of the rollout as
+// TLS secrets of the capsule, and removes the secrets no longer used.
+func (j *rolloutJob) setCertificates(ctx context.Context, stable *v1alpha1.Capsule, rc *capsule.RolloutConfig) error {
+	used := map[string]struct{}{}
+	for _, inf := range rc.GetNetwork().GetInterfaces() {
+		cert := inf.GetPublic().GetMethod().GetIngress().GetCertificate()
+		if !inf.GetPublic().GetEnabled() || cert.GetSecretId() == "" {
+			continue
+		}
+
+		secretID, err := uuid.Parse(cert.GetSecretId())
+		if err != nil {
+			return err
+		}
+
+		bs, err := j.s.sr.Get(ctx, secretID)
+		if err != nil {
+			return err
+		}
+
+		stored := &capsule.Certificate{}
+		if err := proto.Unmarshal(bs, stored); err != nil {
+			return err
+		}
+
+		name := cluster.TLSSecretName(j.capsuleID, inf.GetName())
+		if err := j.s.ccg.SetSecret(ctx, j.capsuleID, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: j.projectID.String(),
+			},
+			Type: v1.SecretTypeTLS,
+			Data: map[string][]byte{
+				v1.TLSCertKey:       stored.GetCertificate(),
+				v1.TLSPrivateKeyKey: stored.GetPrivateKey(),
+			},
+		}); err != nil {
+			return err
+		}
+		used[name] = struct{}{}
+	}
+
+	for _, inf := range stable.Spec.Interfaces {
+		if inf.Public == nil || inf.Public.Ingress == nil || inf.Public.Ingress.TLS == nil {
+			continue
+		}
+
+		name := inf.Public.Ingress.TLS.SecretName
+		if _, ok := used[name]; ok || name == "" {
+			continue
+		}
+
+		if err := j.s.ccg.DeleteSecret(ctx, j.capsuleID, name, j.projectID.String()); errors.IsNotFound(err) {
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func capsuleIngress(capsuleID, interfaceName string, ing *capsule.RoutingMethod_Ingress) *v1alpha1.CapsuleInterfaceIngress {
+	ci := &v1alpha1.CapsuleInterfaceIngress{
+		Host:       ing.GetHost(),
+		PathPrefix: ing.GetPathPrefix(),
+	}
+	if ing.GetTls() {
+		ci.TLS = &v1alpha1.CapsuleInterfaceIngressTLS{}
+		if ing.GetCertificate() != nil {
+			ci.TLS.SecretName = cluster.TLSSecretName(capsuleID, interfaceName)
+		}
+	}
+
+	return ci
+}
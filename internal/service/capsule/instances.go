@@ -0,0 +1,153 @@
+package capsule
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/model"
+	internal_capsule "github.com/rigdev/rig/gen/go/capsule"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.uber.org/zap"
+)
+
+// instanceWatchInterval is how often the instances of all capsules are
+// checked for crash loops.
+const instanceWatchInterval = 30 * time.Second
+
+// listInstances lists the instances of the capsule, reporting the crash
+// looping instances to webhooks.
+func (s *Service) listInstances(ctx context.Context, capsuleID string) (iterator.Iterator[*capsule.Instance], uint64, error) {
+	it, total, err := s.listInstanceStatuses(ctx, capsuleID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return iterator.Map(it, func(i *capsule.Instance) (*capsule.Instance, error) {
+		s.reportCrashLoop(ctx, capsuleID, i)
+		return i, nil
+	}), total, nil
+}
+
+// listInstanceStatuses lists the instances of the capsule as observed in its
+// status. Clusters without a status of the capsule fall back to listing the
+// instances directly.
+func (s *Service) listInstanceStatuses(ctx context.Context, capsuleID string) (iterator.Iterator[*capsule.Instance], uint64, error) {
+	if s.csg == nil {
+		return s.cg.ListInstances(ctx, capsuleID)
+	}
+
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	it, total, err := s.csg.ListInstanceStatuses(ctx, projectID.String(), capsuleID)
+	if errors.IsNotFound(err) || errors.IsUnimplemented(err) {
+		return s.cg.ListInstances(ctx, capsuleID)
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	return iterator.Map(it, func(i *internal_capsule.Instance) (*capsule.Instance, error) {
+		return &capsule.Instance{
+			InstanceId:   i.GetInstanceId(),
+			BuildId:      i.GetBuildId(),
+			State:        capsule.State(i.GetState()),
+			RestartCount: i.GetRestartCount(),
+			CreatedAt:    i.GetCreatedAt(),
+			StartedAt:    i.GetStartedAt(),
+			FinishedAt:   i.GetFinishedAt(),
+			Ready:        i.GetReady(),
+		}, nil
+	}), total, nil
+}
+
+// instanceWatchJob lists the instances of all capsules, so crash looping
+// instances are reported to webhooks when no rollout is observing them.
+type instanceWatchJob struct {
+	s *Service
+}
+
+func (j *instanceWatchJob) Run(ctx context.Context) error {
+	defer j.s.q.AddJob(j, time.Now().Add(instanceWatchInterval))
+
+	return j.s.watchInstances(ctx)
+}
+
+func (s *Service) watchInstances(ctx context.Context) error {
+	var pids []uuid.UUID
+	p := &model.Pagination{
+		Limit: 100,
+	}
+	for {
+		it, total, err := s.ps.List(ctx, p)
+		if err != nil {
+			return err
+		}
+
+		ps, err := iterator.Collect(it)
+		if err != nil {
+			return err
+		}
+
+		for _, pr := range ps {
+			pids = append(pids, uuid.UUID(pr.GetProjectId()))
+		}
+
+		p.Offset += p.Limit
+		if p.Offset >= uint32(total) {
+			break
+		}
+	}
+
+	for _, pid := range pids {
+		projectCtx := auth.WithProjectID(ctx, pid)
+		it, _, err := s.ccg.ListCapsuleConfigs(projectCtx, &model.Pagination{})
+		if err != nil {
+			s.logger.Info("failed to list capsules for project", zap.Stringer("project_id", pid), zap.Error(err))
+			continue
+		}
+
+		cfgs, err := iterator.Collect(it)
+		if err != nil {
+			s.logger.Info("failed to list capsules for project", zap.Stringer("project_id", pid), zap.Error(err))
+			continue
+		}
+
+		for _, cfg := range cfgs {
+			if err := s.watchCapsuleInstances(projectCtx, pid, cfg.GetName()); err != nil {
+				s.logger.Info("failed to list instances of capsule", zap.Stringer("project_id", pid), zap.String("capsule_id", cfg.GetName()), zap.Error(err))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) watchCapsuleInstances(ctx context.Context, projectID uuid.UUID, capsuleID string) error {
+	it, _, err := s.listInstances(ctx, capsuleID)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	ids := map[string]struct{}{}
+	for {
+		i, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		ids[i.GetInstanceId()] = struct{}{}
+	}
+
+	s.cl.forget(projectID.String(), capsuleID, ids)
+	return nil
+}
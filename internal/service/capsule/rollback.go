@@ -0,0 +1,168 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// rollback fails the rollout and creates a new rollout restoring the config
+// of the last successful rollout. A failing rollback is not rolled back again.
+func (j *rolloutJob) rollback(ctx context.Context, rc *capsule.RolloutConfig, rs *rollout.Status, cause error) error {
+	if _, ok := canaryStep(rc, rs); ok && rs.GetStatus().GetCanary() != nil {
+		if err := j.s.abortCanary(ctx, j.capsuleID, rs); err != nil {
+			return err
+		}
+	}
+
+	msg := fmt.Sprintf("rollout not healthy after %v: %s", rc.GetObserveDeadline().AsDuration(), errors.MessageOf(cause))
+
+	var rollbackID uint64
+	if rc.GetRollbackOf() == 0 {
+		prevID, prc, err := j.s.lastSuccessfulRollout(ctx, j.capsuleID, j.rolloutID)
+		if errors.IsNotFound(err) {
+		} else if err != nil {
+			return err
+		} else {
+			if rollbackID, err = j.s.createRollback(ctx, j.capsuleID, j.rolloutID, rc, prc); err != nil {
+				return err
+			}
+
+			ed := &capsule.EventData{
+				Kind: &capsule.EventData_Rollback{
+					Rollback: &capsule.RollbackEvent{
+						FailedRolloutId:   j.rolloutID,
+						RollbackRolloutId: rollbackID,
+					},
+				},
+			}
+			// The rollback rollout is created, so event errors must not cause
+			// the job to be retried.
+			if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, fmt.Sprintf("%s, rolling back to rollout %d in rollout %d", msg, prevID, rollbackID), ed); err != nil {
+				j.s.logger.Warn("error creating rollback event", zap.Error(err))
+			}
+			if err := j.s.CreateEvent(ctx, j.capsuleID, rollbackID, fmt.Sprintf("rolling back failed rollout %d to rollout %d", j.rolloutID, prevID), ed); err != nil {
+				j.s.logger.Warn("error creating rollback event", zap.Error(err))
+			}
+		}
+	}
+
+	if rollbackID == 0 {
+		if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, msg, &capsule.EventData{Kind: &capsule.EventData_Error{Error: &capsule.ErrorEvent{}}}); err != nil {
+			return err
+		}
+	}
+
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_FAILED
+	rs.Status.Message = msg
+	rs.Status.RollbackRolloutId = rollbackID
+	rs.ScheduledAt = nil
+	return nil
+}
+
+// lastSuccessfulRollout returns the newest rollout before the given one that
+// completed.
+func (s *Service) lastSuccessfulRollout(ctx context.Context, capsuleID string, rolloutID uint64) (uint64, *capsule.RolloutConfig, error) {
+	for id := rolloutID - 1; id > 0; id-- {
+		rc, rs, _, err := s.cr.GetRollout(ctx, capsuleID, id)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return 0, nil, err
+		}
+
+		if rs.GetStatus().GetState() == capsule.RolloutState_ROLLOUT_STATE_DONE {
+			return id, rc, nil
+		}
+	}
+
+	return 0, nil, errors.NotFoundErrorf("no successful rollout to roll back to")
+}
+
+func (s *Service) Rollback(ctx context.Context, capsuleID string, rolloutID uint64) (uint64, error) {
+	if _, err := s.ccg.GetCapsuleConfig(ctx, capsuleID); err != nil {
+		return 0, err
+	}
+
+	trc, trs, _, err := s.cr.GetRollout(ctx, capsuleID, rolloutID)
+	if err != nil {
+		return 0, err
+	}
+
+	if trs.GetStatus().GetState() != capsule.RolloutState_ROLLOUT_STATE_DONE {
+		return 0, errors.FailedPreconditionErrorf("can only roll back to a completed rollout")
+	}
+
+	// Rollouts waiting to start don't block the rollback, and are queued
+	// behind it.
+	currentID, crc, waiting, err := s.startedRollout(ctx, capsuleID)
+	if err != nil {
+		return 0, err
+	}
+
+	if currentID == rolloutID {
+		return 0, errors.FailedPreconditionErrorf("rollout %d is the current rollout", rolloutID)
+	}
+
+	// The build may have been deleted since.
+	if _, err := s.cr.GetBuild(ctx, capsuleID, trc.GetBuildId()); err != nil {
+		return 0, err
+	}
+
+	rc := proto.Clone(trc).(*capsule.RolloutConfig)
+	rc.Changes = []*capsule.Change{{
+		Field: &capsule.Change_Rollback{Rollback: rolloutID},
+	}}
+	rc.CreatedAt = timestamppb.Now()
+	rc.Strategy = crc.GetStrategy()
+	rc.ObserveDeadline = crc.GetObserveDeadline()
+	rc.RollbackOf = 0
+	if rc.CreatedBy, err = s.as.GetAuthor(ctx); err != nil {
+		return 0, err
+	}
+
+	carryCertificates(rc, crc)
+	if err := s.validateCertificates(ctx, rc); err != nil {
+		return 0, err
+	}
+
+	if err := s.queueWaitingRollouts(ctx, capsuleID, waiting); err != nil {
+		return 0, err
+	}
+
+	// Rolling back restores a known state, so it is not held by deploy windows.
+	return s.createRollout(ctx, capsuleID, rc, newRolloutStatus(rc, DeployOptions{OverrideDeployWindow: true}))
+}
+
+// createRollback creates a rollout restoring `prc`. The strategy and observe
+// deadline are capsule settings and are kept from the failed rollout.
+func (s *Service) createRollback(ctx context.Context, capsuleID string, failedID uint64, rc, prc *capsule.RolloutConfig) (uint64, error) {
+	rbc := proto.Clone(prc).(*capsule.RolloutConfig)
+	rbc.Changes = nil
+	rbc.CreatedAt = timestamppb.Now()
+	rbc.Strategy = rc.GetStrategy()
+	rbc.ObserveDeadline = rc.GetObserveDeadline()
+	rbc.RollbackOf = failedID
+	var err error
+	if rbc.CreatedBy, err = s.as.GetAuthor(ctx); err != nil {
+		return 0, err
+	}
+
+	return s.createRollout(ctx, capsuleID, rbc, newRolloutStatus(rbc, DeployOptions{OverrideDeployWindow: true}))
+}
+
+func observeDeadlineExceeded(rc *capsule.RolloutConfig, rs *rollout.Status) bool {
+	d := rc.GetObserveDeadline().AsDuration()
+	if d <= 0 || rs.GetObservingSince() == nil {
+		return false
+	}
+
+	return time.Since(rs.GetObservingSince().AsTime()) > d
+}
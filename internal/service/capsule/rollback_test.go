@@ -0,0 +1,68 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func rolloutStatus(state capsule.RolloutState) *rollout.Status {
+	return &rollout.Status{Status: &capsule.RolloutStatus{State: state}}
+}
+
+func Test_LastSuccessfulRollout(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(4)).Return(&capsule.RolloutConfig{BuildId: "b4"}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_FAILED), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(3)).Return(nil, nil, 0, errors.NotFoundErrorf("rollout not found"))
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(2)).Return(&capsule.RolloutConfig{BuildId: "b2"}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE), 1, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	id, rc, err := s.lastSuccessfulRollout(ctx, capsuleID, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), id)
+	require.Equal(t, "b2", rc.GetBuildId())
+}
+
+func Test_LastSuccessfulRollout_NotFound(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_ABORTED), 1, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	_, _, err := s.lastSuccessfulRollout(ctx, capsuleID, 2)
+	require.True(t, errors.IsNotFound(err))
+}
+
+func Test_ObserveDeadlineExceeded(t *testing.T) {
+	rc := &capsule.RolloutConfig{ObserveDeadline: durationpb.New(time.Minute)}
+
+	require.False(t, observeDeadlineExceeded(rc, &rollout.Status{}))
+	require.False(t, observeDeadlineExceeded(rc, &rollout.Status{ObservingSince: timestamppb.Now()}))
+	require.True(t, observeDeadlineExceeded(rc, &rollout.Status{ObservingSince: timestamppb.New(time.Now().Add(-2 * time.Minute))}))
+	require.False(t, observeDeadlineExceeded(&capsule.RolloutConfig{}, &rollout.Status{ObservingSince: timestamppb.New(time.Now().Add(-2 * time.Minute))}))
+}
@@ -37,48 +37,105 @@ func (s *Service) GetRollout(ctx context.Context, capsuleID string, rolloutID ui
 }
 
 func (s *Service) AbortRollout(ctx context.Context, capsuleID string, rolloutID uint64) error {
-	_, rs, version, err := s.cr.GetRollout(ctx, capsuleID, rolloutID)
+	rc, rs, version, err := s.cr.GetRollout(ctx, capsuleID, rolloutID)
 	if err != nil {
 		return err
 	}
 
+	if _, ok := canaryStep(rc, rs); ok && rs.GetStatus().GetCanary() != nil {
+		if err := s.abortCanary(ctx, capsuleID, rs); err != nil {
+			return err
+		}
+	}
+
+	msg := "rollout aborted"
+	switch rs.GetStatus().GetState() {
+	case capsule.RolloutState_ROLLOUT_STATE_PENDING, capsule.RolloutState_ROLLOUT_STATE_QUEUED:
+		msg = "rollout cancelled"
+	}
+
 	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_ABORTED
+	rs.Status.ScheduledAt = nil
+	rs.Status.QueuePosition = 0
 	rs.ScheduledAt = nil
 	if err := s.cr.UpdateRolloutStatus(ctx, capsuleID, rolloutID, version, rs); err != nil {
 		return err
 	}
 
-	return s.CreateEvent(ctx, capsuleID, rolloutID, "rollout aborted", &capsule.EventData{Kind: &capsule.EventData_Abort{}})
+	return s.CreateEvent(ctx, capsuleID, rolloutID, msg, &capsule.EventData{Kind: &capsule.EventData_Abort{}})
 }
 
-func (s *Service) newRollout(ctx context.Context, capsuleID string, cs []*capsule.Change) (uint64, error) {
-	if _, err := s.ccg.GetCapsuleConfig(ctx, capsuleID); err != nil {
+func (s *Service) newRollout(ctx context.Context, capsuleID string, cs []*capsule.Change, opts DeployOptions) (uint64, error) {
+	if opts.Queue {
+		if rolloutID, queued, err := s.queueRollout(ctx, capsuleID, cs, opts); err != nil || queued {
+			return rolloutID, err
+		}
+	}
+
+	rc, waiting, err := s.resolveRolloutConfig(ctx, capsuleID, cs)
+	if err != nil {
+		return 0, err
+	}
+
+	// Rollouts waiting for a later time are queued behind a rollout starting
+	// now, but a rollout scheduled for later would hold them back.
+	if len(waiting) > 0 && opts.ScheduleAt.After(time.Now()) {
+		return 0, errors.FailedPreconditionErrorf("rollout %d is already waiting to start", waiting[0].id)
+	}
+
+	if err := s.queueWaitingRollouts(ctx, capsuleID, waiting); err != nil {
 		return 0, err
 	}
 
+	return s.createRollout(ctx, capsuleID, rc, newRolloutStatus(rc, opts))
+}
+
+// resolveRolloutConfig returns the config of a new rollout, applying the
+// changes to the config of the newest rollout that has started. The rollouts
+// waiting to start are returned as well, as they must be queued behind the new
+// rollout.
+func (s *Service) resolveRolloutConfig(ctx context.Context, capsuleID string, cs []*capsule.Change) (*capsule.RolloutConfig, []waitingRollout, error) {
+	if _, err := s.ccg.GetCapsuleConfig(ctx, capsuleID); err != nil {
+		return nil, nil, err
+	}
+
 	rc := &capsule.RolloutConfig{
 		Replicas: 1,
 	}
 
-	if _, pRC, ps, _, err := s.cr.GetCurrentRollout(ctx, capsuleID); errors.IsNotFound(err) {
+	_, pRC, waiting, err := s.startedRollout(ctx, capsuleID)
+	if errors.IsNotFound(err) {
 	} else if err != nil {
-		return 0, err
+		return nil, nil, err
 	} else {
-		if !isRolloutTerminated(ps) {
-			return 0, errors.FailedPreconditionErrorf("rollout already in progress")
-		}
-
 		rc = pRC
 	}
 
-	now := time.Now()
-	rc.Changes = cs
-	rc.CreatedAt = timestamppb.New(now)
+	if err := s.changeRolloutConfig(ctx, capsuleID, rc, cs); err != nil {
+		return nil, nil, err
+	}
+
+	return rc, waiting, nil
+}
+
+// changeRolloutConfig turns the config of a previous rollout into the config
+// of a new rollout with the changes.
+func (s *Service) changeRolloutConfig(ctx context.Context, capsuleID string, rc *capsule.RolloutConfig, cs []*capsule.Change) error {
 	var err error
+	rc.Changes = cs
+	rc.RollbackOf = 0
+	rc.CreatedAt = timestamppb.Now()
 	if rc.CreatedBy, err = s.as.GetAuthor(ctx); err != nil {
-		return 0, err
+		return err
 	}
 
+	return s.applyChanges(ctx, capsuleID, rc, cs)
+}
+
+// applyChanges applies the changes to the rollout config and validates the
+// resulting build.
+func (s *Service) applyChanges(ctx context.Context, capsuleID string, rc *capsule.RolloutConfig, cs []*capsule.Change) error {
+	now := time.Now()
 	for _, c := range cs {
 		switch v := c.GetField().(type) {
 		case *capsule.Change_Replicas:
@@ -86,17 +143,19 @@ func (s *Service) newRollout(ctx context.Context, capsuleID string, cs []*capsul
 		case *capsule.Change_BuildId:
 			rc.BuildId = v.BuildId
 		case *capsule.Change_Network:
-			rc.Network = v.Network
+			if err := s.setNetwork(rc, v.Network); err != nil {
+				return err
+			}
 		case *capsule.Change_ContainerSettings:
 			rc.ContainerSettings = v.ContainerSettings
 		case *capsule.Change_SetConfigFile:
 			if err := utils.ValiateConfigFilePath(v.SetConfigFile.GetPath()); err != nil {
-				return 0, err
+				return err
 			}
 
 			author, err := s.as.GetAuthor(ctx)
 			if err != nil {
-				return 0, err
+				return err
 			}
 
 			cfg := &capsule.ConfigFile{
@@ -128,47 +187,153 @@ func (s *Service) newRollout(ctx context.Context, capsuleID string, cs []*capsul
 			}
 		case *capsule.Change_AutoAddRigServiceAccounts:
 			rc.AutoAddRigServiceAccounts = v.AutoAddRigServiceAccounts
+		case *capsule.Change_Strategy:
+			if err := validateStrategy(v.Strategy); err != nil {
+				return err
+			}
+
+			rc.Strategy = v.Strategy
+		case *capsule.Change_PreDeployHook:
+			if rc.Hooks == nil {
+				rc.Hooks = &capsule.RolloutHooks{}
+			}
+			rc.Hooks.PreDeploy = hookOrNil(v.PreDeployHook)
+		case *capsule.Change_PostDeployHook:
+			if rc.Hooks == nil {
+				rc.Hooks = &capsule.RolloutHooks{}
+			}
+			rc.Hooks.PostDeploy = hookOrNil(v.PostDeployHook)
+		case *capsule.Change_Autoscaler:
+			if v.Autoscaler.GetMaxReplicas() == 0 {
+				rc.Autoscaler = nil
+				break
+			}
+
+			if err := validateAutoscaler(v.Autoscaler); err != nil {
+				return err
+			}
+
+			rc.Autoscaler = v.Autoscaler
+		case *capsule.Change_CronJob:
+			if v.CronJob.GetSchedule() == "" {
+				rc.CronJob = nil
+				break
+			}
+
+			if err := validateCronJob(v.CronJob); err != nil {
+				return err
+			}
+
+			rc.CronJob = v.CronJob
+		case *capsule.Change_SetVolume:
+			if err := setVolume(rc, v.SetVolume); err != nil {
+				return err
+			}
+		case *capsule.Change_RemoveVolume:
+			if err := removeVolume(rc, v.RemoveVolume); err != nil {
+				return err
+			}
+		case *capsule.Change_ObserveDeadline:
+			if v.ObserveDeadline.AsDuration() < 0 {
+				return errors.InvalidArgumentErrorf("observe deadline must not be negative")
+			}
+
+			rc.ObserveDeadline = v.ObserveDeadline
 		default:
-			return 0, errors.InvalidArgumentErrorf("unhandled change field '%v'", reflect.TypeOf(v))
+			return errors.InvalidArgumentErrorf("unhandled change field '%v'", reflect.TypeOf(v))
 		}
 	}
 
+	if err := validateWorkload(rc); err != nil {
+		return err
+	}
+
 	// Validate the build exists.
 	if _, err := s.cr.GetBuild(ctx, capsuleID, rc.GetBuildId()); err != nil {
-		return 0, err
+		return err
 	}
 
+	return nil
+}
+
+// newRolloutStatus returns the status of a new pending rollout.
+func newRolloutStatus(rc *capsule.RolloutConfig, opts DeployOptions) *rollout.Status {
+	now := rc.GetCreatedAt().AsTime()
 	rs := &rollout.Status{
 		Status: &capsule.RolloutStatus{
 			State:     capsule.RolloutState_ROLLOUT_STATE_PENDING,
 			UpdatedAt: timestamppb.New(now),
 		},
-		ScheduledAt: timestamppb.New(now),
+		ScheduledAt:          timestamppb.New(now),
+		OverrideDeployWindow: opts.OverrideDeployWindow,
+	}
+
+	if opts.ScheduleAt.After(now) {
+		rs.ScheduledAt = timestamppb.New(opts.ScheduleAt)
+		rs.Status.ScheduledAt = rs.ScheduledAt
+		rs.Status.Message = fmt.Sprintf("rollout scheduled for %v", opts.ScheduleAt.UTC().Format(time.RFC3339))
+	}
+
+	return rs
+}
+
+// createRollout stores the rollout as a new rollout and queues it for
+// execution.
+func (s *Service) createRollout(ctx context.Context, capsuleID string, rc *capsule.RolloutConfig, rs *rollout.Status) (uint64, error) {
+	certIDs, err := s.storeCertificates(ctx, rc)
+	if err != nil {
+		return 0, err
 	}
 
 	rolloutID, err := s.cr.CreateRollout(ctx, capsuleID, rc, rs)
 	if err != nil {
+		s.deleteCertificates(ctx, certIDs)
 		return 0, err
 	}
 
-	if err := s.queueRolloutJob(ctx, capsuleID, rolloutID, now); err != nil {
+	if err := s.queueRolloutJob(ctx, capsuleID, rolloutID, rs.GetScheduledAt().AsTime(), ""); err != nil {
 		return 0, err
 	}
 
 	return rolloutID, nil
 }
 
-func (s *Service) queueRolloutJob(ctx context.Context, capsuleID string, rolloutID uint64, ts time.Time) error {
+// rolloutLeaseTTL is how long a rollout job can be unresponsive before
+// another job, possibly on another server, takes over the rollout. A running
+// job renews its lease three times per TTL.
+var rolloutLeaseTTL = 30 * time.Second
+
+// queueRolloutJob queues a job for the rollout at the given time. An empty
+// lease ID starts a new job, which only is queued if no other job holds the
+// lease of the rollout.
+func (s *Service) queueRolloutJob(ctx context.Context, capsuleID string, rolloutID uint64, ts time.Time, leaseID string) error {
 	projectID, err := auth.GetProjectID(ctx)
 	if err != nil {
 		return err
 	}
 
+	if leaseID == "" {
+		leaseID = uuid.New().String()
+	}
+
+	// The lease covers the wait for the job to run.
+	expiresAt := time.Now()
+	if ts.After(expiresAt) {
+		expiresAt = ts
+	}
+	if err := s.cr.LeaseRollout(ctx, capsuleID, rolloutID, leaseID, expiresAt.Add(rolloutLeaseTTL)); errors.IsFailedPrecondition(err) {
+		s.logger.Info("rollout leased by another job", zap.String("capsule_id", capsuleID), zap.Uint64("rollout_id", rolloutID))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
 	s.q.AddJob(&rolloutJob{
 		s:         s,
 		projectID: projectID,
 		capsuleID: capsuleID,
 		rolloutID: rolloutID,
+		leaseID:   leaseID,
 	}, ts)
 	s.logger.Info("scheduled rollout job", zap.Time("scheduled_at", ts), zap.String("capsule_id", capsuleID), zap.Uint64("rollout_id", rolloutID))
 
@@ -182,15 +347,8 @@ type Job interface {
 func (s *Service) run() {
 	ctx := context.Background()
 
-	for {
-		// TODO: Move to rescheduling job (5s on error, 10m on success).
-		if err := s.initJobs(ctx); err != nil {
-			s.logger.Warn("failed to initialize jobs from repository", zap.Error(err))
-			time.Sleep(5 * time.Second)
-		} else {
-			break
-		}
-	}
+	s.q.AddJob(&syncJob{s: s}, time.Now())
+	s.q.AddJob(&instanceWatchJob{s: s}, time.Now())
 
 	const maxJobs = 10
 	sem := semaphore.NewWeighted(maxJobs)
@@ -215,8 +373,25 @@ func (s *Service) run() {
 	}
 }
 
+// syncJob queues jobs for the active rollouts not run by any job, either
+// because the server just started or because the server running the job
+// died.
+type syncJob struct {
+	s *Service
+}
+
+func (j *syncJob) Run(ctx context.Context) error {
+	if err := j.s.initJobs(ctx); err != nil {
+		j.s.q.AddJob(j, time.Now().Add(5*time.Second))
+		return err
+	}
+
+	j.s.q.AddJob(j, time.Now().Add(rolloutLeaseTTL/2))
+	return nil
+}
+
 func (s *Service) initJobs(ctx context.Context) error {
-	s.logger.Info("loading active rollouts from repository")
+	s.logger.Debug("loading active rollouts from repository")
 
 	it, err := s.cr.ActiveRollouts(ctx, &model.Pagination{})
 	if err != nil {
@@ -231,8 +406,12 @@ func (s *Service) initJobs(ctx context.Context) error {
 			return err
 		}
 
+		if ar.LeaseExpiresAt.After(time.Now()) {
+			continue
+		}
+
 		ctx := auth.WithProjectID(ctx, ar.ProjectID)
-		if err := s.queueRolloutJob(ctx, ar.CapsuleID, ar.RolloutID, ar.ScheduledAt); err != nil {
+		if err := s.queueRolloutJob(ctx, ar.CapsuleID, ar.RolloutID, ar.ScheduledAt, ""); err != nil {
 			return err
 		}
 	}
@@ -249,6 +428,7 @@ type rolloutJob struct {
 	projectID uuid.UUID
 	capsuleID string
 	rolloutID uint64
+	leaseID   string
 }
 
 func (j *rolloutJob) Run(ctx context.Context) error {
@@ -262,6 +442,13 @@ func (j *rolloutJob) Run(ctx context.Context) error {
 
 	logger.Info("running rollout job")
 
+	if err := j.s.cr.LeaseRollout(ctx, j.capsuleID, j.rolloutID, j.leaseID, time.Now().Add(rolloutLeaseTTL)); errors.IsFailedPrecondition(err) {
+		logger.Info("rollout taken over by another job")
+		return nil
+	} else if err != nil {
+		return err
+	}
+
 	c, err := j.s.ccg.GetCapsuleConfig(ctx, j.capsuleID)
 	if err != nil {
 		return err
@@ -274,7 +461,18 @@ func (j *rolloutJob) Run(ctx context.Context) error {
 
 	rs := proto.Clone(oldRS).(*rollout.Status)
 
-	err = j.run(ctx, c, rc, rs, version, logger)
+	// A step can outlive the lease, e.g. while pulling an image, so the lease
+	// is renewed while it runs. If the lease is lost anyway, the step is
+	// cancelled and its outcome is left to the job holding the lease.
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stop := j.keepLease(runCtx, cancel, logger)
+	err = j.run(runCtx, c, rc, rs, version, logger)
+	if !stop() {
+		logger.Info("rollout taken over by another job")
+		return nil
+	}
+
 	if err != nil {
 		rs.Status.Message = errors.MessageOf(err)
 	}
@@ -290,17 +488,23 @@ func (j *rolloutJob) Run(ctx context.Context) error {
 			rs.ScheduledAt = nil
 		}
 
+		updated := false
 		if err == nil {
 			err = j.updateContinue(ctx, rc, rs, version, logger)
+			updated = err == nil
 		}
 
 		if err != nil {
-			j.updateError(ctx, rc, rs, version, err, logger)
+			updated = j.updateError(ctx, rc, rs, version, err, logger)
+		}
+
+		if updated && rs.GetStatus().GetState() != oldRS.GetStatus().GetState() {
+			j.stateChanged(ctx, rs, logger)
 		}
 	}
 
 	if rs.GetScheduledAt() != nil {
-		if err := j.s.queueRolloutJob(ctx, j.capsuleID, j.rolloutID, rs.GetScheduledAt().AsTime()); err != nil {
+		if err := j.s.queueRolloutJob(ctx, j.capsuleID, j.rolloutID, rs.GetScheduledAt().AsTime(), j.leaseID); err != nil {
 			return err
 		}
 	}
@@ -308,29 +512,80 @@ func (j *rolloutJob) Run(ctx context.Context) error {
 	return err
 }
 
+// keepLease renews the lease of the job until the returned function is called,
+// which returns false if the lease was lost to another job. A lost lease
+// cancels the context of the job.
+func (j *rolloutJob) keepLease(ctx context.Context, cancel context.CancelFunc, logger *zap.Logger) func() bool {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	lost := false
+	go func() {
+		defer close(stopped)
+
+		t := time.NewTicker(rolloutLeaseTTL / 3)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-t.C:
+			}
+
+			err := j.s.cr.LeaseRollout(ctx, j.capsuleID, j.rolloutID, j.leaseID, time.Now().Add(rolloutLeaseTTL))
+			if errors.IsFailedPrecondition(err) || errors.IsNotFound(err) {
+				lost = true
+				cancel()
+				return
+			} else if err != nil {
+				// The lease is kept until it expires, so the renewal is
+				// just tried again.
+				logger.Warn("error renewing rollout lease", zap.Error(err))
+			}
+		}
+	}()
+
+	return func() bool {
+		close(done)
+		<-stopped
+		return !lost
+	}
+}
+
 func (j *rolloutJob) updateContinue(ctx context.Context, rc *capsule.RolloutConfig, rs *rollout.Status, version uint64, logger *zap.Logger) error {
 	if isRolloutTerminated(rs) {
 		rs.ScheduledAt = nil
-	} else {
-		rs.ScheduledAt = timestamppb.Now()
+	} else if now := time.Now(); rs.GetScheduledAt().AsTime().Before(now) {
+		// Continue right away, unless the job was postponed.
+		rs.ScheduledAt = timestamppb.New(now)
 	}
-	if err := j.s.cr.UpdateRolloutStatus(ctx, j.capsuleID, j.rolloutID, version, rs); err != nil {
+	// The config is resolved when a queued rollout leaves the queue.
+	if err := j.s.cr.UpdateRollout(ctx, j.capsuleID, j.rolloutID, version, rc, rs); err != nil {
 		return err
 	}
 
+	j.s.notifyRollout(ctx, j.capsuleID, j.rolloutID)
 	return nil
 }
 
-func (j *rolloutJob) updateError(ctx context.Context, rc *capsule.RolloutConfig, rs *rollout.Status, version uint64, err error, logger *zap.Logger) {
+// updateError stores the error of the rollout, and returns true if the status
+// was updated.
+func (j *rolloutJob) updateError(ctx context.Context, rc *capsule.RolloutConfig, rs *rollout.Status, version uint64, err error, logger *zap.Logger) bool {
 	rs.ScheduledAt = timestamppb.New(time.Now().Add(3 * time.Second))
 	rs.Status.Message = errors.MessageOf(err)
+	updated := true
 	if err := j.s.cr.UpdateRolloutStatus(ctx, j.capsuleID, j.rolloutID, version, rs); err != nil {
 		logger.Info("error updating rollback on error", zap.Error(err))
+		updated = false
 	}
 
 	if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, errors.MessageOf(err), &capsule.EventData{Kind: &capsule.EventData_Error{Error: &capsule.ErrorEvent{}}}); err != nil {
 		logger.Info("error creating error event", zap.Error(err))
 	}
+
+	return updated
 }
 
 func (j *rolloutJob) run(
@@ -342,8 +597,18 @@ func (j *rolloutJob) run(
 	logger *zap.Logger,
 ) error {
 	switch rs.GetStatus().GetState() {
+	case capsule.RolloutState_ROLLOUT_STATE_QUEUED:
+		return j.dequeue(ctx, rc, rs)
+
 	case capsule.RolloutState_ROLLOUT_STATE_PENDING:
-		if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, "new rollout initiated", &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
+		if !rs.GetOverrideDeployWindow() {
+			if held, err := j.holdForDeployWindow(ctx, rs); err != nil || held {
+				return err
+			}
+		}
+
+		rs.Status.ScheduledAt = nil
+		if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, "new rollout initiated", &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{State: capsule.RolloutState_ROLLOUT_STATE_PREPARING}}}); err != nil {
 			return err
 		}
 
@@ -451,17 +716,24 @@ func (j *rolloutJob) run(
 			}
 
 			// Unused file, remove.
-			if err := j.s.ccg.DeleteFile(ctx, j.capsuleID, "file-"+strings.ReplaceAll(f.Path, "/", "-"), j.projectID.String()); errors.IsNotFound(err) {
+			if err := j.s.ccg.DeleteFile(ctx, j.capsuleID, configFileName(f.Path), j.projectID.String()); errors.IsNotFound(err) {
 			} else if err != nil {
 				return err
 			}
 		}
 
+		if ok, err := j.preDeploy(ctx, cfg, rc, rs); err != nil || !ok {
+			return err
+		}
+
 		rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_DEPLOYING
 		rs.Status.Message = "deploying rollout to cluster"
 		return nil
 
 	case capsule.RolloutState_ROLLOUT_STATE_DEPLOYING:
+		stable := cfg.DeepCopy()
+		rs.ObservingSince = nil
+
 		b, err := j.s.cr.GetBuild(ctx, j.capsuleID, rc.GetBuildId())
 		if errors.IsNotFound(err) {
 			return errors.AbortedErrorf("build not available")
@@ -469,140 +741,37 @@ func (j *rolloutJob) run(
 			return err
 		}
 
-		cfg.Spec.Image = rc.GetBuildId()
-		cfg.Spec.Command = rc.GetContainerSettings().GetCommand()
-		cfg.Spec.Args = rc.GetContainerSettings().GetArgs()
-		cfg.Spec.Replicas = int32(rc.GetReplicas())
-
-	addNext:
-		for _, cf := range rc.GetConfigFiles() {
-			for _, f := range cfg.Spec.Files {
-				if cf.GetPath() == f.Path {
-					continue addNext
-				}
-			}
-
-			// Missing file, add.
-			cm := &v1.ConfigMap{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      "file-" + strings.ReplaceAll(cf.GetPath(), "/", "-"),
-					Namespace: j.projectID.String(),
-				},
-				BinaryData: map[string][]byte{
-					"content": cf.GetContent(),
-				},
-			}
+		for _, cm := range configFileMaps(j.projectID, rc) {
 			if err := j.s.ccg.SetFile(ctx, j.capsuleID, cm); err != nil {
 				return err
 			}
-
-			cfg.Spec.Files = append(cfg.Spec.Files, v1alpha1.File{
-				Path: cf.GetPath(),
-				ConfigMap: &v1alpha1.FileContentRef{
-					Name: cm.Name,
-					Key:  "content",
-				},
-			})
 		}
 
-		for _, i := range rc.GetNetwork().GetInterfaces() {
-			capIf := v1alpha1.CapsuleInterface{
-				Name: i.GetName(),
-				Port: int32(i.GetPort()),
-			}
-			if i.GetPublic().GetEnabled() {
-				switch v := i.GetPublic().GetMethod().GetKind().(type) {
-				case *capsule.RoutingMethod_Ingress_:
-					capIf.Public = &v1alpha1.CapsulePublicInterface{
-						Ingress: &v1alpha1.CapsuleInterfaceIngress{
-							Host: v.Ingress.GetHost(),
-						},
-					}
-				case *capsule.RoutingMethod_LoadBalancer_:
-					capIf.Public = &v1alpha1.CapsulePublicInterface{
-						LoadBalancer: &v1alpha1.CapsuleInterfaceLoadBalancer{
-							Port: int32(v.LoadBalancer.GetPort()),
-						},
-					}
-				}
-			}
-			cfg.Spec.Interfaces = append(cfg.Spec.Interfaces, capIf)
-		}
-
-		ref, err := reference.ParseDockerRef(b.GetBuildId())
-		if err != nil {
-			return errors.InvalidArgumentErrorf("%v", err)
-		}
-
-		host := reference.Domain(ref)
-		pullSecretName := fmt.Sprintf("%s-pull", j.capsuleID)
-		if ds, err := j.s.ps.GetProjectDockerSecret(ctx, host); errors.IsNotFound(err) {
-			if err := j.s.ccg.DeleteSecret(ctx, j.capsuleID, pullSecretName, j.projectID.String()); errors.IsNotFound(err) {
-			} else if err != nil {
-				return err
-			}
+		applyRolloutConfig(cfg, rc)
 
-			cfg.Spec.ImagePullSecret = nil
-		} else if err != nil {
+		if err := j.setPullSecret(ctx, cfg, b.GetBuildId()); err != nil {
 			return err
-		} else {
-			bs, err := json.Marshal(map[string]interface{}{
-				"auths": map[string]interface{}{
-					host: map[string]interface{}{
-						"auth": base64.StdEncoding.EncodeToString(
-							[]byte(fmt.Sprint(
-								ds.GetUsername(),
-								":",
-								ds.GetPassword()),
-							),
-						),
-					},
-				},
-			})
-			if err != nil {
-				return err
-			}
-
-			ds := &v1.Secret{
-				ObjectMeta: metav1.ObjectMeta{
-					Name:      pullSecretName,
-					Namespace: j.projectID.String(),
-				},
-				Type: v1.SecretTypeDockerConfigJson,
-				Data: map[string][]byte{
-					".dockerconfigjson": bs,
-				},
-			}
-			if err := j.s.ccg.SetSecret(ctx, j.capsuleID, ds); err != nil {
-				return err
-			}
-
-			cfg.Spec.ImagePullSecret = &v1.LocalObjectReference{
-				Name: pullSecretName,
-			}
 		}
 
-		envs := rc.GetContainerSettings().GetEnvironmentVariables()
-		if envs == nil {
-			envs = map[string]string{}
+		if err := j.setCertificates(ctx, stable, rc); err != nil {
+			return err
 		}
-		envs["RIG_PROJECT_ID"] = j.projectID.String()
-		if rc.GetAutoAddRigServiceAccounts() {
-			sid := uuid.UUID(rs.GetRigServiceAccount().GetClientSecretKey())
 
-			secretKey, err := j.s.sr.Get(ctx, sid)
-			if err != nil {
-				return err
-			}
-
-			envs["RIG_CLIENT_ID"] = rs.GetRigServiceAccount().GetClientId()
-			envs["RIG_CLIENT_SECRET"] = string(secretKey)
+		envs, err := j.environmentVariables(ctx, rc, rs)
+		if err != nil {
+			return err
 		}
 
 		if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, "configuring cluster resources", &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
 			return err
 		}
 
+		// Only move a subset of the instances if the rollout is a canary
+		// replacing an existing build.
+		if step, ok := canaryStep(rc, rs); ok && rc.GetRollbackOf() == 0 && stable.Spec.Image != "" && stable.Spec.Image != rc.GetBuildId() {
+			return j.deployCanary(ctx, stable, cfg, envs, step, rc, rs)
+		}
+
 		// Upsert the capsule.
 		if err := j.s.ccg.SetEnvironmentVariables(ctx, cfg.Name, envs); err != nil {
 			return err
@@ -612,60 +781,196 @@ func (j *rolloutJob) run(
 			return err
 		}
 
+		if rs.GetStatus().GetCanary() != nil {
+			if err := j.s.ccg.DeleteCanary(ctx, j.capsuleID); err != nil {
+				return err
+			}
+		}
+
 		rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_OBSERVING
 		rs.Status.Message = "waiting for new instances"
 		return nil
 
 	case capsule.RolloutState_ROLLOUT_STATE_OBSERVING:
-		it, _, err := j.s.cg.ListInstances(ctx, cfg.GetName())
-		if err != nil {
+		if rs.GetObservingSince() == nil {
+			rs.ObservingSince = timestamppb.Now()
+		}
+
+		err := j.observe(ctx, cfg, rc, rs)
+		if errors.IsUnavailable(err) && observeDeadlineExceeded(rc, rs) {
+			return j.rollback(ctx, rc, rs, err)
+		}
+		return err
+
+		// Cleanup step, ensure we de-schedule terminated steps. Ideally, should not be needed.
+	case
+		capsule.RolloutState_ROLLOUT_STATE_ABORTED,
+		capsule.RolloutState_ROLLOUT_STATE_DONE,
+		capsule.RolloutState_ROLLOUT_STATE_FAILED:
+		rs.ScheduledAt = nil
+		return nil
+
+	default:
+		return errors.InvalidArgumentErrorf("invalid state %v", rs.GetStatus().GetState())
+	}
+}
+
+// observe checks the instances of the capsule, returning an Unavailable error
+// until they all run the build of the rollout.
+func (j *rolloutJob) observe(
+	ctx context.Context,
+	cfg *v1alpha1.Capsule,
+	rc *capsule.RolloutConfig,
+	rs *rollout.Status,
+) error {
+	if step, ok := canaryStep(rc, rs); ok && rs.GetStatus().GetCanary() != nil {
+		return j.observeCanary(ctx, cfg, step, rc, rs)
+	}
+
+	// The runs of a cron job are started by its schedule, so there are no
+	// instances to wait for.
+	if rc.GetCronJob() == nil {
+		if err := j.observeInstances(ctx, cfg, rc, rs); err != nil {
 			return err
 		}
+	}
 
-		c := 0
-		for {
-			i, err := it.Next()
-			if err == io.EOF {
-				break
-			} else if err != nil {
-				return err
-			}
+	if ok, err := j.postDeploy(ctx, cfg, rc, rs); err != nil || !ok {
+		return err
+	}
 
-			if i.GetBuildId() != rc.GetBuildId() {
-				return errors.UnavailableErrorf("instance '%s' is wrong build", i.GetInstanceId())
-			}
+	if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, "cluster resources created", &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
+		return err
+	}
 
-			if i.GetState() != capsule.State_STATE_RUNNING {
-				return errors.UnavailableErrorf("instance '%s' is running", i.GetInstanceId())
-			}
+	j.deleteUnusedCertificates(ctx, rc)
+
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_DONE
+	rs.Status.Message = "rollout done"
+	rs.ScheduledAt = nil
+	return nil
+}
+
+// observeInstances returns an Unavailable error until all instances of the
+// rollout are running and ready.
+func (j *rolloutJob) observeInstances(
+	ctx context.Context,
+	cfg *v1alpha1.Capsule,
+	rc *capsule.RolloutConfig,
+	rs *rollout.Status,
+) error {
+	it, _, err := j.s.listInstances(ctx, cfg.GetName())
+	if err != nil {
+		return err
+	}
 
-			c++
+	c := 0
+	for {
+		i, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
 		}
 
-		if c < int(rc.GetReplicas()) {
-			return errors.UnavailableErrorf("only %v instances running, should be '%v'", c, rc.GetReplicas())
+		if i.GetBuildId() != rc.GetBuildId() {
+			return errors.UnavailableErrorf("instance '%s' is wrong build", i.GetInstanceId())
 		}
 
-		if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, "cluster resources created", &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
+		if i.GetState() != capsule.State_STATE_RUNNING {
+			return errors.UnavailableErrorf("instance '%s' is not running", i.GetInstanceId())
+		}
+
+		if !i.GetReady() {
+			return errors.UnavailableErrorf("instance '%s' is not ready", i.GetInstanceId())
+		}
+
+		c++
+	}
+
+	if n := rolloutReplicas(rc); c < int(n) {
+		return errors.UnavailableErrorf("only %v instances running, should be '%v'", c, n)
+	}
+
+	return nil
+}
+
+// setPullSecret stores the credentials of the project for the registry of
+// the build, and sets the pull secret of cfg accordingly.
+func (j *rolloutJob) setPullSecret(ctx context.Context, cfg *v1alpha1.Capsule, buildID string) error {
+	ref, err := reference.ParseDockerRef(buildID)
+	if err != nil {
+		return errors.InvalidArgumentErrorf("%v", err)
+	}
+
+	host := reference.Domain(ref)
+	pullSecretName := fmt.Sprintf("%s-pull", j.capsuleID)
+	if ds, err := j.s.ps.GetProjectDockerSecret(ctx, host); errors.IsNotFound(err) {
+		if err := j.s.ccg.DeleteSecret(ctx, j.capsuleID, pullSecretName, j.projectID.String()); errors.IsNotFound(err) {
+		} else if err != nil {
 			return err
 		}
 
-		rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_DONE
-		rs.Status.Message = "rollout done"
-		rs.ScheduledAt = nil
-		return nil
+		cfg.Spec.ImagePullSecret = nil
+	} else if err != nil {
+		return err
+	} else {
+		bs, err := json.Marshal(map[string]interface{}{
+			"auths": map[string]interface{}{
+				host: map[string]interface{}{
+					"auth": base64.StdEncoding.EncodeToString(
+						[]byte(fmt.Sprint(
+							ds.GetUsername(),
+							":",
+							ds.GetPassword()),
+						),
+					),
+				},
+			},
+		})
+		if err != nil {
+			return err
+		}
 
-		// Cleanup step, ensure we de-schedule terminated steps. Ideally, should not be needed.
-	case
-		capsule.RolloutState_ROLLOUT_STATE_ABORTED,
-		capsule.RolloutState_ROLLOUT_STATE_DONE,
-		capsule.RolloutState_ROLLOUT_STATE_FAILED:
-		rs.ScheduledAt = nil
-		return nil
+		ds := &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      pullSecretName,
+				Namespace: j.projectID.String(),
+			},
+			Type: v1.SecretTypeDockerConfigJson,
+			Data: map[string][]byte{
+				".dockerconfigjson": bs,
+			},
+		}
+		if err := j.s.ccg.SetSecret(ctx, j.capsuleID, ds); err != nil {
+			return err
+		}
 
-	default:
-		return errors.InvalidArgumentErrorf("invalid state %v", rs.GetStatus().GetState())
+		cfg.Spec.ImagePullSecret = &v1.LocalObjectReference{
+			Name: pullSecretName,
+		}
 	}
+
+	return nil
+}
+
+// environmentVariables returns the environment variables of the rollout,
+// including the credentials of the rig service account.
+func (j *rolloutJob) environmentVariables(ctx context.Context, rc *capsule.RolloutConfig, rs *rollout.Status) (map[string]string, error) {
+	envs := rolloutEnvironmentVariables(j.projectID, rc)
+	if rc.GetAutoAddRigServiceAccounts() {
+		sid := uuid.UUID(rs.GetRigServiceAccount().GetClientSecretKey())
+
+		secretKey, err := j.s.sr.Get(ctx, sid)
+		if err != nil {
+			return nil, err
+		}
+
+		envs["RIG_CLIENT_ID"] = rs.GetRigServiceAccount().GetClientId()
+		envs["RIG_CLIENT_SECRET"] = string(secretKey)
+	}
+
+	return envs, nil
 }
 
 func isRolloutTerminated(r *rollout.Status) bool {
@@ -680,3 +985,136 @@ func isRolloutTerminated(r *rollout.Status) bool {
 		return false
 	}
 }
+
+// configFileMaps returns the ConfigMaps holding the config files of the
+// rollout.
+func configFileMaps(projectID uuid.UUID, rc *capsule.RolloutConfig) []*v1.ConfigMap {
+	var cms []*v1.ConfigMap
+	for _, cf := range rc.GetConfigFiles() {
+		cms = append(cms, &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configFileName(cf.GetPath()),
+				Namespace: projectID.String(),
+			},
+			BinaryData: map[string][]byte{
+				"content": cf.GetContent(),
+			},
+		})
+	}
+	return cms
+}
+
+func configFileName(path string) string {
+	return "file-" + strings.ReplaceAll(path, "/", "-")
+}
+
+// applyRolloutConfig updates the capsule config to run the rollout. The config
+// files are expected to be stored as returned by configFileMaps.
+func applyRolloutConfig(cfg *v1alpha1.Capsule, rc *capsule.RolloutConfig) {
+	cfg.Spec.Image = rc.GetBuildId()
+	cfg.Spec.Command = rc.GetContainerSettings().GetCommand()
+	cfg.Spec.Args = rc.GetContainerSettings().GetArgs()
+	cfg.Spec.Replicas = int32(rolloutReplicas(rc))
+	cfg.Spec.Autoscaler = capsuleAutoscaler(rc.GetAutoscaler())
+	cfg.Spec.CronJob = capsuleCronJob(rc.GetCronJob())
+	cfg.Spec.Volumes = capsuleVolumes(rc.GetVolumes())
+	cfg.Spec.Probes = capsuleProbes(rc.GetContainerSettings().GetProbes())
+
+	cfg.Spec.Files = nil
+	for _, cf := range rc.GetConfigFiles() {
+		cfg.Spec.Files = append(cfg.Spec.Files, v1alpha1.File{
+			Path: cf.GetPath(),
+			ConfigMap: &v1alpha1.FileContentRef{
+				Name: configFileName(cf.GetPath()),
+				Key:  "content",
+			},
+		})
+	}
+
+	cfg.Spec.Interfaces = nil
+	if rc.GetCronJob() != nil {
+		// The runs of a cron job do not serve traffic.
+		return
+	}
+
+	for _, i := range rc.GetNetwork().GetInterfaces() {
+		capIf := v1alpha1.CapsuleInterface{
+			Name: i.GetName(),
+			Port: int32(i.GetPort()),
+		}
+		if i.GetPublic().GetEnabled() {
+			switch v := i.GetPublic().GetMethod().GetKind().(type) {
+			case *capsule.RoutingMethod_Ingress_:
+				capIf.Public = &v1alpha1.CapsulePublicInterface{
+					Ingress: capsuleIngress(cfg.Name, i.GetName(), v.Ingress),
+				}
+			case *capsule.RoutingMethod_LoadBalancer_:
+				capIf.Public = &v1alpha1.CapsulePublicInterface{
+					LoadBalancer: &v1alpha1.CapsuleInterfaceLoadBalancer{
+						Port: int32(v.LoadBalancer.GetPort()),
+					},
+				}
+			}
+		}
+		cfg.Spec.Interfaces = append(cfg.Spec.Interfaces, capIf)
+	}
+}
+
+func capsuleProbes(ps *capsule.Probes) *v1alpha1.CapsuleProbes {
+	if ps == nil {
+		return nil
+	}
+
+	return &v1alpha1.CapsuleProbes{
+		Liveness:  capsuleProbe(ps.GetLiveness()),
+		Readiness: capsuleProbe(ps.GetReadiness()),
+		Startup:   capsuleProbe(ps.GetStartup()),
+	}
+}
+
+func capsuleProbe(p *capsule.Probe) *v1alpha1.CapsuleProbe {
+	if p == nil {
+		return nil
+	}
+
+	cp := &v1alpha1.CapsuleProbe{
+		InitialDelaySeconds: int32(p.GetInitialDelay().AsDuration().Seconds()),
+		PeriodSeconds:       int32(p.GetPeriod().AsDuration().Seconds()),
+		TimeoutSeconds:      int32(p.GetTimeout().AsDuration().Seconds()),
+		FailureThreshold:    int32(p.GetFailureThreshold()),
+	}
+
+	switch v := p.GetKind().(type) {
+	case *capsule.Probe_Http:
+		cp.HTTP = &v1alpha1.HTTPProbe{
+			Port: int32(v.Http.GetPort()),
+			Path: v.Http.GetPath(),
+		}
+	case *capsule.Probe_Tcp:
+		cp.TCP = &v1alpha1.TCPProbe{
+			Port: int32(v.Tcp.GetPort()),
+		}
+	case *capsule.Probe_Grpc:
+		cp.GRPC = &v1alpha1.GRPCProbe{
+			Port:    int32(v.Grpc.GetPort()),
+			Service: v.Grpc.GetService(),
+		}
+	case *capsule.Probe_Exec:
+		cp.Exec = &v1alpha1.ExecProbe{
+			Command: v.Exec.GetCommand(),
+		}
+	}
+
+	return cp
+}
+
+// rolloutEnvironmentVariables returns the environment variables of the
+// rollout, excluding the service-account credentials.
+func rolloutEnvironmentVariables(projectID uuid.UUID, rc *capsule.RolloutConfig) map[string]string {
+	envs := map[string]string{}
+	for k, v := range rc.GetContainerSettings().GetEnvironmentVariables() {
+		envs[k] = v
+	}
+	envs["RIG_PROJECT_ID"] = projectID.String()
+	return envs
+}
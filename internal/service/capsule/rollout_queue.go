@@ -0,0 +1,331 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// queueRollout queues the changes behind the rollout in progress. If a
+// rollout is already queued, the changes are squashed into it. Returns false
+// if no rollout is in progress.
+func (s *Service) queueRollout(ctx context.Context, capsuleID string, cs []*capsule.Change, opts DeployOptions) (uint64, bool, error) {
+	if _, err := s.ccg.GetCapsuleConfig(ctx, capsuleID); err != nil {
+		return 0, false, err
+	}
+
+	currentID, rc, crs, _, err := s.cr.GetCurrentRollout(ctx, capsuleID)
+	if errors.IsNotFound(err) {
+		return 0, false, nil
+	} else if err != nil {
+		return 0, false, err
+	}
+
+	if isRolloutTerminated(crs) {
+		return 0, false, nil
+	}
+
+	if queuedID, qrc, qrs, version, err := s.queuedRollout(ctx, capsuleID, currentID); errors.IsNotFound(err) {
+	} else if err != nil {
+		return 0, false, err
+	} else {
+		return queuedID, true, s.squashRollout(ctx, capsuleID, queuedID, qrc, qrs, version, cs, opts)
+	}
+
+	// The config is resolved again when the rollout leaves the queue, as the
+	// rollout in progress may be rolled back.
+	if err := s.changeRolloutConfig(ctx, capsuleID, rc, cs); err != nil {
+		return 0, false, err
+	}
+
+	msg := fmt.Sprintf("rollout queued behind rollout %d", currentID)
+	rs := newRolloutStatus(rc, opts)
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_QUEUED
+	rs.Status.Message = msg
+	// Later deploys are squashed, so there is never more than one rollout in
+	// the queue.
+	rs.Status.QueuePosition = 1
+
+	rolloutID, err := s.createRollout(ctx, capsuleID, rc, rs)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if err := s.CreateEvent(ctx, capsuleID, rolloutID, msg, &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
+		s.logger.Warn("error creating queue event", zap.Error(err))
+	}
+
+	return rolloutID, true, nil
+}
+
+// waitingRollout is a rollout that hasn't started yet.
+type waitingRollout struct {
+	id      uint64
+	rc      *capsule.RolloutConfig
+	rs      *rollout.Status
+	version uint64
+}
+
+// startedRollout returns the newest rollout that has started, looking past the
+// rollouts waiting to start, which are returned as well, newest first. A
+// rollout is waiting to start if it's queued, or pending until a later time
+// because it's scheduled or held by a deploy window. A pending rollout due to
+// start counts as started, so it isn't reordered behind newer rollouts.
+// Returns a FailedPrecondition error if the started rollout is still in
+// progress, and a NotFound error if no rollout has started.
+func (s *Service) startedRollout(ctx context.Context, capsuleID string) (uint64, *capsule.RolloutConfig, []waitingRollout, error) {
+	id, rc, rs, waiting, err := s.latestStartedRollout(ctx, capsuleID)
+	if err != nil {
+		return 0, nil, waiting, err
+	}
+
+	if !isRolloutTerminated(rs) {
+		return 0, nil, nil, errors.FailedPreconditionErrorf("rollout already in progress")
+	}
+
+	return id, rc, waiting, nil
+}
+
+// latestStartedRollout is like startedRollout, but also returns the started
+// rollout while it is still in progress.
+func (s *Service) latestStartedRollout(ctx context.Context, capsuleID string) (uint64, *capsule.RolloutConfig, *rollout.Status, []waitingRollout, error) {
+	currentID, rc, rs, version, err := s.cr.GetCurrentRollout(ctx, capsuleID)
+	if err != nil {
+		return 0, nil, nil, nil, err
+	}
+
+	var waiting []waitingRollout
+	for id := currentID; id > 0; id-- {
+		if id != currentID {
+			if rc, rs, version, err = s.cr.GetRollout(ctx, capsuleID, id); errors.IsNotFound(err) {
+				continue
+			} else if err != nil {
+				return 0, nil, nil, nil, err
+			}
+		}
+
+		if isRolloutWaiting(rs) {
+			waiting = append(waiting, waitingRollout{id: id, rc: rc, rs: rs, version: version})
+			continue
+		}
+
+		return id, rc, rs, waiting, nil
+	}
+
+	return 0, nil, nil, waiting, errors.NotFoundErrorf("rollout not found")
+}
+
+// isRolloutWaiting returns true if the rollout is queued, or pending until a
+// later time.
+func isRolloutWaiting(rs *rollout.Status) bool {
+	switch rs.GetStatus().GetState() {
+	case capsule.RolloutState_ROLLOUT_STATE_QUEUED:
+		return true
+	case capsule.RolloutState_ROLLOUT_STATE_PENDING:
+		return rs.GetStatus().GetScheduledAt().AsTime().After(time.Now())
+	default:
+		return false
+	}
+}
+
+// queueWaitingRollouts queues the rollouts waiting to start behind a new
+// rollout, so they don't block it. They keep their schedule, and their changes
+// are applied to the config of the new rollout once it's done. There is never
+// more than one rollout in the queue, so the changes of the newer rollouts are
+// squashed into the oldest.
+func (s *Service) queueWaitingRollouts(ctx context.Context, capsuleID string, waiting []waitingRollout) error {
+	if len(waiting) == 0 {
+		return nil
+	}
+
+	oldest := waiting[len(waiting)-1]
+	if oldest.rs.GetStatus().GetState() == capsule.RolloutState_ROLLOUT_STATE_PENDING {
+		msg := "rollout queued behind a new rollout"
+		oldest.rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_QUEUED
+		oldest.rs.Status.Message = msg
+		oldest.rs.Status.QueuePosition = 1
+		if err := s.cr.UpdateRolloutStatus(ctx, capsuleID, oldest.id, oldest.version, oldest.rs); err != nil {
+			return err
+		}
+
+		if err := s.CreateEvent(ctx, capsuleID, oldest.id, msg, &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
+			s.logger.Warn("error creating queue event", zap.Error(err))
+		}
+	}
+
+	for i := len(waiting) - 2; i >= 0; i-- {
+		w := waiting[i]
+		qrc, qrs, version, err := s.cr.GetRollout(ctx, capsuleID, oldest.id)
+		if err != nil {
+			return err
+		}
+
+		if err := s.squashRollout(ctx, capsuleID, oldest.id, qrc, qrs, version, w.rc.GetChanges(), DeployOptions{
+			ScheduleAt:           w.rs.GetStatus().GetScheduledAt().AsTime(),
+			OverrideDeployWindow: w.rs.GetOverrideDeployWindow(),
+		}); err != nil {
+			return err
+		}
+
+		if err := s.cancelRollout(ctx, capsuleID, w, fmt.Sprintf("rollout squashed into queued rollout %d", oldest.id)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cancelRollout cancels a rollout that hasn't started, as its changes are
+// carried by another rollout. It's not aborted by anyone, so no abort event is
+// created.
+func (s *Service) cancelRollout(ctx context.Context, capsuleID string, w waitingRollout, msg string) error {
+	w.rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_ABORTED
+	w.rs.Status.Message = msg
+	w.rs.Status.ScheduledAt = nil
+	w.rs.Status.QueuePosition = 0
+	w.rs.ScheduledAt = nil
+	if err := s.cr.UpdateRolloutStatus(ctx, capsuleID, w.id, w.version, w.rs); err != nil {
+		return err
+	}
+
+	return s.CreateEvent(ctx, capsuleID, w.id, msg, &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}})
+}
+
+// queuedRollout returns the queued rollout of the capsule, looking through
+// the rollouts in progress from the given one and down.
+func (s *Service) queuedRollout(ctx context.Context, capsuleID string, rolloutID uint64) (uint64, *capsule.RolloutConfig, *rollout.Status, uint64, error) {
+	for id := rolloutID; id > 0; id-- {
+		rc, rs, version, err := s.cr.GetRollout(ctx, capsuleID, id)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return 0, nil, nil, 0, err
+		}
+
+		if rs.GetStatus().GetState() == capsule.RolloutState_ROLLOUT_STATE_QUEUED {
+			return id, rc, rs, version, nil
+		}
+
+		if isRolloutTerminated(rs) {
+			break
+		}
+	}
+
+	return 0, nil, nil, 0, errors.NotFoundErrorf("no queued rollout")
+}
+
+// squashRollout adds the changes to the queued rollout.
+func (s *Service) squashRollout(
+	ctx context.Context,
+	capsuleID string,
+	rolloutID uint64,
+	rc *capsule.RolloutConfig,
+	rs *rollout.Status,
+	version uint64,
+	cs []*capsule.Change,
+	opts DeployOptions,
+) error {
+	if err := s.applyChanges(ctx, capsuleID, rc, cs); err != nil {
+		return err
+	}
+
+	rc.Changes = append(rc.GetChanges(), cs...)
+	certIDs, err := s.storeCertificates(ctx, rc)
+	if err != nil {
+		return err
+	}
+
+	rs.OverrideDeployWindow = rs.GetOverrideDeployWindow() || opts.OverrideDeployWindow
+	if opts.ScheduleAt.After(time.Now()) && opts.ScheduleAt.After(rs.GetStatus().GetScheduledAt().AsTime()) {
+		rs.Status.ScheduledAt = timestamppb.New(opts.ScheduleAt)
+	}
+
+	if err := s.cr.UpdateRollout(ctx, capsuleID, rolloutID, version, rc, rs); err != nil {
+		s.deleteCertificates(ctx, certIDs)
+		return err
+	}
+
+	if err := s.CreateEvent(ctx, capsuleID, rolloutID, fmt.Sprintf("squashed %d changes into queued rollout", len(cs)), &capsule.EventData{Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{}}}); err != nil {
+		s.logger.Warn("error creating queue event", zap.Error(err))
+	}
+
+	return nil
+}
+
+// dequeue moves a queued rollout to PENDING once the rollouts ahead of it are
+// terminated. The queued changes are applied to the config of the newest of
+// them, which is a rollback if the rollout it was queued behind failed.
+func (j *rolloutJob) dequeue(ctx context.Context, rc *capsule.RolloutConfig, rs *rollout.Status) error {
+	currentID, _, _, _, err := j.s.cr.GetCurrentRollout(ctx, j.capsuleID)
+	if err != nil {
+		return err
+	}
+
+	base := &capsule.RolloutConfig{
+		Replicas: 1,
+	}
+	for id := currentID; id > 0; id-- {
+		if id == j.rolloutID {
+			continue
+		}
+
+		prc, prs, _, err := j.s.cr.GetRollout(ctx, j.capsuleID, id)
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if !isRolloutTerminated(prs) {
+			// Keep waiting.
+			return nil
+		}
+
+		base = prc
+		break
+	}
+
+	base.Changes = rc.GetChanges()
+	base.RollbackOf = 0
+	base.CreatedAt = rc.GetCreatedAt()
+	base.CreatedBy = rc.GetCreatedBy()
+	if err := j.s.applyChanges(ctx, j.capsuleID, base, rc.GetChanges()); errors.IsNotFound(err) {
+		// The build was deleted while queued.
+		return errors.InvalidArgumentErrorf("%v", errors.MessageOf(err))
+	} else if err != nil {
+		return err
+	}
+
+	// Keep the authors of the config files set when the changes were queued.
+	for _, c := range rc.GetChanges() {
+		path := c.GetSetConfigFile().GetPath()
+		for _, cf := range base.GetConfigFiles() {
+			for _, qcf := range rc.GetConfigFiles() {
+				if path != "" && cf.GetPath() == path && qcf.GetPath() == path {
+					cf.UpdatedAt = qcf.GetUpdatedAt()
+					cf.UpdatedBy = qcf.GetUpdatedBy()
+				}
+			}
+		}
+	}
+
+	proto.Reset(rc)
+	proto.Merge(rc, base)
+
+	rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_PENDING
+	rs.Status.QueuePosition = 0
+	rs.Status.Message = "rollout left the queue"
+	if ts := rs.GetStatus().GetScheduledAt(); ts.AsTime().After(time.Now()) {
+		rs.ScheduledAt = ts
+		rs.Status.Message = fmt.Sprintf("rollout scheduled for %v", ts.AsTime().UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/internal/repository"
+	service_auth "github.com/rigdev/rig/internal/service/auth"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_QueuedRollout(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	// A rollback (5) of a failed rollout (3) started after rollout 4 was queued.
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(5)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DEPLOYING), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(4)).Return(&capsule.RolloutConfig{BuildId: "b4"}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_QUEUED), 2, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	id, rc, _, version, err := s.queuedRollout(ctx, capsuleID, 5)
+	require.NoError(t, err)
+	require.Equal(t, uint64(4), id)
+	require.Equal(t, "b4", rc.GetBuildId())
+	require.Equal(t, uint64(2), version)
+}
+
+func Test_QueuedRollout_NotFound(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(3)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_OBSERVING), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(2)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE), 1, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	_, _, _, _, err := s.queuedRollout(ctx, capsuleID, 3)
+	require.True(t, errors.IsNotFound(err))
+}
+
+func Test_StartedRollout(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	// Rollout 5 is held by a deploy window, and rollout 4 is queued behind it.
+	held := rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_PENDING)
+	held.Status.ScheduledAt = timestamppb.New(time.Now().Add(time.Hour))
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetCurrentRollout(mock.Anything, capsuleID).Return(5, &capsule.RolloutConfig{BuildId: "b5"}, held, 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(4)).Return(&capsule.RolloutConfig{BuildId: "b4"}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_QUEUED), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(3)).Return(&capsule.RolloutConfig{BuildId: "b3"}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE), 1, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	id, rc, waiting, err := s.startedRollout(ctx, capsuleID)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), id)
+	require.Equal(t, "b3", rc.GetBuildId())
+	require.Len(t, waiting, 2)
+	require.Equal(t, uint64(5), waiting[0].id)
+	require.Equal(t, uint64(4), waiting[1].id)
+}
+
+func Test_StartedRollout_PendingDue(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	// Rollout 2 is pending, but its job hasn't started it yet.
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetCurrentRollout(mock.Anything, capsuleID).Return(2, &capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_PENDING), 1, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	_, _, _, err := s.startedRollout(ctx, capsuleID)
+	require.True(t, errors.IsFailedPrecondition(err))
+}
+
+func Test_StartedRollout_InProgress(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetCurrentRollout(mock.Anything, capsuleID).Return(3, &capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_QUEUED), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(2)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DEPLOYING), 1, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	_, _, _, err := s.startedRollout(ctx, capsuleID)
+	require.True(t, errors.IsFailedPrecondition(err))
+}
+
+func Test_QueueWaitingRollouts(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().UpdateRolloutStatus(mock.Anything, capsuleID, uint64(4), uint64(2), mock.Anything).RunAndReturn(func(_ context.Context, _ string, _ uint64, _ uint64, rs *rollout.Status) error {
+		require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_QUEUED, rs.GetStatus().GetState())
+		require.Equal(t, uint32(1), rs.GetStatus().GetQueuePosition())
+		return nil
+	})
+	cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).Return(nil)
+
+	s := &Service{
+		cr:     cr,
+		as:     &service_auth.Service{},
+		rw:     newRolloutWatchers(),
+		logger: zaptest.NewLogger(t),
+	}
+
+	// The held rollout starts once the new rollout is done.
+	require.NoError(t, s.queueWaitingRollouts(ctx, capsuleID, []waitingRollout{{
+		id:      4,
+		rc:      &capsule.RolloutConfig{},
+		rs:      rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_PENDING),
+		version: 2,
+	}}))
+}
+
+func Test_NewRollout_BackToBack(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().GetCapsuleConfig(mock.Anything, capsuleID).Return(&v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}, nil)
+
+	cr := repository.NewMockCapsule(t)
+	rollouts := newTestRollouts(cr, capsuleID)
+
+	s := &Service{
+		cr:     cr,
+		ccg:    ccg,
+		as:     &service_auth.Service{},
+		q:      NewQueue[Job](),
+		rw:     newRolloutWatchers(),
+		logger: zaptest.NewLogger(t),
+	}
+
+	build := func(id string) []*capsule.Change {
+		return []*capsule.Change{{Field: &capsule.Change_BuildId{BuildId: id}}}
+	}
+
+	first, err := s.newRollout(ctx, capsuleID, build("first"), DeployOptions{})
+	require.NoError(t, err)
+
+	// The first rollout is about to start, so the second deploy doesn't get
+	// ahead of it.
+	_, err = s.newRollout(ctx, capsuleID, build("second"), DeployOptions{})
+	require.True(t, errors.IsFailedPrecondition(err))
+
+	second, err := s.newRollout(ctx, capsuleID, build("second"), DeployOptions{Queue: true})
+	require.NoError(t, err)
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_PENDING, rollouts.rss[first-1].GetStatus().GetState())
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_QUEUED, rollouts.rss[second-1].GetStatus().GetState())
+
+	// The second rollout leaves the queue once the first is done, and its
+	// config wins.
+	rollouts.rss[first-1].Status.State = capsule.RolloutState_ROLLOUT_STATE_DONE
+	j := &rolloutJob{s: s, capsuleID: capsuleID, rolloutID: second}
+	rc, rs := rollouts.rcs[second-1], rollouts.rss[second-1]
+	require.NoError(t, j.dequeue(ctx, rc, rs))
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_PENDING, rs.GetStatus().GetState())
+	require.Equal(t, "second", rc.GetBuildId())
+}
+
+func Test_QueueWaitingRollouts_Squash(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	rollouts := newTestRollouts(cr, capsuleID)
+	ws, events := testWebhooks(t)
+
+	s := &Service{
+		cr:     cr,
+		as:     &service_auth.Service{},
+		ws:     ws,
+		rw:     newRolloutWatchers(),
+		logger: zaptest.NewLogger(t),
+	}
+
+	// Rollouts 2 and 3 are scheduled after the done rollout 1.
+	for i, buildID := range []string{"b1", "b2", "b3"} {
+		rs := rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE)
+		if i > 0 {
+			rs.Status.State = capsule.RolloutState_ROLLOUT_STATE_PENDING
+			rs.Status.ScheduledAt = timestamppb.New(time.Now().Add(time.Hour))
+		}
+		_, err := cr.CreateRollout(ctx, capsuleID, &capsule.RolloutConfig{
+			BuildId:  buildID,
+			Replicas: 1,
+			Changes:  []*capsule.Change{{Field: &capsule.Change_BuildId{BuildId: buildID}}},
+		}, rs)
+		require.NoError(t, err)
+	}
+
+	_, _, waiting, err := s.startedRollout(ctx, capsuleID)
+	require.NoError(t, err)
+	require.NoError(t, s.queueWaitingRollouts(ctx, capsuleID, waiting))
+
+	// Rollout 3 is squashed into rollout 2, which is queued.
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_QUEUED, rollouts.rss[1].GetStatus().GetState())
+	require.Equal(t, "b3", rollouts.rcs[1].GetBuildId())
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_ABORTED, rollouts.rss[2].GetStatus().GetState())
+
+	// Squashing is routine, so webhooks aren't told the rollout was aborted.
+	require.NotEmpty(t, rollouts.events)
+	require.Empty(t, *events)
+}
+
+// testRollouts stores the rollouts of a capsule in memory, behind a mock
+// repository.
+type testRollouts struct {
+	rcs    []*capsule.RolloutConfig
+	rss    []*rollout.Status
+	events []*capsule.Event
+}
+
+func newTestRollouts(cr *repository.MockCapsule, capsuleID string) *testRollouts {
+	r := &testRollouts{}
+	get := func(id uint64) (*capsule.RolloutConfig, *rollout.Status, uint64, error) {
+		if id == 0 || id > uint64(len(r.rcs)) {
+			return nil, nil, 0, errors.NotFoundErrorf("rollout not found")
+		}
+		return proto.Clone(r.rcs[id-1]).(*capsule.RolloutConfig), proto.Clone(r.rss[id-1]).(*rollout.Status), 1, nil
+	}
+
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, mock.Anything).RunAndReturn(func(_ context.Context, _ string, buildID string) (*capsule.Build, error) {
+		return &capsule.Build{BuildId: buildID}, nil
+	}).Maybe()
+	cr.EXPECT().GetCurrentRollout(mock.Anything, capsuleID).RunAndReturn(func(context.Context, string) (uint64, *capsule.RolloutConfig, *rollout.Status, uint64, error) {
+		id := uint64(len(r.rcs))
+		if id == 0 {
+			return 0, nil, nil, 0, errors.NotFoundErrorf("rollout not found")
+		}
+		rc, rs, version, err := get(id)
+		return id, rc, rs, version, err
+	}).Maybe()
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, mock.Anything).RunAndReturn(func(_ context.Context, _ string, id uint64) (*capsule.RolloutConfig, *rollout.Status, uint64, error) {
+		return get(id)
+	}).Maybe()
+	cr.EXPECT().CreateRollout(mock.Anything, capsuleID, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, _ string, rc *capsule.RolloutConfig, rs *rollout.Status) (uint64, error) {
+		r.rcs = append(r.rcs, proto.Clone(rc).(*capsule.RolloutConfig))
+		r.rss = append(r.rss, proto.Clone(rs).(*rollout.Status))
+		return uint64(len(r.rcs)), nil
+	}).Maybe()
+	cr.EXPECT().UpdateRolloutStatus(mock.Anything, capsuleID, mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, _ string, id uint64, _ uint64, rs *rollout.Status) error {
+		r.rss[id-1] = proto.Clone(rs).(*rollout.Status)
+		return nil
+	}).Maybe()
+	cr.EXPECT().UpdateRollout(mock.Anything, capsuleID, mock.Anything, mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, _ string, id uint64, _ uint64, rc *capsule.RolloutConfig, rs *rollout.Status) error {
+		r.rcs[id-1] = proto.Clone(rc).(*capsule.RolloutConfig)
+		r.rss[id-1] = proto.Clone(rs).(*rollout.Status)
+		return nil
+	}).Maybe()
+	cr.EXPECT().LeaseRollout(mock.Anything, capsuleID, mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).RunAndReturn(func(_ context.Context, _ string, e *capsule.Event) error {
+		r.events = append(r.events, e)
+		return nil
+	}).Maybe()
+	return r
+}
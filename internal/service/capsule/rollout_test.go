@@ -0,0 +1,624 @@
+package capsule
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	internal_capsule "github.com/rigdev/rig/gen/go/capsule"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/internal/repository"
+	service_auth "github.com/rigdev/rig/internal/service/auth"
+	"github.com/rigdev/rig/internal/service/project"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/proto"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_RolloutJob_LeasedByOtherJob(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().LeaseRollout(mock.Anything, capsuleID, uint64(3), "lease", mock.Anything).Return(errors.FailedPreconditionErrorf("rollout is leased by another job"))
+
+	j := &rolloutJob{
+		s: &Service{
+			cr:     cr,
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: uuid.New(),
+		capsuleID: capsuleID,
+		rolloutID: 3,
+		leaseID:   "lease",
+	}
+
+	// The job stops without touching the rollout.
+	require.NoError(t, j.Run(context.Background()))
+}
+
+func Test_QueueRolloutJob_Lease(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+	ts := time.Now().Add(time.Hour)
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().LeaseRollout(mock.Anything, capsuleID, uint64(1), mock.Anything, ts.Add(rolloutLeaseTTL)).Return(nil)
+	cr.EXPECT().LeaseRollout(mock.Anything, capsuleID, uint64(2), mock.Anything, mock.Anything).Return(errors.FailedPreconditionErrorf("rollout is leased by another job"))
+
+	s := &Service{
+		cr:     cr,
+		q:      NewQueue[Job](),
+		logger: zaptest.NewLogger(t),
+	}
+
+	require.NoError(t, s.queueRolloutJob(ctx, capsuleID, 1, ts, ""))
+	require.NoError(t, s.queueRolloutJob(ctx, capsuleID, 2, ts, ""))
+
+	// Only the rollout without another lease is queued.
+	require.Equal(t, 1, s.q.is.Len())
+	j := s.q.is.Peek().t.(*rolloutJob)
+	require.Equal(t, uint64(1), j.rolloutID)
+	require.NotEmpty(t, j.leaseID)
+}
+
+// testLease holds the lease of a rollout in memory.
+type testLease struct {
+	mu        sync.Mutex
+	owner     string
+	expiresAt time.Time
+}
+
+func (l *testLease) lease(_ context.Context, _ string, _ uint64, owner string, expiresAt time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.owner != owner && l.expiresAt.After(time.Now()) {
+		return errors.FailedPreconditionErrorf("rollout is leased by another job")
+	}
+
+	l.owner = owner
+	l.expiresAt = expiresAt
+	return nil
+}
+
+// deployingJob returns a job for a deploying rollout, where updating the
+// capsule config is left to the caller.
+func deployingJob(t *testing.T, lease *testLease, leaseID string) (*rolloutJob, *repository.MockCapsule, *cluster.MockConfigGateway) {
+	projectID := uuid.New()
+	capsuleID := uuid.New().String()
+	rc := &capsule.RolloutConfig{BuildId: "nginx:1", Replicas: 1}
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().LeaseRollout(mock.Anything, capsuleID, uint64(1), mock.Anything, mock.Anything).RunAndReturn(lease.lease)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).RunAndReturn(func(context.Context, string, uint64) (*capsule.RolloutConfig, *rollout.Status, uint64, error) {
+		return proto.Clone(rc).(*capsule.RolloutConfig), rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DEPLOYING), 1, nil
+	}).Maybe()
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, "nginx:1").Return(&capsule.Build{BuildId: "nginx:1"}, nil).Maybe()
+	cr.EXPECT().CreateEvent(mock.Anything, capsuleID, mock.Anything).Return(nil).Maybe()
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().GetCapsuleConfig(mock.Anything, capsuleID).RunAndReturn(func(context.Context, string) (*v1alpha1.Capsule, error) {
+		return &v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}, nil
+	}).Maybe()
+	ccg.EXPECT().DeleteSecret(mock.Anything, capsuleID, mock.Anything, projectID.String()).Return(nil).Maybe()
+	ccg.EXPECT().SetEnvironmentVariables(mock.Anything, capsuleID, mock.Anything).Return(nil).Maybe()
+
+	ps := project.NewMockService(t)
+	ps.EXPECT().GetProjectDockerSecret(mock.Anything, "docker.io").Return(nil, errors.NotFoundErrorf("secret not found")).Maybe()
+
+	return &rolloutJob{
+		s: &Service{
+			cr:     cr,
+			ccg:    ccg,
+			ps:     ps,
+			as:     &service_auth.Service{},
+			q:      NewQueue[Job](),
+			rw:     newRolloutWatchers(),
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: projectID,
+		capsuleID: capsuleID,
+		rolloutID: 1,
+		leaseID:   leaseID,
+	}, cr, ccg
+}
+
+func Test_RolloutJob_RenewsLease(t *testing.T) {
+	ttl := rolloutLeaseTTL
+	rolloutLeaseTTL = 300 * time.Millisecond
+	t.Cleanup(func() { rolloutLeaseTTL = ttl })
+
+	lease := &testLease{}
+	j, cr, ccg := deployingJob(t, lease, "first")
+	cr.EXPECT().UpdateRollout(mock.Anything, j.capsuleID, uint64(1), uint64(1), mock.Anything, mock.Anything).Return(nil)
+
+	var applied atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	ccg.EXPECT().UpdateCapsuleConfig(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, *v1alpha1.Capsule) error {
+		if applied.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- j.Run(context.Background()) }()
+	<-started
+
+	// The step outlives the TTL, and a job started in the meantime, as by
+	// syncJob, leaves the rollout to the first job.
+	time.Sleep(2 * rolloutLeaseTTL)
+	second := &rolloutJob{s: j.s, projectID: j.projectID, capsuleID: j.capsuleID, rolloutID: 1, leaseID: "second"}
+	require.NoError(t, second.Run(context.Background()))
+
+	close(release)
+	require.NoError(t, <-done)
+	require.Equal(t, int32(1), applied.Load())
+}
+
+func Test_RolloutJob_LeaseLost(t *testing.T) {
+	ttl := rolloutLeaseTTL
+	rolloutLeaseTTL = 300 * time.Millisecond
+	t.Cleanup(func() { rolloutLeaseTTL = ttl })
+
+	lease := &testLease{}
+	j, _, ccg := deployingJob(t, lease, "first")
+	ccg.EXPECT().UpdateCapsuleConfig(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, _ *v1alpha1.Capsule) error {
+		// Another job takes over the rollout.
+		lease.mu.Lock()
+		lease.owner = "second"
+		lease.expiresAt = time.Now().Add(time.Hour)
+		lease.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			return errors.DeadlineExceededErrorf("step not cancelled")
+		}
+	})
+
+	// The step is cancelled, and the status is left to the other job.
+	require.NoError(t, j.Run(context.Background()))
+}
+
+func Test_Observe_InstanceNotReady(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cg := cluster.NewMockGateway(t)
+	cg.EXPECT().ListInstances(mock.Anything, capsuleID).Return(iterator.FromList([]*capsule.Instance{{
+		InstanceId: "instance",
+		BuildId:    "build",
+		State:      capsule.State_STATE_RUNNING,
+	}}), 1, nil)
+
+	j := &rolloutJob{
+		s: &Service{
+			cg:     cg,
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: uuid.New(),
+		capsuleID: capsuleID,
+		rolloutID: 3,
+	}
+
+	rc := &capsule.RolloutConfig{
+		BuildId:  "build",
+		Replicas: 1,
+	}
+	cfg := &v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}
+	rs := &rollout.Status{Status: &capsule.RolloutStatus{State: capsule.RolloutState_ROLLOUT_STATE_OBSERVING}}
+
+	// The rollout waits for the running instance to pass its readiness probe.
+	err := j.observe(context.Background(), cfg, rc, rs)
+	require.True(t, errors.IsUnavailable(err))
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_OBSERVING, rs.GetStatus().GetState())
+}
+
+func Test_Observe_InstanceStatusNotReady(t *testing.T) {
+	capsuleID := uuid.New().String()
+	projectID := uuid.New()
+
+	csg := cluster.NewMockStatusGateway(t)
+	csg.EXPECT().ListInstanceStatuses(mock.Anything, projectID.String(), capsuleID).Return(iterator.FromList([]*internal_capsule.Instance{{
+		InstanceId: "instance",
+		BuildId:    "build",
+		State:      internal_capsule.State_STATE_RUNNING,
+	}}), 1, nil)
+
+	j := &rolloutJob{
+		s: &Service{
+			csg:    csg,
+			logger: zaptest.NewLogger(t),
+		},
+		projectID: projectID,
+		capsuleID: capsuleID,
+		rolloutID: 3,
+	}
+
+	rc := &capsule.RolloutConfig{
+		BuildId:  "build",
+		Replicas: 1,
+	}
+	cfg := &v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}
+	rs := &rollout.Status{Status: &capsule.RolloutStatus{State: capsule.RolloutState_ROLLOUT_STATE_OBSERVING}}
+
+	// The instances are read from the status of the capsule.
+	err := j.observe(auth.WithProjectID(context.Background(), projectID), cfg, rc, rs)
+	require.True(t, errors.IsUnavailable(err))
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_OBSERVING, rs.GetStatus().GetState())
+}
+
+func Test_ListInstances_NoCapsuleStatus(t *testing.T) {
+	capsuleID := uuid.New().String()
+	projectID := uuid.New()
+
+	csg := cluster.NewMockStatusGateway(t)
+	csg.EXPECT().ListInstanceStatuses(mock.Anything, projectID.String(), capsuleID).Return(nil, 0, errors.NotFoundErrorf("capsule not found"))
+
+	cg := cluster.NewMockGateway(t)
+	cg.EXPECT().ListInstances(mock.Anything, capsuleID).Return(iterator.FromList([]*capsule.Instance{{
+		InstanceId: "instance",
+	}}), 1, nil)
+
+	s := &Service{
+		cg:     cg,
+		csg:    csg,
+		logger: zaptest.NewLogger(t),
+	}
+
+	// Clusters without the status of the capsule fall back to the gateway.
+	it, _, err := s.listInstances(auth.WithProjectID(context.Background(), projectID), capsuleID)
+	require.NoError(t, err)
+	is, err := iterator.Collect(it)
+	require.NoError(t, err)
+	require.Len(t, is, 1)
+}
+
+func Test_ApplyChanges_Autoscaler(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, "build").Return(&capsule.Build{BuildId: "build"}, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	rc := &capsule.RolloutConfig{BuildId: "build", Replicas: 1}
+	a := &capsule.Autoscaler{MinReplicas: 2, MaxReplicas: 5, CpuTarget: 80}
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_Autoscaler{Autoscaler: a},
+	}}))
+	require.Equal(t, a, rc.GetAutoscaler())
+	require.Equal(t, uint32(2), rolloutReplicas(rc))
+
+	err := s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_Autoscaler{Autoscaler: &capsule.Autoscaler{MinReplicas: 2, MaxReplicas: 5}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+
+	// An autoscaler without max replicas turns autoscaling off.
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_Autoscaler{Autoscaler: &capsule.Autoscaler{}},
+	}}))
+	require.Nil(t, rc.GetAutoscaler())
+	require.Equal(t, uint32(1), rolloutReplicas(rc))
+}
+
+func Test_ApplyChanges_CronJob(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, "build").Return(&capsule.Build{BuildId: "build"}, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	rc := &capsule.RolloutConfig{BuildId: "build"}
+	cj := &capsule.CronJob{Schedule: "0 3 * * *", ConcurrencyPolicy: capsule.ConcurrencyPolicy_CONCURRENCY_POLICY_FORBID}
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_CronJob{CronJob: cj},
+	}}))
+	require.Equal(t, cj, rc.GetCronJob())
+
+	err := s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_CronJob{CronJob: &capsule.CronJob{Schedule: "every night"}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+
+	err = s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_Autoscaler{Autoscaler: &capsule.Autoscaler{MinReplicas: 1, MaxReplicas: 2, CpuTarget: 80}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+
+	cfg := &v1alpha1.Capsule{}
+	rc.Network = &capsule.Network{Interfaces: []*capsule.Interface{{Name: "http", Port: 80}}}
+	applyRolloutConfig(cfg, rc)
+	require.Equal(t, &v1alpha1.CapsuleCronJob{
+		Schedule:          "0 3 * * *",
+		ConcurrencyPolicy: batchv1.ForbidConcurrent,
+		HistoryLimit:      defaultCronJobHistoryLimit,
+	}, cfg.Spec.CronJob)
+	require.Empty(t, cfg.Spec.Interfaces)
+}
+
+func Test_ApplyChanges_Volumes(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, "build").Return(&capsule.Build{BuildId: "build"}, nil)
+
+	s := &Service{
+		cr:     cr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	rc := &capsule.RolloutConfig{BuildId: "build", Replicas: 1}
+	v := &capsule.Volume{Name: "data", Path: "/var/lib/data/", SizeBytes: 1 << 30}
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_SetVolume{SetVolume: v},
+	}}))
+	require.Equal(t, []*capsule.Volume{v}, rc.GetVolumes())
+
+	// Only the path of an existing volume can be changed.
+	moved := &capsule.Volume{Name: "data", Path: "/data", SizeBytes: 1 << 30}
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_SetVolume{SetVolume: moved},
+	}}))
+	require.Equal(t, []*capsule.Volume{moved}, rc.GetVolumes())
+
+	err := s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_SetVolume{SetVolume: &capsule.Volume{Name: "data", Path: "/data", SizeBytes: 2 << 30}},
+	}})
+	require.True(t, errors.IsFailedPrecondition(err))
+
+	err = s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_SetVolume{SetVolume: &capsule.Volume{Name: "cache", Path: "/data", SizeBytes: 1 << 30}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+
+	err = s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_CronJob{CronJob: &capsule.CronJob{Schedule: "@hourly"}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+	rc.CronJob = nil
+
+	cfg := &v1alpha1.Capsule{}
+	applyRolloutConfig(cfg, rc)
+	require.Equal(t, []v1alpha1.CapsuleVolume{{
+		Name: "data",
+		Path: "/data",
+		Size: *resource.NewQuantity(1<<30, resource.BinarySI),
+	}}, cfg.Spec.Volumes)
+
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_RemoveVolume{RemoveVolume: "data"},
+	}}))
+	require.Empty(t, rc.GetVolumes())
+}
+
+func Test_ApplyChanges_IngressTLS(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetBuild(mock.Anything, capsuleID, "build").Return(&capsule.Build{BuildId: "build"}, nil)
+
+	sr := repository.NewMockSecret(t)
+
+	s := &Service{
+		cr:     cr,
+		sr:     sr,
+		logger: zaptest.NewLogger(t),
+	}
+	s.cfg.Cluster.CertManager.ClusterIssuer = "letsencrypt"
+
+	ingressInterface := func(name string, ing *capsule.RoutingMethod_Ingress) *capsule.Interface {
+		return &capsule.Interface{
+			Name: name,
+			Port: 80,
+			Public: &capsule.PublicInterface{
+				Enabled: true,
+				Method: &capsule.RoutingMethod{
+					Kind: &capsule.RoutingMethod_Ingress_{Ingress: ing},
+				},
+			},
+		}
+	}
+
+	rc := &capsule.RolloutConfig{BuildId: "build", Replicas: 1}
+	err := s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_Network{Network: &capsule.Network{Interfaces: []*capsule.Interface{
+			ingressInterface("http", &capsule.RoutingMethod_Ingress{Host: "test.com", PathPrefix: "api"}),
+		}}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+
+	certPEM, keyPEM := selfSignedCertificate(t, "test.com")
+	cert := &capsule.Certificate{Certificate: certPEM, PrivateKey: keyPEM}
+	cs := []*capsule.Change{{
+		Field: &capsule.Change_Network{Network: &capsule.Network{Interfaces: []*capsule.Interface{
+			ingressInterface("http", &capsule.RoutingMethod_Ingress{Host: "test.com", PathPrefix: "/api", Tls: true, Certificate: cert}),
+		}}},
+	}}
+	// The certificate is only validated, as the changes may not be rolled out.
+	require.NoError(t, s.applyChanges(context.Background(), capsuleID, rc, cs))
+	require.Equal(t, certPEM, rc.GetNetwork().GetInterfaces()[0].GetPublic().GetMethod().GetIngress().GetCertificate().GetCertificate())
+
+	// The certificate is stored once, when the rollout is created, and only its
+	// ID is kept in the rollout config.
+	var stored []byte
+	sr.EXPECT().Create(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, _ uuid.UUID, bs []byte) error {
+		stored = bs
+		return nil
+	}).Once()
+	rc.Changes = cs
+	ids, err := s.storeCertificates(context.Background(), rc)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	require.NotEmpty(t, stored)
+
+	storedCert := rc.GetNetwork().GetInterfaces()[0].GetPublic().GetMethod().GetIngress().GetCertificate()
+	require.Empty(t, storedCert.GetCertificate())
+	require.Empty(t, storedCert.GetPrivateKey())
+	require.Equal(t, ids[0].String(), storedCert.GetSecretId())
+	require.Equal(t, ids[0].String(), rc.GetChanges()[0].GetNetwork().GetInterfaces()[0].GetPublic().GetMethod().GetIngress().GetCertificate().GetSecretId())
+
+	// The changes of the caller are left as is.
+	require.Equal(t, certPEM, cert.GetCertificate())
+	require.Empty(t, cert.GetSecretId())
+
+	cfg := &v1alpha1.Capsule{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}
+	applyRolloutConfig(cfg, rc)
+	require.Equal(t, &v1alpha1.CapsuleInterfaceIngress{
+		Host:       "test.com",
+		PathPrefix: "/api",
+		TLS: &v1alpha1.CapsuleInterfaceIngressTLS{
+			SecretName: cluster.TLSSecretName(capsuleID, "http"),
+		},
+	}, cfg.Spec.Interfaces[0].Public.Ingress)
+
+	// cert-manager would replace the supplied certificate.
+	err = s.applyChanges(context.Background(), capsuleID, rc, []*capsule.Change{{
+		Field: &capsule.Change_Network{Network: &capsule.Network{Interfaces: []*capsule.Interface{
+			ingressInterface("http", &capsule.RoutingMethod_Ingress{Host: "test.com", Tls: true, Certificate: &capsule.Certificate{SecretId: storedCert.GetSecretId()}}),
+			ingressInterface("admin", &capsule.RoutingMethod_Ingress{Host: "admin.test.com", Tls: true}),
+		}}},
+	}})
+	require.True(t, errors.IsInvalidArgument(err))
+}
+
+func Test_CreateRollout_DeletesCertificates(t *testing.T) {
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().CreateRollout(mock.Anything, capsuleID, mock.Anything, mock.Anything).Return(0, errors.AbortedErrorf("conflict"))
+
+	var secretID uuid.UUID
+	sr := repository.NewMockSecret(t)
+	sr.EXPECT().Create(mock.Anything, mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, id uuid.UUID, _ []byte) error {
+		secretID = id
+		return nil
+	})
+	sr.EXPECT().Delete(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, id uuid.UUID) error {
+		require.Equal(t, secretID, id)
+		return nil
+	})
+
+	s := &Service{
+		cr:     cr,
+		sr:     sr,
+		logger: zaptest.NewLogger(t),
+	}
+
+	certPEM, keyPEM := selfSignedCertificate(t, "test.com")
+	rc := &capsule.RolloutConfig{Network: &capsule.Network{Interfaces: []*capsule.Interface{{
+		Name: "http",
+		Public: &capsule.PublicInterface{
+			Enabled: true,
+			Method: &capsule.RoutingMethod{Kind: &capsule.RoutingMethod_Ingress_{Ingress: &capsule.RoutingMethod_Ingress{
+				Host:        "test.com",
+				Tls:         true,
+				Certificate: &capsule.Certificate{Certificate: certPEM, PrivateKey: keyPEM},
+			}}},
+		},
+	}}}}
+
+	// The certificate is not left behind when the rollout isn't created.
+	_, err := s.createRollout(context.Background(), capsuleID, rc, &rollout.Status{})
+	require.True(t, errors.IsAborted(err))
+}
+
+func Test_DeleteUnusedCertificates(t *testing.T) {
+	capsuleID := uuid.New().String()
+	current, replaced, failed, queued, waiting := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+
+	network := func(ids ...uuid.UUID) *capsule.Network {
+		n := &capsule.Network{}
+		for _, id := range ids {
+			n.Interfaces = append(n.Interfaces, &capsule.Interface{
+				Name: id.String(),
+				Public: &capsule.PublicInterface{
+					Method: &capsule.RoutingMethod{Kind: &capsule.RoutingMethod_Ingress_{Ingress: &capsule.RoutingMethod_Ingress{
+						Certificate: &capsule.Certificate{SecretId: id.String()},
+					}}},
+				},
+			})
+		}
+		return n
+	}
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(5)).Return(&capsule.RolloutConfig{Network: network(queued)}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_PENDING), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(6)).Return(nil, nil, 0, errors.NotFoundErrorf("rollout not found"))
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(3)).Return(&capsule.RolloutConfig{Network: network(failed, queued)}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_FAILED), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(2)).Return(&capsule.RolloutConfig{Network: network(waiting)}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_QUEUED), 1, nil)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).Return(&capsule.RolloutConfig{Network: network(replaced, waiting)}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE), 1, nil)
+
+	var deleted []uuid.UUID
+	sr := repository.NewMockSecret(t)
+	sr.EXPECT().Delete(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, id uuid.UUID) error {
+		deleted = append(deleted, id)
+		return nil
+	})
+
+	j := &rolloutJob{
+		s: &Service{
+			cr:     cr,
+			sr:     sr,
+			logger: zaptest.NewLogger(t),
+		},
+		capsuleID: capsuleID,
+		rolloutID: 4,
+	}
+
+	j.deleteUnusedCertificates(context.Background(), &capsule.RolloutConfig{
+		Network: network(current),
+		Changes: []*capsule.Change{{Field: &capsule.Change_Network{Network: network(current)}}},
+	})
+	require.ElementsMatch(t, []uuid.UUID{replaced, failed}, deleted)
+}
+
+func selfSignedCertificate(t *testing.T, host string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{host},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
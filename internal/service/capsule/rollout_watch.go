@@ -0,0 +1,197 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+)
+
+// rolloutWatchResync is how often a watch polls the rollout without being
+// notified, to pick up changes written by other servers. A watch sees those
+// changes up to this late.
+const rolloutWatchResync = 5 * time.Second
+
+// rolloutWatchers notifies the watches of a rollout when this server changes
+// its status or creates an event for it. The notifications don't reach other
+// servers, which may be running the rollout job, so the watches poll as well.
+type rolloutWatchers struct {
+	lock     sync.Mutex
+	watchers map[string]map[chan struct{}]struct{}
+}
+
+func newRolloutWatchers() *rolloutWatchers {
+	return &rolloutWatchers{
+		watchers: map[string]map[chan struct{}]struct{}{},
+	}
+}
+
+func rolloutWatchKey(projectID uuid.UUID, capsuleID string, rolloutID uint64) string {
+	return fmt.Sprintf("%s/%s/%d", projectID, capsuleID, rolloutID)
+}
+
+// subscribe returns a channel that is signalled when the rollout changes,
+// and a function that stops the subscription.
+func (w *rolloutWatchers) subscribe(key string) (<-chan struct{}, func()) {
+	c := make(chan struct{}, 1)
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.watchers[key] == nil {
+		w.watchers[key] = map[chan struct{}]struct{}{}
+	}
+	w.watchers[key][c] = struct{}{}
+
+	return c, func() {
+		w.lock.Lock()
+		defer w.lock.Unlock()
+
+		delete(w.watchers[key], c)
+		if len(w.watchers[key]) == 0 {
+			delete(w.watchers, key)
+		}
+	}
+}
+
+func (w *rolloutWatchers) notify(key string) {
+	if w == nil {
+		return
+	}
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	for c := range w.watchers[key] {
+		select {
+		case c <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyRollout signals the watches of the rollout that it has changed.
+func (s *Service) notifyRollout(ctx context.Context, capsuleID string, rolloutID uint64) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return
+	}
+
+	s.rw.notify(rolloutWatchKey(projectID, capsuleID, rolloutID))
+}
+
+// WatchRollout streams the rollout whenever its status changes, and its events
+// as they are created, starting from the given offset. The iterator ends once
+// the rollout is done, failed or aborted.
+//
+// The watch reads the rollout when notified by this server, and otherwise
+// polls it every rolloutWatchResync. Each read gets the rollout and only the
+// events after the last one sent, so a poll of an unchanged rollout is two
+// small reads per watch.
+func (s *Service) WatchRollout(ctx context.Context, capsuleID string, rolloutID uint64, eventsOffset uint32) (iterator.Iterator[*capsule.WatchRolloutResponse], error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, _, _, err := s.cr.GetRollout(ctx, capsuleID, rolloutID); err != nil {
+		return nil, err
+	}
+
+	c, unsubscribe := s.rw.subscribe(rolloutWatchKey(projectID, capsuleID, rolloutID))
+
+	p := iterator.NewProducer[*capsule.WatchRolloutResponse]()
+	go func() {
+		defer unsubscribe()
+		err := s.watchRollout(ctx, capsuleID, rolloutID, eventsOffset, c, p)
+		if err != nil {
+			s.logger.Debug("rollout watch stopped", zap.String("capsule_id", capsuleID), zap.Uint64("rollout_id", rolloutID), zap.Error(err))
+		}
+		p.Error(err)
+	}()
+
+	return p, nil
+}
+
+func (s *Service) watchRollout(
+	ctx context.Context,
+	capsuleID string,
+	rolloutID uint64,
+	eventsOffset uint32,
+	c <-chan struct{},
+	p *iterator.Producer[*capsule.WatchRolloutResponse],
+) error {
+	var status *capsule.RolloutStatus
+	t := time.NewTicker(rolloutWatchResync)
+	defer t.Stop()
+
+	for {
+		rc, rs, _, err := s.cr.GetRollout(ctx, capsuleID, rolloutID)
+		if err != nil {
+			return err
+		}
+
+		if !proto.Equal(rs.GetStatus(), status) {
+			status = rs.GetStatus()
+			if err := p.Value(&capsule.WatchRolloutResponse{
+				Kind: &capsule.WatchRolloutResponse_Rollout{Rollout: &capsule.Rollout{
+					RolloutId: rolloutID,
+					Config:    rc,
+					Status:    rs.GetStatus(),
+				}},
+			}); err != nil {
+				return err
+			}
+		}
+
+		// The events are read after the status, so the events leading up
+		// to a final state are all sent before the stream ends. Only the
+		// events after the ones sent are read.
+		it, _, err := s.cr.ListEvents(ctx, &model.Pagination{Offset: eventsOffset}, capsuleID, rolloutID)
+		if err != nil {
+			return err
+		}
+
+		for {
+			e, err := it.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				it.Close()
+				return err
+			}
+
+			eventsOffset++
+			if err := p.Value(&capsule.WatchRolloutResponse{
+				Kind: &capsule.WatchRolloutResponse_Event{Event: e},
+			}); err != nil {
+				it.Close()
+				return err
+			}
+		}
+		it.Close()
+
+		switch rs.GetStatus().GetState() {
+		case capsule.RolloutState_ROLLOUT_STATE_DONE,
+			capsule.RolloutState_ROLLOUT_STATE_FAILED,
+			capsule.RolloutState_ROLLOUT_STATE_ABORTED:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-c:
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package capsule
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func Test_WatchRollout_Notify(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	cr := repository.NewMockCapsule(t)
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DEPLOYING), 1, nil).Times(2)
+	cr.EXPECT().ListEvents(mock.Anything, &model.Pagination{Offset: 2}, capsuleID, uint64(1)).Return(iterator.FromList([]*capsule.Event{{Message: "deploying"}}), 3, nil).Once()
+	cr.EXPECT().GetRollout(mock.Anything, capsuleID, uint64(1)).Return(&capsule.RolloutConfig{}, rolloutStatus(capsule.RolloutState_ROLLOUT_STATE_DONE), 2, nil).Once()
+	cr.EXPECT().ListEvents(mock.Anything, &model.Pagination{Offset: 3}, capsuleID, uint64(1)).Return(iterator.FromList([]*capsule.Event{{Message: "done"}}), 4, nil).Once()
+
+	s := &Service{
+		cr:     cr,
+		rw:     newRolloutWatchers(),
+		logger: zaptest.NewLogger(t),
+	}
+
+	it, err := s.WatchRollout(ctx, capsuleID, 1, 2)
+	require.NoError(t, err)
+	defer it.Close()
+
+	res, err := it.Next()
+	require.NoError(t, err)
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_DEPLOYING, res.GetRollout().GetStatus().GetState())
+
+	res, err = it.Next()
+	require.NoError(t, err)
+	require.Equal(t, "deploying", res.GetEvent().GetMessage())
+
+	// The rollout is read again when notified, instead of waiting for the
+	// resync.
+	s.notifyRollout(ctx, capsuleID, 1)
+
+	res, err = it.Next()
+	require.NoError(t, err)
+	require.Equal(t, capsule.RolloutState_ROLLOUT_STATE_DONE, res.GetRollout().GetStatus().GetState())
+
+	res, err = it.Next()
+	require.NoError(t, err)
+	require.Equal(t, "done", res.GetEvent().GetMessage())
+
+	_, err = it.Next()
+	require.ErrorIs(t, err, io.EOF)
+}
@@ -2,6 +2,7 @@ package capsule
 
 import (
 	"context"
+	"time"
 
 	"github.com/rigdev/rig-go-api/api/v1/capsule"
 	"github.com/rigdev/rig-go-api/model"
@@ -10,6 +11,7 @@ import (
 	"github.com/rigdev/rig/internal/repository"
 	service_auth "github.com/rigdev/rig/internal/service/auth"
 	"github.com/rigdev/rig/internal/service/project"
+	"github.com/rigdev/rig/internal/service/webhook"
 	"github.com/rigdev/rig/pkg/api/v1alpha1"
 	"github.com/rigdev/rig/pkg/auth"
 	"github.com/rigdev/rig/pkg/errors"
@@ -28,11 +30,14 @@ type Service struct {
 	csg    cluster.StatusGateway
 	as     *service_auth.Service
 	ps     project.Service
+	ws     *webhook.Service
 	q      *Queue[Job]
+	rw     *rolloutWatchers
+	cl     *crashLoops
 	cfg    config.Config
 }
 
-func NewService(cr repository.Capsule, sr repository.Secret, cg cluster.Gateway, ccg cluster.ConfigGateway, csg cluster.StatusGateway, as *service_auth.Service, ps project.Service, cfg config.Config, logger *zap.Logger) *Service {
+func NewService(cr repository.Capsule, sr repository.Secret, cg cluster.Gateway, ccg cluster.ConfigGateway, csg cluster.StatusGateway, as *service_auth.Service, ps project.Service, ws *webhook.Service, cfg config.Config, logger *zap.Logger) *Service {
 	s := &Service{
 		cr:     cr,
 		sr:     sr,
@@ -41,7 +46,10 @@ func NewService(cr repository.Capsule, sr repository.Secret, cg cluster.Gateway,
 		csg:    csg,
 		as:     as,
 		ps:     ps,
+		ws:     ws,
 		q:      NewQueue[Job](),
+		rw:     newRolloutWatchers(),
+		cl:     newCrashLoops(),
 		cfg:    cfg,
 		logger: logger,
 	}
@@ -107,6 +115,15 @@ func (s *Service) Logs(ctx context.Context, capsuleID string, instanceID string,
 	return s.cg.Logs(ctx, d.GetCapsuleId(), instanceID, follow)
 }
 
+func (s *Service) AccessLogs(ctx context.Context, capsuleID string, instanceID string, follow bool) (iterator.Iterator[*capsule.Log], error) {
+	d, err := s.GetCapsule(ctx, capsuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.cg.AccessLogs(ctx, d.GetCapsuleId(), instanceID, follow)
+}
+
 func (s *Service) DeleteCapsule(ctx context.Context, capsuleID string) error {
 	_, err := s.GetCapsule(ctx, capsuleID)
 	if err != nil {
@@ -147,7 +164,7 @@ func (s *Service) ListInstances(ctx context.Context, capsuleID string, paginatio
 		return nil, 0, err
 	}
 
-	return s.cg.ListInstances(ctx, c.GetCapsuleId())
+	return s.listInstances(ctx, c.GetCapsuleId())
 }
 
 func (s *Service) RestartInstance(ctx context.Context, capsuleID string, instanceID string) error {
@@ -159,6 +176,55 @@ func (s *Service) RestartInstance(ctx context.Context, capsuleID string, instanc
 	return s.cg.RestartInstance(ctx, c.GetCapsuleId(), instanceID)
 }
 
+// Exec runs the command in the instance of the capsule until it exits, and
+// returns its exit code.
+func (s *Service) Exec(ctx context.Context, capsuleID string, instanceID string, command []string, streams *cluster.ExecStreams) (int32, error) {
+	if len(command) == 0 || command[0] == "" {
+		return 0, errors.InvalidArgumentErrorf("missing command")
+	}
+
+	c, err := s.GetCapsule(ctx, capsuleID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.cg.Exec(ctx, c.GetCapsuleId(), instanceID, command, streams)
+}
+
+// PortForward opens a connection to a port of the instance. The port can be
+// given either as a number or as the name of one of the interfaces of the
+// capsule.
+func (s *Service) PortForward(ctx context.Context, capsuleID string, instanceID string, port uint32, iface string) (cluster.Conn, error) {
+	c, err := s.GetCapsule(ctx, capsuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if iface != "" {
+		cfg, err := s.ccg.GetCapsuleConfig(ctx, c.GetCapsuleId())
+		if err != nil {
+			return nil, err
+		}
+
+		port = 0
+		for _, i := range cfg.Spec.Interfaces {
+			if i.Name == iface {
+				port = uint32(i.Port)
+				break
+			}
+		}
+		if port == 0 {
+			return nil, errors.NotFoundErrorf("interface '%s' not found", iface)
+		}
+	}
+
+	if port == 0 || port > 65535 {
+		return nil, errors.InvalidArgumentErrorf("invalid port '%d'", port)
+	}
+
+	return s.cg.PortForward(ctx, c.GetCapsuleId(), instanceID, port)
+}
+
 func (s *Service) DeleteBuild(ctx context.Context, capsuleID string, buildID string) error {
 	cp, err := s.GetCapsule(ctx, capsuleID)
 	if err != nil {
@@ -178,8 +244,22 @@ func (s *Service) DeleteBuild(ctx context.Context, capsuleID string, buildID str
 	return s.cr.DeleteBuild(ctx, capsuleID, buildID)
 }
 
-func (s *Service) Deploy(ctx context.Context, capsuleID string, cs []*capsule.Change) (uint64, error) {
-	rolloutID, err := s.newRollout(ctx, capsuleID, cs)
+// DeployOptions controls when the rollout of a deploy starts.
+type DeployOptions struct {
+	// ScheduleAt holds the rollout in PENDING until the given time. Rollouts
+	// deployed to start right away in the meantime go first, and the
+	// scheduled rollout is queued behind them.
+	ScheduleAt time.Time
+	// OverrideDeployWindow starts the rollout even if outside the deploy
+	// windows of the project.
+	OverrideDeployWindow bool
+	// Queue queues the rollout behind the rollout in progress, instead of
+	// failing. Changes of later queued deploys are squashed into it.
+	Queue bool
+}
+
+func (s *Service) Deploy(ctx context.Context, capsuleID string, cs []*capsule.Change, opts DeployOptions) (uint64, error) {
+	rolloutID, err := s.newRollout(ctx, capsuleID, cs, opts)
 	if err != nil {
 		return 0, err
 	}
@@ -0,0 +1,166 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// taskPollInterval is how often the status of a task is checked while
+	// waiting for it to start or exit.
+	taskPollInterval = time.Second
+	// taskStartTimeout is how long a task may take to start, e.g. to pull
+	// the image, before it is given up.
+	taskStartTimeout = 5 * time.Minute
+)
+
+func taskInstanceID(capsuleID string) string {
+	return fmt.Sprintf("%s-hook-task-%s", capsuleID, uuid.New().String()[:8])
+}
+
+// RunTask runs the command in a new instance with the config of the current
+// rollout of the capsule. The iterator returns the ID of the instance, its
+// logs and lastly its exit code. The instance is removed once the iterator
+// ends.
+func (s *Service) RunTask(ctx context.Context, capsuleID, command string, args []string) (iterator.Iterator[*capsule.RunTaskResponse], error) {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if command == "" {
+		return nil, errors.InvalidArgumentErrorf("missing command")
+	}
+
+	cfg, err := s.ccg.GetCapsuleConfig(ctx, capsuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Spec.Image == "" {
+		return nil, errors.FailedPreconditionErrorf("capsule '%s' has no rollout", capsuleID)
+	}
+
+	envs, err := s.ccg.GetEnvironmentVariables(ctx, capsuleID)
+	if err != nil {
+		return nil, err
+	}
+
+	instanceID := taskInstanceID(capsuleID)
+	if err := s.ccg.StartHook(ctx, cfg, envs, &cluster.Hook{
+		InstanceID: instanceID,
+		Kind:       cluster.HookKindTask,
+		Command:    command,
+		Args:       args,
+	}); err != nil {
+		return nil, err
+	}
+
+	p := iterator.NewProducer[*capsule.RunTaskResponse]()
+	go func() {
+		err := s.runTask(ctx, capsuleID, instanceID, p)
+
+		// The task is removed even if the caller went away.
+		cctx := auth.WithProjectID(context.Background(), projectID)
+		if err := s.ccg.DeleteHook(cctx, capsuleID, instanceID); err != nil && !errors.IsNotFound(err) {
+			s.logger.Warn("could not delete task", zap.String("capsule_id", capsuleID), zap.String("instance_id", instanceID), zap.Error(err))
+		}
+
+		p.Error(err)
+	}()
+
+	return p, nil
+}
+
+func (s *Service) runTask(ctx context.Context, capsuleID, instanceID string, p *iterator.Producer[*capsule.RunTaskResponse]) error {
+	if err := p.Value(&capsule.RunTaskResponse{
+		Kind: &capsule.RunTaskResponse_InstanceId{InstanceId: instanceID},
+	}); err != nil {
+		return err
+	}
+
+	it, err := s.taskLogs(ctx, capsuleID, instanceID)
+	if err != nil {
+		return err
+	}
+
+	for {
+		l, err := it.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			it.Close()
+			return err
+		}
+
+		if err := p.Value(&capsule.RunTaskResponse{
+			Kind: &capsule.RunTaskResponse_Log{Log: l},
+		}); err != nil {
+			it.Close()
+			return err
+		}
+	}
+	it.Close()
+
+	// The log stream may end slightly before the instance is reported as
+	// exited.
+	for {
+		exited, code, err := s.ccg.GetHookStatus(ctx, capsuleID, instanceID)
+		if err != nil {
+			return err
+		}
+
+		if exited {
+			return p.Value(&capsule.RunTaskResponse{
+				Kind: &capsule.RunTaskResponse_ExitCode{ExitCode: code},
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
+
+// taskLogs follows the logs of the task, waiting for it to start as the logs
+// can't be read before that.
+func (s *Service) taskLogs(ctx context.Context, capsuleID, instanceID string) (iterator.Iterator[*capsule.Log], error) {
+	deadline := time.Now().Add(taskStartTimeout)
+	for {
+		it, err := s.cg.Logs(ctx, capsuleID, instanceID, true)
+		if err == nil {
+			return it, nil
+		}
+
+		exited, _, serr := s.ccg.GetHookStatus(ctx, capsuleID, instanceID)
+		if serr != nil {
+			return nil, serr
+		}
+
+		if exited {
+			return s.cg.Logs(ctx, capsuleID, instanceID, false)
+		}
+
+		if time.Now().After(deadline) {
+			return nil, errors.DeadlineExceededErrorf("task did not start: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(taskPollInterval):
+		}
+	}
+}
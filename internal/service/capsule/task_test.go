@@ -0,0 +1,80 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_RunTask(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+	envs := map[string]string{"RIG_PROJECT_ID": "test"}
+	cfg := &v1alpha1.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: capsuleID},
+		Spec:       v1alpha1.CapsuleSpec{Image: "nginx:latest"},
+	}
+	l := &capsule.Log{Message: &capsule.LogMessage{Message: &capsule.LogMessage_Stdout{Stdout: []byte("seeded\n")}}}
+
+	var instanceID string
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().GetCapsuleConfig(mock.Anything, capsuleID).Return(cfg, nil)
+	ccg.EXPECT().GetEnvironmentVariables(mock.Anything, capsuleID).Return(envs, nil)
+	ccg.EXPECT().StartHook(mock.Anything, cfg, envs, mock.Anything).
+		RunAndReturn(func(_ context.Context, _ *v1alpha1.Capsule, _ map[string]string, h *cluster.Hook) error {
+			require.Equal(t, cluster.HookKindTask, h.Kind)
+			require.Equal(t, "rake", h.Command)
+			require.Equal(t, []string{"db:seed"}, h.Args)
+			instanceID = h.InstanceID
+			return nil
+		})
+	ccg.EXPECT().GetHookStatus(mock.Anything, capsuleID, mock.Anything).Return(true, 3, nil)
+	ccg.EXPECT().DeleteHook(mock.Anything, capsuleID, mock.Anything).Return(nil)
+
+	cg := cluster.NewMockGateway(t)
+	cg.EXPECT().Logs(mock.Anything, capsuleID, mock.Anything, true).Return(iterator.FromList([]*capsule.Log{l}), nil)
+
+	s := &Service{
+		cg:     cg,
+		ccg:    ccg,
+		logger: zaptest.NewLogger(t),
+	}
+
+	it, err := s.RunTask(ctx, capsuleID, "rake", []string{"db:seed"})
+	require.NoError(t, err)
+
+	res, err := iterator.Collect(it)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+	require.Equal(t, instanceID, res[0].GetInstanceId())
+	require.Equal(t, l, res[1].GetLog())
+	require.Equal(t, int32(3), res[2].GetExitCode())
+}
+
+func Test_RunTask_NoRollout(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	capsuleID := uuid.New().String()
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().GetCapsuleConfig(mock.Anything, capsuleID).Return(&v1alpha1.Capsule{
+		ObjectMeta: metav1.ObjectMeta{Name: capsuleID},
+	}, nil)
+
+	s := &Service{
+		ccg:    ccg,
+		logger: zaptest.NewLogger(t),
+	}
+
+	_, err := s.RunTask(ctx, capsuleID, "rake", nil)
+	require.Error(t, err)
+}
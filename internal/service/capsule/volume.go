@@ -0,0 +1,82 @@
+package capsule
+
+import (
+	"path"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+func validateVolume(v *capsule.Volume) error {
+	if errs := validation.IsDNS1123Label(v.GetName()); len(errs) > 0 {
+		return errors.InvalidArgumentErrorf("invalid volume name '%s': %s", v.GetName(), errs[0])
+	}
+
+	if !path.IsAbs(v.GetPath()) || path.Clean(v.GetPath()) == "/" {
+		return errors.InvalidArgumentErrorf("volume path must be an absolute path other than /")
+	}
+
+	if v.GetSizeBytes() == 0 {
+		return errors.InvalidArgumentErrorf("volume size must be positive")
+	}
+
+	return nil
+}
+
+// setVolume adds the volume to the rollout config, or replaces the volume with
+// the same name. Existing volumes are not resized or moved to another storage
+// class, so those can't be changed.
+func setVolume(rc *capsule.RolloutConfig, v *capsule.Volume) error {
+	if err := validateVolume(v); err != nil {
+		return err
+	}
+
+	for i, ev := range rc.GetVolumes() {
+		if ev.GetName() != v.GetName() {
+			if path.Clean(ev.GetPath()) == path.Clean(v.GetPath()) {
+				return errors.InvalidArgumentErrorf("volume '%s' is already mounted at '%s'", ev.GetName(), ev.GetPath())
+			}
+			continue
+		}
+
+		if ev.GetSizeBytes() != v.GetSizeBytes() {
+			return errors.FailedPreconditionErrorf("the size of volume '%s' cannot be changed", v.GetName())
+		}
+		if ev.GetStorageClass() != v.GetStorageClass() {
+			return errors.FailedPreconditionErrorf("the storage class of volume '%s' cannot be changed", v.GetName())
+		}
+
+		rc.Volumes[i] = v
+		return nil
+	}
+
+	rc.Volumes = append(rc.Volumes, v)
+	return nil
+}
+
+func removeVolume(rc *capsule.RolloutConfig, name string) error {
+	for i, v := range rc.GetVolumes() {
+		if v.GetName() == name {
+			rc.Volumes = append(rc.Volumes[:i], rc.Volumes[i+1:]...)
+			return nil
+		}
+	}
+
+	return errors.NotFoundErrorf("volume '%s' not found", name)
+}
+
+func capsuleVolumes(vs []*capsule.Volume) []v1alpha1.CapsuleVolume {
+	var cvs []v1alpha1.CapsuleVolume
+	for _, v := range vs {
+		cvs = append(cvs, v1alpha1.CapsuleVolume{
+			Name:             v.GetName(),
+			Path:             path.Clean(v.GetPath()),
+			Size:             *resource.NewQuantity(int64(v.GetSizeBytes()), resource.BinarySI),
+			StorageClassName: v.GetStorageClass(),
+		})
+	}
+	return cvs
+}
@@ -0,0 +1,138 @@
+package capsule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/gen/go/rollout"
+	"github.com/rigdev/rig/pkg/auth"
+	"go.uber.org/zap"
+)
+
+// webhookEventType returns the webhook event type of a capsule event, or
+// EVENT_TYPE_UNSPECIFIED if the event is not sent to webhooks.
+func webhookEventType(ed *capsule.EventData) webhook.EventType {
+	switch v := ed.GetKind().(type) {
+	case *capsule.EventData_Abort:
+		return webhook.EventType_EVENT_TYPE_ROLLOUT_ABORTED
+	case *capsule.EventData_Rollout:
+		switch v.Rollout.GetState() {
+		case capsule.RolloutState_ROLLOUT_STATE_PREPARING:
+			return webhook.EventType_EVENT_TYPE_ROLLOUT_STARTED
+		case capsule.RolloutState_ROLLOUT_STATE_DONE:
+			return webhook.EventType_EVENT_TYPE_ROLLOUT_DONE
+		case capsule.RolloutState_ROLLOUT_STATE_FAILED:
+			return webhook.EventType_EVENT_TYPE_ROLLOUT_FAILED
+		}
+	}
+
+	return webhook.EventType_EVENT_TYPE_UNSPECIFIED
+}
+
+// emitWebhook sends the event to the webhooks of the project. Webhooks are
+// best-effort, so errors are only logged.
+func (s *Service) emitWebhook(ctx context.Context, e *webhook.Event) {
+	if err := s.ws.Emit(ctx, e); err != nil {
+		s.logger.Warn("error emitting webhook event", zap.String("capsule_id", e.GetCapsuleId()), zap.Stringer("type", e.GetType()), zap.Error(err))
+	}
+}
+
+// stateChanged creates an event when the rollout has moved to a final state.
+func (j *rolloutJob) stateChanged(ctx context.Context, rs *rollout.Status, logger *zap.Logger) {
+	var msg string
+	switch rs.GetStatus().GetState() {
+	case capsule.RolloutState_ROLLOUT_STATE_DONE:
+		msg = "rollout done"
+	case capsule.RolloutState_ROLLOUT_STATE_FAILED:
+		msg = fmt.Sprintf("rollout failed: %s", rs.GetStatus().GetMessage())
+	default:
+		return
+	}
+
+	if err := j.s.CreateEvent(ctx, j.capsuleID, j.rolloutID, msg, &capsule.EventData{
+		Kind: &capsule.EventData_Rollout{Rollout: &capsule.RolloutEvent{State: rs.GetStatus().GetState()}},
+	}); err != nil {
+		logger.Info("error creating rollout state event", zap.Error(err))
+	}
+}
+
+// crashLoops holds the crash looping instances reported to webhooks by this
+// server, with their restart count when reported.
+type crashLoops struct {
+	mu       sync.Mutex
+	reported map[crashLoopKey]uint32
+}
+
+type crashLoopKey struct {
+	projectID  string
+	capsuleID  string
+	instanceID string
+}
+
+func newCrashLoops() *crashLoops {
+	return &crashLoops{
+		reported: map[crashLoopKey]uint32{},
+	}
+}
+
+// report returns true if the instance isn't reported already. An instance
+// replacing another with the same ID, as the instances of a StatefulSet do,
+// starts over on restarts, so it's reported again.
+func (c *crashLoops) report(key crashLoopKey, restarts uint32) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r, ok := c.reported[key]; ok && restarts >= r {
+		return false
+	}
+
+	c.reported[key] = restarts
+	return true
+}
+
+// forget drops the reported instances of the capsule that are gone.
+func (c *crashLoops) forget(projectID, capsuleID string, instanceIDs map[string]struct{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.reported {
+		if key.projectID != projectID || key.capsuleID != capsuleID {
+			continue
+		}
+
+		if _, ok := instanceIDs[key.instanceID]; !ok {
+			delete(c.reported, key)
+		}
+	}
+}
+
+// reportCrashLoop sends a webhook event the first time a failing instance is
+// seen, whether listed by the user, a rollout or the instance watch.
+func (s *Service) reportCrashLoop(ctx context.Context, capsuleID string, i *capsule.Instance) {
+	if i.GetState() != capsule.State_STATE_FAILED {
+		return
+	}
+
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return
+	}
+
+	if !s.cl.report(crashLoopKey{
+		projectID:  projectID.String(),
+		capsuleID:  capsuleID,
+		instanceID: i.GetInstanceId(),
+	}, i.GetRestartCount()) {
+		return
+	}
+
+	s.emitWebhook(ctx, &webhook.Event{
+		Type:       webhook.EventType_EVENT_TYPE_INSTANCE_CRASH_LOOP,
+		CapsuleId:  capsuleID,
+		InstanceId: i.GetInstanceId(),
+		Message:    fmt.Sprintf("instance '%s' is crash looping after %d restarts", i.GetInstanceId(), i.GetRestartCount()),
+	})
+}
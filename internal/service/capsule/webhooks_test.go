@@ -0,0 +1,101 @@
+package capsule
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/capsule"
+	api_project "github.com/rigdev/rig-go-api/api/v1/project"
+	api_webhook "github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/internal/gateway/cluster"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/internal/service/project"
+	"github.com/rigdev/rig/internal/service/webhook"
+	"github.com/rigdev/rig/pkg/api/v1alpha1"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// testWebhooks returns a webhook service with a single webhook, and the events
+// delivered to it.
+func testWebhooks(t *testing.T) (*webhook.Service, *[]*api_webhook.Event) {
+	var events []*api_webhook.Event
+	wr := repository.NewMockWebhook(t)
+	wr.EXPECT().List(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, *model.Pagination) (iterator.Iterator[*api_webhook.Webhook], uint64, error) {
+		return iterator.FromList([]*api_webhook.Webhook{{WebhookId: uuid.New().String()}}), 1, nil
+	}).Maybe()
+	wr.EXPECT().CreateDelivery(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, d *api_webhook.Delivery) error {
+		events = append(events, d.GetEvent())
+		return nil
+	}).Maybe()
+	wr.EXPECT().ClaimDelivery(mock.Anything, mock.Anything, mock.Anything).Return(uuid.Nil, nil, errors.NotFoundErrorf("no delivery")).Maybe()
+
+	return webhook.NewService(wr, repository.NewMockSecret(t), zaptest.NewLogger(t)), &events
+}
+
+func Test_WatchInstances_CrashLoop(t *testing.T) {
+	projectID := uuid.New()
+	capsuleID := uuid.New().String()
+
+	ps := project.NewMockService(t)
+	ps.EXPECT().List(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, *model.Pagination) (iterator.Iterator[*api_project.Project], int64, error) {
+		return iterator.FromList([]*api_project.Project{{ProjectId: projectID.String()}}), 1, nil
+	})
+
+	ccg := cluster.NewMockConfigGateway(t)
+	ccg.EXPECT().ListCapsuleConfigs(mock.Anything, mock.Anything).RunAndReturn(func(context.Context, *model.Pagination) (iterator.Iterator[*v1alpha1.Capsule], int64, error) {
+		return iterator.FromList([]*v1alpha1.Capsule{{ObjectMeta: v1.ObjectMeta{Name: capsuleID}}}), 1, nil
+	})
+
+	var instances []*capsule.Instance
+	cg := cluster.NewMockGateway(t)
+	cg.EXPECT().ListInstances(mock.Anything, capsuleID).RunAndReturn(func(context.Context, string) (iterator.Iterator[*capsule.Instance], uint64, error) {
+		return iterator.FromList(instances), uint64(len(instances)), nil
+	})
+
+	ws, events := testWebhooks(t)
+	s := &Service{
+		cg:     cg,
+		ccg:    ccg,
+		ps:     ps,
+		ws:     ws,
+		cl:     newCrashLoops(),
+		logger: zaptest.NewLogger(t),
+	}
+
+	watch := func(restarts ...uint32) {
+		instances = nil
+		for _, r := range restarts {
+			instances = append(instances, &capsule.Instance{
+				InstanceId:   "instance-0",
+				State:        capsule.State_STATE_FAILED,
+				RestartCount: r,
+			})
+		}
+		require.NoError(t, s.watchInstances(context.Background()))
+	}
+
+	// The instance is reported once while crash looping, after the rollout is
+	// done.
+	watch(3)
+	watch(4)
+	require.Len(t, *events, 1)
+	require.Equal(t, api_webhook.EventType_EVENT_TYPE_INSTANCE_CRASH_LOOP, (*events)[0].GetType())
+	require.Equal(t, capsuleID, (*events)[0].GetCapsuleId())
+	require.Equal(t, "instance-0", (*events)[0].GetInstanceId())
+
+	// An instance replacing it with the same ID is reported again.
+	watch(1)
+	require.Len(t, *events, 2)
+
+	// So is an instance coming back after being gone.
+	watch()
+	watch(5)
+	require.Len(t, *events, 3)
+}
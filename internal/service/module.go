@@ -11,6 +11,7 @@ import (
 	"github.com/rigdev/rig/internal/service/project"
 	"github.com/rigdev/rig/internal/service/storage"
 	"github.com/rigdev/rig/internal/service/user"
+	"github.com/rigdev/rig/internal/service/webhook"
 	"go.uber.org/fx"
 )
 
@@ -27,5 +28,6 @@ var Module = fx.Module(
 		metrics.NewService,
 		operator.New,
 		cluster.NewService,
+		webhook.NewService,
 	),
 )
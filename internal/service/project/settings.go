@@ -6,6 +6,7 @@ import (
 
 	project_settings "github.com/rigdev/rig-go-api/api/v1/project/settings"
 	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/utils"
 	"github.com/rigdev/rig/pkg/uuid"
 	"google.golang.org/protobuf/proto"
 )
@@ -155,6 +156,13 @@ func (s *service) applySettingsUpdates(ctx context.Context, set *project_setting
 			if err := s.applyDeleteDockerRegistry(ctx, set, v); err != nil {
 				return err
 			}
+		case *project_settings.Update_SetDeployWindows:
+			for _, w := range v.SetDeployWindows.GetWindows() {
+				if err := utils.ValidateDeployWindow(w); err != nil {
+					return err
+				}
+			}
+			set.DeployWindows = v.SetDeployWindows.GetWindows()
 		}
 	}
 	return nil
@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *Service) Create(ctx context.Context, initializers []*webhook.Update) (*webhook.Webhook, error) {
+	webhookID := uuid.New()
+	w := &webhook.Webhook{
+		WebhookId: webhookID.String(),
+		CreatedAt: timestamppb.Now(),
+	}
+
+	secret, err := applyUpdates(w, initializers)
+	if err != nil {
+		return nil, err
+	}
+
+	if w.GetUrl() == "" {
+		return nil, errors.InvalidArgumentErrorf("missing required webhook url")
+	}
+
+	if secret == "" {
+		return nil, errors.InvalidArgumentErrorf("missing required webhook secret")
+	}
+
+	// The signing secret is stored encrypted, by the ID of the webhook.
+	if err := s.sr.Create(ctx, webhookID, []byte(secret)); err != nil {
+		return nil, err
+	}
+
+	if err := s.wr.Create(ctx, w); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (s *Service) Delete(ctx context.Context, webhookID uuid.UUID) error {
+	if err := s.wr.Delete(ctx, webhookID); err != nil {
+		return err
+	}
+
+	if err := s.sr.Delete(ctx, webhookID); errors.IsNotFound(err) {
+	} else if err != nil {
+		return err
+	}
+
+	return nil
+}
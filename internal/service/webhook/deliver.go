@@ -0,0 +1,184 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	SignatureHeader = "X-Rig-Signature"
+	TimestampHeader = "X-Rig-Timestamp"
+	EventHeader     = "X-Rig-Event"
+	DeliveryHeader  = "X-Rig-Delivery"
+)
+
+// run starts the delivery workers, so a slow webhook only holds up one of
+// them.
+func (s *Service) run(ctx context.Context) {
+	for i := 0; i < deliveryWorkers; i++ {
+		go s.work(ctx)
+	}
+}
+
+func (s *Service) work(ctx context.Context) {
+	t := time.NewTicker(deliveryPollInterval)
+	defer t.Stop()
+
+	for {
+		for s.deliverNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.c:
+		case <-t.C:
+		}
+	}
+}
+
+// deliverNext attempts the next pending delivery that is due, and returns
+// false if there is none.
+func (s *Service) deliverNext(ctx context.Context) bool {
+	now := time.Now()
+	projectID, d, err := s.wr.ClaimDelivery(ctx, now, now.Add(2*deliveryTimeout))
+	if errors.IsNotFound(err) {
+		return false
+	} else if err != nil {
+		s.logger.Warn("error claiming webhook delivery", zap.Error(err))
+		return false
+	}
+
+	// Wake another worker, in case more deliveries are due.
+	s.wake()
+
+	if err := s.deliver(auth.WithProjectID(ctx, projectID), d); err != nil {
+		s.logger.Warn("error delivering webhook", zap.String("delivery_id", d.GetDeliveryId()), zap.Error(err))
+	}
+
+	return true
+}
+
+// wake signals an idle worker to look for due deliveries.
+func (s *Service) wake() {
+	select {
+	case s.c <- struct{}{}:
+	default:
+	}
+}
+
+// deliver makes an attempt of the delivery and stores the outcome. Failed
+// attempts are retried with exponential backoff, until the maximum number of
+// attempts is reached.
+func (s *Service) deliver(ctx context.Context, d *webhook.Delivery) error {
+	webhookID := uuid.UUID(d.GetWebhookId())
+	w, err := s.wr.Get(ctx, webhookID)
+	if errors.IsNotFound(err) {
+		// The webhook was deleted along with its deliveries.
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Failing to get the secret counts as a failed attempt, so the delivery
+	// isn't retried without backoff.
+	var code int
+	secret, err := s.sr.Get(ctx, webhookID)
+	if err != nil {
+		err = fmt.Errorf("could not get webhook secret: %w", err)
+	} else {
+		code, err = s.post(ctx, w.GetUrl(), secret, d)
+	}
+
+	now := time.Now()
+	d.Attempts++
+	d.LastAttemptAt = timestamppb.New(now)
+	d.StatusCode = int32(code)
+	d.Error = ""
+	d.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		d.State = webhook.DeliveryState_DELIVERY_STATE_DELIVERED
+	case d.GetAttempts() >= s.maxAttempts:
+		d.State = webhook.DeliveryState_DELIVERY_STATE_FAILED
+		d.Error = err.Error()
+	default:
+		d.Error = err.Error()
+		d.NextAttemptAt = timestamppb.New(now.Add(s.backoff(d.GetAttempts())))
+	}
+
+	return s.wr.UpdateDelivery(ctx, d)
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (s *Service) backoff(attempts uint32) time.Duration {
+	b := s.minBackoff
+	for i := uint32(1); i < attempts && b < s.maxBackoff; i++ {
+		b *= 2
+	}
+
+	if b > s.maxBackoff {
+		return s.maxBackoff
+	}
+	return b
+}
+
+// post sends the event of the delivery, signed with the secret, and returns
+// the status code of the response.
+func (s *Service) post(ctx context.Context, url string, secret []byte, d *webhook.Delivery) (int, error) {
+	body, err := protojson.Marshal(d.GetEvent())
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "rig-webhook")
+	req.Header.Set(EventHeader, d.GetEvent().GetType().String())
+	req.Header.Set(DeliveryHeader, d.GetDeliveryId())
+	timestamp := time.Now().Unix()
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(secret, timestamp, body))
+
+	res, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// Sign returns the signature of a webhook request, as set in the
+// X-Rig-Signature header. The unix timestamp of the X-Rig-Timestamp header is
+// signed along with the body, as "<timestamp>.<body>", so receivers can reject
+// replayed requests.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
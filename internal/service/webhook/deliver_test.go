@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+func newTestDelivery(webhookID uuid.UUID) *webhook.Delivery {
+	return &webhook.Delivery{
+		DeliveryId: uuid.New().String(),
+		WebhookId:  webhookID.String(),
+		Event: &webhook.Event{
+			Type:      webhook.EventType_EVENT_TYPE_ROLLOUT_FAILED,
+			CapsuleId: "my-capsule",
+			RolloutId: 3,
+			Message:   "rollout failed",
+		},
+		State: webhook.DeliveryState_DELIVERY_STATE_PENDING,
+	}
+}
+
+func Test_Deliver_Signed(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	webhookID := uuid.New()
+	d := newTestDelivery(webhookID)
+
+	var received *webhook.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		require.WithinDuration(t, time.Now(), time.Unix(timestamp, 0), time.Minute)
+		require.Equal(t, Sign([]byte("secret"), timestamp, body), r.Header.Get(SignatureHeader))
+		require.NotEqual(t, Sign([]byte("secret"), timestamp+1, body), r.Header.Get(SignatureHeader))
+		require.Equal(t, "EVENT_TYPE_ROLLOUT_FAILED", r.Header.Get(EventHeader))
+		require.Equal(t, d.GetDeliveryId(), r.Header.Get(DeliveryHeader))
+
+		received = &webhook.Event{}
+		require.NoError(t, protojson.Unmarshal(body, received))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	wr := repository.NewMockWebhook(t)
+	wr.EXPECT().Get(mock.Anything, webhookID).Return(&webhook.Webhook{WebhookId: webhookID.String(), Url: srv.URL}, nil)
+	wr.EXPECT().UpdateDelivery(mock.Anything, d).Return(nil)
+
+	sr := repository.NewMockSecret(t)
+	sr.EXPECT().Get(mock.Anything, webhookID).Return([]byte("secret"), nil)
+
+	s := newService(wr, sr, zaptest.NewLogger(t))
+	require.NoError(t, s.deliver(ctx, d))
+
+	require.Equal(t, "rollout failed", received.GetMessage())
+	require.Equal(t, webhook.DeliveryState_DELIVERY_STATE_DELIVERED, d.GetState())
+	require.Equal(t, uint32(1), d.GetAttempts())
+	require.Equal(t, int32(http.StatusNoContent), d.GetStatusCode())
+	require.Nil(t, d.GetNextAttemptAt())
+}
+
+func Test_Deliver_Retry(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	webhookID := uuid.New()
+	d := newTestDelivery(webhookID)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	wr := repository.NewMockWebhook(t)
+	wr.EXPECT().Get(mock.Anything, webhookID).Return(&webhook.Webhook{WebhookId: webhookID.String(), Url: srv.URL}, nil)
+	wr.EXPECT().UpdateDelivery(mock.Anything, d).Return(nil)
+
+	sr := repository.NewMockSecret(t)
+	sr.EXPECT().Get(mock.Anything, webhookID).Return([]byte("secret"), nil)
+
+	s := newService(wr, sr, zaptest.NewLogger(t))
+	s.maxAttempts = 2
+
+	// The first failure is retried after the minimum backoff.
+	require.NoError(t, s.deliver(ctx, d))
+	require.Equal(t, webhook.DeliveryState_DELIVERY_STATE_PENDING, d.GetState())
+	require.Equal(t, int32(http.StatusBadGateway), d.GetStatusCode())
+	require.NotEmpty(t, d.GetError())
+	require.WithinDuration(t, time.Now().Add(s.minBackoff), d.GetNextAttemptAt().AsTime(), time.Second)
+
+	// The last attempt fails the delivery.
+	require.NoError(t, s.deliver(ctx, d))
+	require.Equal(t, webhook.DeliveryState_DELIVERY_STATE_FAILED, d.GetState())
+	require.Equal(t, uint32(2), d.GetAttempts())
+	require.Nil(t, d.GetNextAttemptAt())
+}
+
+func Test_Deliver_SecretError(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	webhookID := uuid.New()
+	d := newTestDelivery(webhookID)
+
+	wr := repository.NewMockWebhook(t)
+	wr.EXPECT().Get(mock.Anything, webhookID).Return(&webhook.Webhook{WebhookId: webhookID.String(), Url: "http://localhost"}, nil)
+	wr.EXPECT().UpdateDelivery(mock.Anything, d).Return(nil)
+
+	sr := repository.NewMockSecret(t)
+	sr.EXPECT().Get(mock.Anything, webhookID).Return(nil, errors.UnavailableErrorf("database unavailable"))
+
+	s := newService(wr, sr, zaptest.NewLogger(t))
+	require.NoError(t, s.deliver(ctx, d))
+
+	require.Equal(t, webhook.DeliveryState_DELIVERY_STATE_PENDING, d.GetState())
+	require.Equal(t, uint32(1), d.GetAttempts())
+	require.Contains(t, d.GetError(), "database unavailable")
+	require.WithinDuration(t, time.Now().Add(s.minBackoff), d.GetNextAttemptAt().AsTime(), time.Second)
+}
+
+func Test_Deliver_WebhookDeleted(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+	webhookID := uuid.New()
+
+	wr := repository.NewMockWebhook(t)
+	wr.EXPECT().Get(mock.Anything, webhookID).Return(nil, errors.NotFoundErrorf("webhook not found"))
+
+	s := newService(wr, repository.NewMockSecret(t), zaptest.NewLogger(t))
+	require.NoError(t, s.deliver(ctx, newTestDelivery(webhookID)))
+}
+
+func Test_Backoff(t *testing.T) {
+	s := &Service{
+		minBackoff: 10 * time.Second,
+		maxBackoff: time.Minute,
+	}
+
+	tests := []struct {
+		attempts uint32
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 10 * time.Second},
+		{attempts: 2, expected: 20 * time.Second},
+		{attempts: 3, expected: 40 * time.Second},
+		{attempts: 4, expected: time.Minute},
+		{attempts: 10, expected: time.Minute},
+	}
+
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, s.backoff(tt.attempts), "attempts: %d", tt.attempts)
+	}
+}
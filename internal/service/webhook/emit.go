@@ -0,0 +1,69 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Emit creates a delivery of the event for each webhook of the project that
+// matches it. The deliveries are sent in the background.
+func (s *Service) Emit(ctx context.Context, e *webhook.Event) error {
+	projectID, err := auth.GetProjectID(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.EventId = uuid.New().String()
+	e.ProjectId = projectID.String()
+	if e.GetCreatedAt() == nil {
+		e.CreatedAt = timestamppb.Now()
+	}
+
+	created := false
+	for offset := uint32(0); ; {
+		it, total, err := s.wr.List(ctx, &model.Pagination{Offset: offset})
+		if err != nil {
+			return err
+		}
+
+		ws, err := iterator.Collect(it)
+		if err != nil {
+			return err
+		}
+
+		for _, w := range ws {
+			if !matches(w, e) {
+				continue
+			}
+
+			if err := s.wr.CreateDelivery(ctx, &webhook.Delivery{
+				DeliveryId:    uuid.New().String(),
+				WebhookId:     w.GetWebhookId(),
+				Event:         e,
+				State:         webhook.DeliveryState_DELIVERY_STATE_PENDING,
+				CreatedAt:     e.GetCreatedAt(),
+				NextAttemptAt: e.GetCreatedAt(),
+			}); err != nil {
+				return err
+			}
+			created = true
+		}
+
+		offset += uint32(len(ws))
+		if len(ws) == 0 || uint64(offset) >= total {
+			break
+		}
+	}
+
+	if created {
+		s.wake()
+	}
+
+	return nil
+}
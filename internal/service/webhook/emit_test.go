@@ -0,0 +1,52 @@
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/auth"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func Test_Emit_Filter(t *testing.T) {
+	ctx := auth.WithProjectID(context.Background(), uuid.New())
+
+	all := &webhook.Webhook{WebhookId: uuid.New().String()}
+	failed := &webhook.Webhook{WebhookId: uuid.New().String(), Filter: &webhook.Filter{
+		Events: []webhook.EventType{webhook.EventType_EVENT_TYPE_ROLLOUT_FAILED},
+	}}
+	done := &webhook.Webhook{WebhookId: uuid.New().String(), Filter: &webhook.Filter{
+		Events: []webhook.EventType{webhook.EventType_EVENT_TYPE_ROLLOUT_DONE},
+	}}
+	otherCapsule := &webhook.Webhook{WebhookId: uuid.New().String(), Filter: &webhook.Filter{
+		CapsuleIds: []string{"other-capsule"},
+	}}
+
+	wr := repository.NewMockWebhook(t)
+	wr.EXPECT().List(mock.Anything, mock.Anything).Return(iterator.FromList([]*webhook.Webhook{all, failed, done, otherCapsule}), 4, nil)
+
+	var webhookIDs []string
+	wr.EXPECT().CreateDelivery(mock.Anything, mock.Anything).RunAndReturn(func(ctx context.Context, d *webhook.Delivery) error {
+		require.Equal(t, webhook.DeliveryState_DELIVERY_STATE_PENDING, d.GetState())
+		require.NotNil(t, d.GetNextAttemptAt())
+		webhookIDs = append(webhookIDs, d.GetWebhookId())
+		return nil
+	})
+
+	s := newService(wr, repository.NewMockSecret(t), zaptest.NewLogger(t))
+	require.NoError(t, s.Emit(ctx, &webhook.Event{
+		Type:      webhook.EventType_EVENT_TYPE_ROLLOUT_FAILED,
+		CapsuleId: "my-capsule",
+	}))
+
+	require.Equal(t, []string{all.GetWebhookId(), failed.GetWebhookId()}, webhookIDs)
+
+	// The deliverer is woken up.
+	require.Len(t, s.c, 1)
+}
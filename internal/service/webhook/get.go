@@ -0,0 +1,12 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (s *Service) Get(ctx context.Context, webhookID uuid.UUID) (*webhook.Webhook, error) {
+	return s.wr.Get(ctx, webhookID)
+}
@@ -0,0 +1,13 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/pkg/iterator"
+)
+
+func (s *Service) List(ctx context.Context, pagination *model.Pagination) (iterator.Iterator[*webhook.Webhook], uint64, error) {
+	return s.wr.List(ctx, pagination)
+}
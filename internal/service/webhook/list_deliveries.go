@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig-go-api/model"
+	"github.com/rigdev/rig/pkg/iterator"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (s *Service) ListDeliveries(ctx context.Context, webhookID uuid.UUID, pagination *model.Pagination) (iterator.Iterator[*webhook.Delivery], uint64, error) {
+	if _, err := s.wr.Get(ctx, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	return s.wr.ListDeliveries(ctx, webhookID, pagination)
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/internal/repository"
+	"github.com/rigdev/rig/pkg/errors"
+	"go.uber.org/zap"
+)
+
+const (
+	// deliveryTimeout is the timeout of a single delivery attempt.
+	deliveryTimeout = 10 * time.Second
+	// deliveryPollInterval is how often pending deliveries are looked up,
+	// to pick up retries and deliveries created by other servers.
+	deliveryPollInterval = 5 * time.Second
+	// deliveryWorkers is the number of deliveries attempted concurrently.
+	deliveryWorkers = 8
+
+	defaultMinBackoff  = 10 * time.Second
+	defaultMaxBackoff  = time.Hour
+	defaultMaxAttempts = 8
+)
+
+type Service struct {
+	logger *zap.Logger
+	wr     repository.Webhook
+	sr     repository.Secret
+	client *http.Client
+
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	maxAttempts uint32
+
+	c chan struct{}
+}
+
+func NewService(wr repository.Webhook, sr repository.Secret, logger *zap.Logger) *Service {
+	s := newService(wr, sr, logger)
+	go s.run(context.Background())
+	return s
+}
+
+func newService(wr repository.Webhook, sr repository.Secret, logger *zap.Logger) *Service {
+	return &Service{
+		logger:      logger,
+		wr:          wr,
+		sr:          sr,
+		client:      &http.Client{Timeout: deliveryTimeout},
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		maxAttempts: defaultMaxAttempts,
+		c:           make(chan struct{}, 1),
+	}
+}
+
+// applyUpdates applies the updates to the webhook, and returns the new
+// signing secret if it is updated.
+func applyUpdates(w *webhook.Webhook, us []*webhook.Update) (string, error) {
+	var secret string
+	for _, u := range us {
+		switch v := u.GetField().(type) {
+		case *webhook.Update_Url:
+			pu, err := url.Parse(v.Url)
+			if err != nil {
+				return "", errors.InvalidArgumentErrorf("invalid webhook url: %v", err)
+			}
+			if pu.Scheme != "http" && pu.Scheme != "https" {
+				return "", errors.InvalidArgumentErrorf("webhook url must be http or https")
+			}
+			w.Url = v.Url
+		case *webhook.Update_Filter:
+			w.Filter = v.Filter
+		case *webhook.Update_Secret:
+			if v.Secret == "" {
+				return "", errors.InvalidArgumentErrorf("webhook secret must not be empty")
+			}
+			secret = v.Secret
+		default:
+			return "", errors.InvalidArgumentErrorf("invalid webhook update type '%v'", reflect.TypeOf(u.GetField()))
+		}
+	}
+
+	return secret, nil
+}
+
+// matches returns true if the event passes the filter of the webhook.
+func matches(w *webhook.Webhook, e *webhook.Event) bool {
+	if es := w.GetFilter().GetEvents(); len(es) > 0 && !contains(es, e.GetType()) {
+		return false
+	}
+
+	if cs := w.GetFilter().GetCapsuleIds(); len(cs) > 0 && !contains(cs, e.GetCapsuleId()) {
+		return false
+	}
+
+	return true
+}
+
+func contains[T comparable](ts []T, t T) bool {
+	for _, v := range ts {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
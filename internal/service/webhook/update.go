@@ -0,0 +1,28 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/webhook"
+	"github.com/rigdev/rig/pkg/uuid"
+)
+
+func (s *Service) Update(ctx context.Context, webhookID uuid.UUID, updates []*webhook.Update) error {
+	w, err := s.wr.Get(ctx, webhookID)
+	if err != nil {
+		return err
+	}
+
+	secret, err := applyUpdates(w, updates)
+	if err != nil {
+		return err
+	}
+
+	if secret != "" {
+		if err := s.sr.Update(ctx, webhookID, []byte(secret)); err != nil {
+			return err
+		}
+	}
+
+	return s.wr.Update(ctx, w)
+}
@@ -1,7 +1,9 @@
 package v1alpha1
 
 import (
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -15,6 +17,10 @@ type CapsuleSpec struct {
 	Files           []File                   `json:"files,omitempty"`
 	Resources       *v1.ResourceRequirements `json:"resources,omitempty"`
 	ImagePullSecret *v1.LocalObjectReference `json:"imagePullSecret,omitempty"`
+	Probes          *CapsuleProbes           `json:"probes,omitempty"`
+	Autoscaler      *CapsuleAutoscaler       `json:"autoscaler,omitempty"`
+	CronJob         *CapsuleCronJob          `json:"cronJob,omitempty"`
+	Volumes         []CapsuleVolume          `json:"volumes,omitempty"`
 }
 
 // CapsuleInterface defines an interface for a capsule
@@ -34,6 +40,19 @@ type CapsulePublicInterface struct {
 // ingress
 type CapsuleInterfaceIngress struct {
 	Host string `json:"host"`
+	// PathPrefix is the prefix of the paths routed to the interface, so
+	// several capsules can share a host. Defaults to /
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// TLS enables TLS for the host
+	TLS *CapsuleInterfaceIngressTLS `json:"tls,omitempty"`
+}
+
+// CapsuleInterfaceIngressTLS defines the certificate of an ingress
+type CapsuleInterfaceIngressTLS struct {
+	// SecretName is the name of the kubernetes.io/tls secret holding the
+	// certificate. If empty, the certificate is issued by cert-manager if
+	// configured
+	SecretName string `json:"secretName,omitempty"`
 }
 
 // CapsuleInterfaceLoadBalancer defines that the interface should be exposed as
@@ -56,8 +75,143 @@ type FileContentRef struct {
 	Key  string `json:"key"`
 }
 
+// CapsuleProbes defines the health checks of the capsule instances
+type CapsuleProbes struct {
+	Liveness  *CapsuleProbe `json:"liveness,omitempty"`
+	Readiness *CapsuleProbe `json:"readiness,omitempty"`
+	Startup   *CapsuleProbe `json:"startup,omitempty"`
+}
+
+// CapsuleProbe defines a health check of the capsule instances. Exactly one of
+// HTTP, TCP, GRPC and Exec must be set
+type CapsuleProbe struct {
+	HTTP                *HTTPProbe `json:"http,omitempty"`
+	TCP                 *TCPProbe  `json:"tcp,omitempty"`
+	GRPC                *GRPCProbe `json:"grpc,omitempty"`
+	Exec                *ExecProbe `json:"exec,omitempty"`
+	InitialDelaySeconds int32      `json:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int32      `json:"periodSeconds,omitempty"`
+	TimeoutSeconds      int32      `json:"timeoutSeconds,omitempty"`
+	FailureThreshold    int32      `json:"failureThreshold,omitempty"`
+}
+
+// HTTPProbe defines a probe which sends a GET request to the path on the port
+type HTTPProbe struct {
+	Port int32  `json:"port"`
+	Path string `json:"path,omitempty"`
+}
+
+// TCPProbe defines a probe which opens a TCP connection to the port
+type TCPProbe struct {
+	Port int32 `json:"port"`
+}
+
+// GRPCProbe defines a probe which calls the gRPC health check of the service
+// on the port
+type GRPCProbe struct {
+	Port    int32  `json:"port"`
+	Service string `json:"service,omitempty"`
+}
+
+// ExecProbe defines a probe which runs the command in the container
+type ExecProbe struct {
+	Command []string `json:"command"`
+}
+
+// CapsuleAutoscaler defines how to scale the number of capsule instances. When
+// set, Replicas is ignored
+type CapsuleAutoscaler struct {
+	MinReplicas int32 `json:"minReplicas"`
+	MaxReplicas int32 `json:"maxReplicas"`
+	// CPUUtilization is the target average CPU utilization in percent of the
+	// requested CPU
+	CPUUtilization int32 `json:"cpuUtilization,omitempty"`
+	// MemoryUtilization is the target average memory utilization in percent of
+	// the requested memory
+	MemoryUtilization int32 `json:"memoryUtilization,omitempty"`
+}
+
+// CapsuleCronJob defines that the capsule runs to completion on a schedule,
+// instead of as long-running instances. When set, Replicas, Autoscaler and
+// Interfaces are ignored
+type CapsuleCronJob struct {
+	// Schedule is a cron expression in UTC
+	Schedule          string                    `json:"schedule"`
+	ConcurrencyPolicy batchv1.ConcurrencyPolicy `json:"concurrencyPolicy,omitempty"`
+	// HistoryLimit is the number of finished runs to keep
+	HistoryLimit int32 `json:"historyLimit,omitempty"`
+	// TimeoutSeconds is how long a run may take before it is stopped
+	TimeoutSeconds int64 `json:"timeoutSeconds,omitempty"`
+}
+
+// CapsuleVolume defines a persistent volume mounted into each instance of the
+// capsule. Capsules with volumes run as a StatefulSet, and the volumes are kept
+// when instances or the capsule are removed
+type CapsuleVolume struct {
+	// Name is unique within the capsule and is a DNS label
+	Name string `json:"name"`
+	// Path is where the volume is mounted in the container
+	Path string `json:"path"`
+	// Size is the requested size of the volume. It cannot be changed
+	Size resource.Quantity `json:"size"`
+	// StorageClassName is the storage class of the volume. The default storage
+	// class is used if empty. It cannot be changed
+	StorageClassName string `json:"storageClassName,omitempty"`
+}
+
 // CapsuleStatus defines the observed state of Capsule
-type CapsuleStatus struct{}
+type CapsuleStatus struct {
+	// ObservedGeneration is the generation of the capsule the status was
+	// computed from
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the Ready, Progressing and Degraded conditions of the
+	// capsule
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// Image is the image currently run by the capsule instances
+	Image string `json:"image,omitempty"`
+	// Replicas is the desired number of instances
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of instances which are ready
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Interfaces are the public addresses of the interfaces
+	Interfaces []CapsuleInterfaceStatus `json:"interfaces,omitempty"`
+	// Instances are the observed instances of the capsule
+	Instances []CapsuleInstanceStatus `json:"instances,omitempty"`
+}
+
+// CapsuleInterfaceStatus defines the observed public addresses of an
+// interface
+type CapsuleInterfaceStatus struct {
+	Name string `json:"name"`
+	// Addresses are the hostnames or IPs of the ingress or load balancer of
+	// the interface
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// CapsuleInstanceStatus defines the observed state of a capsule instance
+type CapsuleInstanceStatus struct {
+	Name         string       `json:"name"`
+	Image        string       `json:"image"`
+	Phase        v1.PodPhase  `json:"phase,omitempty"`
+	Ready        bool         `json:"ready"`
+	CrashLooping bool         `json:"crashLooping,omitempty"`
+	RestartCount int32        `json:"restartCount"`
+	CreatedAt    metav1.Time  `json:"createdAt"`
+	StartedAt    *metav1.Time `json:"startedAt,omitempty"`
+	FinishedAt   *metav1.Time `json:"finishedAt,omitempty"`
+}
+
+// The condition types of a capsule.
+const (
+	// CapsuleConditionReady is true when all desired instances are ready.
+	CapsuleConditionReady = "Ready"
+	// CapsuleConditionProgressing is true while instances are being replaced
+	// or scaled.
+	CapsuleConditionProgressing = "Progressing"
+	// CapsuleConditionDegraded is true when instances are crash looping or
+	// the workload could not be reconciled.
+	CapsuleConditionDegraded = "Degraded"
+)
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
@@ -1,7 +1,13 @@
 package v1alpha1
 
 import (
+	"path"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+	batchv1 "k8s.io/api/batch/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
@@ -40,7 +46,16 @@ func (r *Capsule) ValidateCreate() (admission.Warnings, error) {
 // ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
 func (r *Capsule) ValidateUpdate(old runtime.Object) (admission.Warnings, error) {
 	capsulelog.Info("validate update", "name", r.Name)
-	return r.validate()
+	warns, err := r.validate()
+	if err != nil {
+		return warns, err
+	}
+
+	if oc, ok := old.(*Capsule); ok {
+		return warns, r.validateVolumesUpdate(oc).ToAggregate()
+	}
+
+	return warns, nil
 }
 
 // ValidateDelete implements webhook.Validator so a webhook will be registered for the type
@@ -64,6 +79,22 @@ func (r *Capsule) validate() (admission.Warnings, error) {
 	allWarns = append(allWarns, warns...)
 	allErrs = append(allErrs, errs...)
 
+	warns, errs = r.validateProbes()
+	allWarns = append(allWarns, warns...)
+	allErrs = append(allErrs, errs...)
+
+	warns, errs = r.validateAutoscaler()
+	allWarns = append(allWarns, warns...)
+	allErrs = append(allErrs, errs...)
+
+	warns, errs = r.validateCronJob()
+	allWarns = append(allWarns, warns...)
+	allErrs = append(allErrs, errs...)
+
+	warns, errs = r.validateVolumes()
+	allWarns = append(allWarns, warns...)
+	allErrs = append(allErrs, errs...)
+
 	return allWarns, allErrs.ToAggregate()
 }
 
@@ -101,6 +132,13 @@ func (r *Capsule) validateInterfaces() (admission.Warnings, field.ErrorList) {
 			if public.Ingress != nil && public.LoadBalancer != nil {
 				errs = append(errs, field.Invalid(publicPath, public, "ingress and loadBalancer are mutually exclusive"))
 			}
+			if public.Ingress != nil && public.Ingress.PathPrefix != "" && !strings.HasPrefix(public.Ingress.PathPrefix, "/") {
+				errs = append(errs, field.Invalid(
+					publicPath.Child("ingress").Child("pathPrefix"),
+					public.Ingress.PathPrefix,
+					"pathPrefix must be an absolute path",
+				))
+			}
 		}
 	}
 
@@ -131,3 +169,190 @@ func (r *Capsule) validateFiles() (admission.Warnings, field.ErrorList) {
 
 	return nil, errs
 }
+
+func (r *Capsule) validateProbes() (admission.Warnings, field.ErrorList) {
+	if r.Spec.Probes == nil {
+		return nil, nil
+	}
+
+	var errs field.ErrorList
+
+	probesPath := field.NewPath("spec").Child("probes")
+	errs = append(errs, validateProbe(probesPath.Child("liveness"), r.Spec.Probes.Liveness)...)
+	errs = append(errs, validateProbe(probesPath.Child("readiness"), r.Spec.Probes.Readiness)...)
+	errs = append(errs, validateProbe(probesPath.Child("startup"), r.Spec.Probes.Startup)...)
+
+	return nil, errs
+}
+
+func validateProbe(pPath *field.Path, p *CapsuleProbe) field.ErrorList {
+	if p == nil {
+		return nil
+	}
+
+	var errs field.ErrorList
+
+	kinds := 0
+	if p.HTTP != nil {
+		kinds++
+		if p.HTTP.Port <= 0 {
+			errs = append(errs, field.Invalid(pPath.Child("http").Child("port"), p.HTTP.Port, "port must be positive"))
+		}
+	}
+	if p.TCP != nil {
+		kinds++
+		if p.TCP.Port <= 0 {
+			errs = append(errs, field.Invalid(pPath.Child("tcp").Child("port"), p.TCP.Port, "port must be positive"))
+		}
+	}
+	if p.GRPC != nil {
+		kinds++
+		if p.GRPC.Port <= 0 {
+			errs = append(errs, field.Invalid(pPath.Child("grpc").Child("port"), p.GRPC.Port, "port must be positive"))
+		}
+	}
+	if p.Exec != nil {
+		kinds++
+		if len(p.Exec.Command) == 0 {
+			errs = append(errs, field.Required(pPath.Child("exec").Child("command"), "command is required"))
+		}
+	}
+
+	if kinds == 0 {
+		errs = append(errs, field.Required(pPath, "one of http, tcp, grpc or exec is required"))
+	}
+	if kinds > 1 {
+		errs = append(errs, field.Invalid(pPath, p, "http, tcp, grpc and exec are mutually exclusive"))
+	}
+
+	return errs
+}
+
+func (r *Capsule) validateAutoscaler() (admission.Warnings, field.ErrorList) {
+	a := r.Spec.Autoscaler
+	if a == nil {
+		return nil, nil
+	}
+
+	var errs field.ErrorList
+
+	aPath := field.NewPath("spec").Child("autoscaler")
+	if a.MinReplicas < 1 {
+		errs = append(errs, field.Invalid(aPath.Child("minReplicas"), a.MinReplicas, "minReplicas must be at least 1"))
+	}
+	if a.MaxReplicas < a.MinReplicas {
+		errs = append(errs, field.Invalid(aPath.Child("maxReplicas"), a.MaxReplicas, "maxReplicas cannot be less than minReplicas"))
+	}
+	if a.CPUUtilization < 0 {
+		errs = append(errs, field.Invalid(aPath.Child("cpuUtilization"), a.CPUUtilization, "cpuUtilization cannot be negative"))
+	}
+	if a.MemoryUtilization < 0 {
+		errs = append(errs, field.Invalid(aPath.Child("memoryUtilization"), a.MemoryUtilization, "memoryUtilization cannot be negative"))
+	}
+	if a.CPUUtilization == 0 && a.MemoryUtilization == 0 {
+		errs = append(errs, field.Required(aPath, "one of cpuUtilization or memoryUtilization is required"))
+	}
+
+	return nil, errs
+}
+
+func (r *Capsule) validateCronJob() (admission.Warnings, field.ErrorList) {
+	cj := r.Spec.CronJob
+	if cj == nil {
+		return nil, nil
+	}
+
+	var errs field.ErrorList
+
+	cjPath := field.NewPath("spec").Child("cronJob")
+	if cj.Schedule == "" {
+		errs = append(errs, field.Required(cjPath.Child("schedule"), "schedule is required"))
+	} else if _, err := cron.ParseStandard(cj.Schedule); err != nil {
+		errs = append(errs, field.Invalid(cjPath.Child("schedule"), cj.Schedule, err.Error()))
+	}
+
+	switch cj.ConcurrencyPolicy {
+	case "", batchv1.AllowConcurrent, batchv1.ForbidConcurrent, batchv1.ReplaceConcurrent:
+	default:
+		errs = append(errs, field.NotSupported(cjPath.Child("concurrencyPolicy"), cj.ConcurrencyPolicy, []string{
+			string(batchv1.AllowConcurrent), string(batchv1.ForbidConcurrent), string(batchv1.ReplaceConcurrent),
+		}))
+	}
+
+	if cj.HistoryLimit < 0 {
+		errs = append(errs, field.Invalid(cjPath.Child("historyLimit"), cj.HistoryLimit, "historyLimit cannot be negative"))
+	}
+	if cj.TimeoutSeconds < 0 {
+		errs = append(errs, field.Invalid(cjPath.Child("timeoutSeconds"), cj.TimeoutSeconds, "timeoutSeconds cannot be negative"))
+	}
+
+	return nil, errs
+}
+
+func (r *Capsule) validateVolumes() (admission.Warnings, field.ErrorList) {
+	if len(r.Spec.Volumes) == 0 {
+		return nil, nil
+	}
+
+	var errs field.ErrorList
+
+	volsPath := field.NewPath("spec").Child("volumes")
+	if r.Spec.CronJob != nil {
+		errs = append(errs, field.Forbidden(volsPath, "volumes cannot be used with a cron job"))
+	}
+
+	names := map[string]struct{}{}
+	paths := map[string]struct{}{}
+	for i, v := range r.Spec.Volumes {
+		vPath := volsPath.Index(i)
+
+		if _, ok := names[v.Name]; ok {
+			errs = append(errs, field.Duplicate(vPath.Child("name"), v.Name))
+		} else {
+			names[v.Name] = struct{}{}
+		}
+		for _, msg := range validation.IsDNS1123Label(v.Name) {
+			errs = append(errs, field.Invalid(vPath.Child("name"), v.Name, msg))
+		}
+
+		if _, ok := paths[v.Path]; ok {
+			errs = append(errs, field.Duplicate(vPath.Child("path"), v.Path))
+		} else {
+			paths[v.Path] = struct{}{}
+		}
+		if !path.IsAbs(v.Path) || path.Clean(v.Path) == "/" {
+			errs = append(errs, field.Invalid(vPath.Child("path"), v.Path, "path must be an absolute path other than /"))
+		}
+
+		if v.Size.Sign() <= 0 {
+			errs = append(errs, field.Invalid(vPath.Child("size"), v.Size.String(), "size must be positive"))
+		}
+	}
+
+	return nil, errs
+}
+
+// validateVolumesUpdate checks that the size and storage class of the volumes
+// kept from old are unchanged, as the existing volumes are not resized.
+func (r *Capsule) validateVolumesUpdate(old *Capsule) field.ErrorList {
+	var errs field.ErrorList
+
+	volsPath := field.NewPath("spec").Child("volumes")
+	for i, v := range r.Spec.Volumes {
+		for _, ov := range old.Spec.Volumes {
+			if v.Name != ov.Name {
+				continue
+			}
+
+			vPath := volsPath.Index(i)
+			if !v.Size.Equal(ov.Size) {
+				errs = append(errs, field.Forbidden(vPath.Child("size"), "size of an existing volume cannot be changed"))
+			}
+			if v.StorageClassName != ov.StorageClassName {
+				errs = append(errs, field.Forbidden(vPath.Child("storageClassName"), "storage class of an existing volume cannot be changed"))
+			}
+		}
+	}
+
+	return errs
+}
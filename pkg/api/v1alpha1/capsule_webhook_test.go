@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
@@ -68,6 +70,26 @@ func TestValidateInterfaces(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "public: ingress pathPrefix must be absolute",
+			interfaces: []CapsuleInterface{
+				{
+					Public: &CapsulePublicInterface{
+						Ingress: &CapsuleInterfaceIngress{
+							Host:       "test.com",
+							PathPrefix: "api",
+						},
+					},
+				},
+			},
+			expectedErrs: field.ErrorList{
+				field.Invalid(
+					infsPath.Index(0).Child("public").Child("ingress").Child("pathPrefix"),
+					"api",
+					"pathPrefix must be an absolute path",
+				),
+			},
+		},
 	}
 
 	for i := range tests {
@@ -154,3 +176,266 @@ func TestValidateFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateProbes(t *testing.T) {
+	t.Parallel()
+	path := field.NewPath("spec").Child("probes")
+	tests := []struct {
+		name         string
+		probes       *CapsuleProbes
+		expectedErrs field.ErrorList
+	}{
+		{name: "no probes should cause no errors"},
+		{
+			name: "valid probes should cause no errors",
+			probes: &CapsuleProbes{
+				Liveness:  &CapsuleProbe{HTTP: &HTTPProbe{Port: 8080, Path: "/healthz"}},
+				Readiness: &CapsuleProbe{GRPC: &GRPCProbe{Port: 9090}},
+				Startup:   &CapsuleProbe{Exec: &ExecProbe{Command: []string{"true"}}},
+			},
+		},
+		{
+			name: "one of http, tcp, grpc or exec is required",
+			probes: &CapsuleProbes{
+				Readiness: &CapsuleProbe{},
+			},
+			expectedErrs: field.ErrorList{
+				field.Required(path.Child("readiness"), "one of http, tcp, grpc or exec is required"),
+			},
+		},
+		{
+			name: "http, tcp, grpc and exec are mutually exclusive",
+			probes: &CapsuleProbes{
+				Liveness: &CapsuleProbe{
+					HTTP: &HTTPProbe{Port: 8080},
+					TCP:  &TCPProbe{Port: 8080},
+				},
+			},
+			expectedErrs: field.ErrorList{
+				field.Invalid(path.Child("liveness"), &CapsuleProbe{
+					HTTP: &HTTPProbe{Port: 8080},
+					TCP:  &TCPProbe{Port: 8080},
+				}, "http, tcp, grpc and exec are mutually exclusive"),
+			},
+		},
+		{
+			name: "port must be positive and command is required",
+			probes: &CapsuleProbes{
+				Liveness: &CapsuleProbe{TCP: &TCPProbe{}},
+				Startup:  &CapsuleProbe{Exec: &ExecProbe{}},
+			},
+			expectedErrs: field.ErrorList{
+				field.Invalid(path.Child("liveness").Child("tcp").Child("port"), int32(0), "port must be positive"),
+				field.Required(path.Child("startup").Child("exec").Child("command"), "command is required"),
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Probes: test.probes,
+				},
+			}
+
+			_, err := c.validateProbes()
+			assert.Equal(t, test.expectedErrs, err)
+		})
+	}
+}
+
+func TestValidateAutoscaler(t *testing.T) {
+	t.Parallel()
+	path := field.NewPath("spec").Child("autoscaler")
+	tests := []struct {
+		name         string
+		autoscaler   *CapsuleAutoscaler
+		expectedErrs field.ErrorList
+	}{
+		{name: "no autoscaler should cause no errors"},
+		{
+			name:       "valid autoscaler should cause no errors",
+			autoscaler: &CapsuleAutoscaler{MinReplicas: 1, MaxReplicas: 3, CPUUtilization: 80},
+		},
+		{
+			name:       "maxReplicas cannot be less than minReplicas",
+			autoscaler: &CapsuleAutoscaler{MinReplicas: 3, MaxReplicas: 2, MemoryUtilization: 80},
+			expectedErrs: field.ErrorList{
+				field.Invalid(path.Child("maxReplicas"), int32(2), "maxReplicas cannot be less than minReplicas"),
+			},
+		},
+		{
+			name:       "one of cpuUtilization or memoryUtilization is required",
+			autoscaler: &CapsuleAutoscaler{MinReplicas: 0, MaxReplicas: 2},
+			expectedErrs: field.ErrorList{
+				field.Invalid(path.Child("minReplicas"), int32(0), "minReplicas must be at least 1"),
+				field.Required(path, "one of cpuUtilization or memoryUtilization is required"),
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Autoscaler: test.autoscaler,
+				},
+			}
+
+			_, err := c.validateAutoscaler()
+			assert.Equal(t, test.expectedErrs, err)
+		})
+	}
+}
+
+func TestValidateCronJob(t *testing.T) {
+	t.Parallel()
+	path := field.NewPath("spec").Child("cronJob")
+	tests := []struct {
+		name         string
+		cronJob      *CapsuleCronJob
+		expectedErrs field.ErrorList
+	}{
+		{name: "no cron job should cause no errors"},
+		{
+			name:    "valid cron job should cause no errors",
+			cronJob: &CapsuleCronJob{Schedule: "0 3 * * *", ConcurrencyPolicy: "Forbid", HistoryLimit: 3},
+		},
+		{
+			name:    "schedule is required",
+			cronJob: &CapsuleCronJob{},
+			expectedErrs: field.ErrorList{
+				field.Required(path.Child("schedule"), "schedule is required"),
+			},
+		},
+		{
+			name:    "concurrencyPolicy must be supported",
+			cronJob: &CapsuleCronJob{Schedule: "@hourly", ConcurrencyPolicy: "Never"},
+			expectedErrs: field.ErrorList{
+				field.NotSupported(path.Child("concurrencyPolicy"), batchv1.ConcurrencyPolicy("Never"), []string{"Allow", "Forbid", "Replace"}),
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					CronJob: test.cronJob,
+				},
+			}
+
+			_, err := c.validateCronJob()
+			assert.Equal(t, test.expectedErrs, err)
+		})
+	}
+}
+
+func TestValidateVolumes(t *testing.T) {
+	t.Parallel()
+	path := field.NewPath("spec").Child("volumes")
+	size := resource.MustParse("1Gi")
+	tests := []struct {
+		name         string
+		volumes      []CapsuleVolume
+		cronJob      *CapsuleCronJob
+		expectedErrs field.ErrorList
+	}{
+		{name: "no volumes should cause no errors"},
+		{
+			name: "valid volumes should cause no errors",
+			volumes: []CapsuleVolume{
+				{Name: "data", Path: "/var/lib/data", Size: size},
+				{Name: "cache", Path: "/cache", Size: size, StorageClassName: "fast"},
+			},
+		},
+		{
+			name: "names and paths must be unique",
+			volumes: []CapsuleVolume{
+				{Name: "data", Path: "/data", Size: size},
+				{Name: "data", Path: "/data", Size: size},
+			},
+			expectedErrs: field.ErrorList{
+				field.Duplicate(path.Index(1).Child("name"), "data"),
+				field.Duplicate(path.Index(1).Child("path"), "/data"),
+			},
+		},
+		{
+			name: "path must be absolute and size positive",
+			volumes: []CapsuleVolume{
+				{Name: "data", Path: "data"},
+			},
+			expectedErrs: field.ErrorList{
+				field.Invalid(path.Index(0).Child("path"), "data", "path must be an absolute path other than /"),
+				field.Invalid(path.Index(0).Child("size"), "0", "size must be positive"),
+			},
+		},
+		{
+			name: "volumes cannot be used with a cron job",
+			volumes: []CapsuleVolume{
+				{Name: "data", Path: "/data", Size: size},
+			},
+			cronJob: &CapsuleCronJob{Schedule: "@hourly"},
+			expectedErrs: field.ErrorList{
+				field.Forbidden(path, "volumes cannot be used with a cron job"),
+			},
+		},
+	}
+
+	for i := range tests {
+		test := tests[i]
+
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &Capsule{
+				Spec: CapsuleSpec{
+					Volumes: test.volumes,
+					CronJob: test.cronJob,
+				},
+			}
+
+			_, err := c.validateVolumes()
+			assert.Equal(t, test.expectedErrs, err)
+		})
+	}
+}
+
+func TestValidateVolumesUpdate(t *testing.T) {
+	t.Parallel()
+	path := field.NewPath("spec").Child("volumes").Index(0)
+
+	old := &Capsule{
+		Spec: CapsuleSpec{
+			Volumes: []CapsuleVolume{
+				{Name: "data", Path: "/data", Size: resource.MustParse("1Gi")},
+			},
+		},
+	}
+	c := &Capsule{
+		Spec: CapsuleSpec{
+			Volumes: []CapsuleVolume{
+				{Name: "data", Path: "/mnt/data", Size: resource.MustParse("2Gi"), StorageClassName: "fast"},
+			},
+		},
+	}
+
+	assert.Equal(t, field.ErrorList{
+		field.Forbidden(path.Child("size"), "size of an existing volume cannot be changed"),
+		field.Forbidden(path.Child("storageClassName"), "storage class of an existing volume cannot be changed"),
+	}, c.validateVolumesUpdate(old))
+	assert.Empty(t, c.validateVolumesUpdate(c))
+}
@@ -0,0 +1,39 @@
+package proxy
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AccessLogKind is the kind of the access log lines written by rig-proxy. It
+// tells the access log apart from the other output of rig-proxy.
+const AccessLogKind = "access"
+
+// AccessLog is a line of the access log of rig-proxy, written as JSON to
+// stdout.
+type AccessLog struct {
+	Kind        string    `json:"kind"`
+	Time        time.Time `json:"time"`
+	Method      string    `json:"method"`
+	Path        string    `json:"path"`
+	Status      int       `json:"status"`
+	LatencyMS   float64   `json:"latency_ms"`
+	BytesIn     int64     `json:"bytes_in"`
+	BytesOut    int64     `json:"bytes_out"`
+	UserID      string    `json:"user_id,omitempty"`
+	GRPCService string    `json:"grpc_service,omitempty"`
+	GRPCMethod  string    `json:"grpc_method,omitempty"`
+	GRPCStatus  string    `json:"grpc_status,omitempty"`
+}
+
+// IsAccessLog returns true if the line is an access log line.
+func IsAccessLog(line []byte) bool {
+	var l struct {
+		Kind string `json:"kind"`
+	}
+	if err := json.Unmarshal(line, &l); err != nil {
+		return false
+	}
+
+	return l.Kind == AccessLogKind
+}
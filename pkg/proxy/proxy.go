@@ -48,8 +48,13 @@ func New(target string, logger *zap.Logger) (*Proxy, error) {
 	}, nil
 }
 
+// IsGRPC returns true if the request is a gRPC request.
+func IsGRPC(req *http.Request) bool {
+	return req.ProtoMajor == 2 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc")
+}
+
 func (p *Proxy) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	if req.ProtoMajor == 2 && strings.HasPrefix(req.Header.Get("Content-Type"), "application/grpc") {
+	if IsGRPC(req) {
 		// It's a gRPC request, use the gRPC proxy.
 		p.logger.Info("proxying request", zap.String("host", req.Host), zap.Stringer("from", req.URL))
 		p.gp.ServeHTTP(res, req)
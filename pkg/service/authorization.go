@@ -42,6 +42,17 @@ var AllUsersAllow = map[string]struct{}{
 	"/api.v1.authentication.Service": {},
 }
 
+// RequirePermission are the paths only accessible with the given permission,
+// in addition to being authenticated.
+var RequirePermission = map[string]string{
+	"/api.v1.capsule.Service/Exec": service_auth.PermissionCapsuleExec,
+	// Tasks run any command with the environment of the capsule, as Exec does.
+	"/api.v1.capsule.Service/RunTask": service_auth.PermissionCapsuleExec,
+	// Port forwarding reaches ports of the instances which aren't exposed by
+	// an interface.
+	"/api.v1.capsule.Service/PortForward": service_auth.PermissionCapsulePortForward,
+}
+
 const (
 	RigProjectTokenHeader = "X-Rig-Project-Token"
 )
@@ -139,6 +150,18 @@ func (a *Authorization) handleAuth(ctx context.Context, path string, h http.Head
 		return nil, errors.UnauthenticatedErrorf("invalid auth token content")
 	}
 
+	if p, ok := RequirePermission[path]; ok {
+		ok, err := a.as.HasPermission(ctx, c, p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			logger.Debug("request is missing permission", zap.String("permission", p))
+			return nil, errors.PermissionDeniedErrorf("missing permission '%s'", p)
+		}
+	}
+
 	ctx = auth.WithClaims(ctx, c)
 
 	return ctx, nil
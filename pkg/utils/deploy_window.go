@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/settings"
+	"github.com/rigdev/rig/pkg/errors"
+)
+
+const deployWindowLayout = "15:04"
+
+// ValidateDeployWindow checks that the window has valid days and that it
+// starts before it ends.
+func ValidateDeployWindow(w *settings.DeployWindow) error {
+	for _, d := range w.GetDays() {
+		if d < settings.Weekday_WEEKDAY_MONDAY || d > settings.Weekday_WEEKDAY_SUNDAY {
+			return errors.InvalidArgumentErrorf("invalid weekday '%v'", d)
+		}
+	}
+
+	start, end, err := parseDeployWindow(w)
+	if err != nil {
+		return err
+	}
+
+	if start >= end {
+		return errors.InvalidArgumentErrorf("deploy window must start before it ends")
+	}
+
+	return nil
+}
+
+// NextDeployWindow returns the first time at or after t that is within one of
+// the windows. If there are no windows, t is returned.
+func NextDeployWindow(ws []*settings.DeployWindow, t time.Time) (time.Time, error) {
+	if len(ws) == 0 {
+		return t, nil
+	}
+
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	var next time.Time
+	for _, w := range ws {
+		start, end, err := parseDeployWindow(w)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		// A week ahead covers all windows.
+		for i := 0; i < 8; i++ {
+			day := midnight.AddDate(0, 0, i)
+			if !deployWindowOnDay(w, day.Weekday()) {
+				continue
+			}
+
+			opens, closes := day.Add(start), day.Add(end)
+			if !t.Before(closes) {
+				continue
+			}
+
+			c := opens
+			if t.After(opens) {
+				c = t
+			}
+			if next.IsZero() || c.Before(next) {
+				next = c
+			}
+			break
+		}
+	}
+
+	if next.IsZero() {
+		return time.Time{}, errors.InvalidArgumentErrorf("deploy windows never open")
+	}
+
+	return next, nil
+}
+
+func deployWindowOnDay(w *settings.DeployWindow, d time.Weekday) bool {
+	if len(w.GetDays()) == 0 {
+		return true
+	}
+
+	for _, wd := range w.GetDays() {
+		// time.Weekday starts from Sunday = 0.
+		if time.Weekday(wd%7) == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseDeployWindow(w *settings.DeployWindow) (time.Duration, time.Duration, error) {
+	start, err := time.Parse(deployWindowLayout, w.GetStart())
+	if err != nil {
+		return 0, 0, errors.InvalidArgumentErrorf("invalid deploy window start '%s'", w.GetStart())
+	}
+
+	end, err := time.Parse(deployWindowLayout, w.GetEnd())
+	if err != nil {
+		return 0, 0, errors.InvalidArgumentErrorf("invalid deploy window end '%s'", w.GetEnd())
+	}
+
+	zero, _ := time.Parse(deployWindowLayout, "00:00")
+	return start.Sub(zero), end.Sub(zero), nil
+}
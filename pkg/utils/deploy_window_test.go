@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rigdev/rig-go-api/api/v1/project/settings"
+	"github.com/rigdev/rig/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextDeployWindow(t *testing.T) {
+	weekdays := &settings.DeployWindow{
+		Days: []settings.Weekday{
+			settings.Weekday_WEEKDAY_MONDAY,
+			settings.Weekday_WEEKDAY_TUESDAY,
+			settings.Weekday_WEEKDAY_WEDNESDAY,
+			settings.Weekday_WEEKDAY_THURSDAY,
+			settings.Weekday_WEEKDAY_FRIDAY,
+		},
+		Start: "09:00",
+		End:   "16:00",
+	}
+	sunday := &settings.DeployWindow{
+		Days:  []settings.Weekday{settings.Weekday_WEEKDAY_SUNDAY},
+		Start: "12:00",
+		End:   "13:00",
+	}
+
+	// 2023-09-06 is a Wednesday.
+	wednesday := func(hour, min int) time.Time {
+		return time.Date(2023, 9, 6, hour, min, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		name     string
+		windows  []*settings.DeployWindow
+		t        time.Time
+		expected time.Time
+	}{
+		{
+			name:     "no windows",
+			t:        wednesday(20, 0),
+			expected: wednesday(20, 0),
+		},
+		{
+			name:     "within window",
+			windows:  []*settings.DeployWindow{weekdays},
+			t:        wednesday(10, 30),
+			expected: wednesday(10, 30),
+		},
+		{
+			name:     "before window",
+			windows:  []*settings.DeployWindow{weekdays},
+			t:        wednesday(7, 0),
+			expected: wednesday(9, 0),
+		},
+		{
+			name:     "after window",
+			windows:  []*settings.DeployWindow{weekdays},
+			t:        wednesday(16, 0),
+			expected: wednesday(9, 0).AddDate(0, 0, 1),
+		},
+		{
+			name:     "friday evening",
+			windows:  []*settings.DeployWindow{weekdays},
+			t:        wednesday(18, 0).AddDate(0, 0, 2),
+			expected: wednesday(9, 0).AddDate(0, 0, 5),
+		},
+		{
+			name:     "earliest of multiple windows",
+			windows:  []*settings.DeployWindow{weekdays, sunday},
+			t:        wednesday(18, 0).AddDate(0, 0, 2),
+			expected: wednesday(12, 0).AddDate(0, 0, 4),
+		},
+		{
+			name:     "same day next week",
+			windows:  []*settings.DeployWindow{sunday},
+			t:        wednesday(14, 0).AddDate(0, 0, 4),
+			expected: wednesday(12, 0).AddDate(0, 0, 11),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			next, err := NextDeployWindow(tc.windows, tc.t)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, next)
+		})
+	}
+}
+
+func TestValidateDeployWindow(t *testing.T) {
+	testCases := []struct {
+		name     string
+		window   *settings.DeployWindow
+		expected error
+	}{
+		{
+			name:   "valid",
+			window: &settings.DeployWindow{Start: "09:00", End: "16:00"},
+		},
+		{
+			name:     "invalid start",
+			window:   &settings.DeployWindow{Start: "9", End: "16:00"},
+			expected: errors.InvalidArgumentErrorf("invalid deploy window start '9'"),
+		},
+		{
+			name:     "ends before start",
+			window:   &settings.DeployWindow{Start: "16:00", End: "09:00"},
+			expected: errors.InvalidArgumentErrorf("deploy window must start before it ends"),
+		},
+		{
+			name: "invalid day",
+			window: &settings.DeployWindow{
+				Days:  []settings.Weekday{settings.Weekday_WEEKDAY_UNSPECIFIED},
+				Start: "09:00",
+				End:   "16:00",
+			},
+			expected: errors.InvalidArgumentErrorf("invalid weekday 'WEEKDAY_UNSPECIFIED'"),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateDeployWindow(tc.window)
+			if tc.expected == nil {
+				require.NoError(t, err)
+			} else {
+				require.EqualError(t, err, tc.expected.Error())
+			}
+		})
+	}
+}
@@ -14,10 +14,13 @@ import (
 	v1 "k8s.io/api/core/v1"
 	netv1 "k8s.io/api/networking/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
@@ -106,6 +109,18 @@ func tearDownTest(t *testing.T) {
 	}
 }
 
+// updateCapsule updates the capsule, retrying if the controller updated its
+// status in the meantime.
+func updateCapsule(ctx context.Context, t *testing.T, capsule *v1alpha1.Capsule, update func(*v1alpha1.Capsule)) {
+	assert.NoError(t, retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if err := k8sClient.Get(ctx, client.ObjectKeyFromObject(capsule), capsule); err != nil {
+			return err
+		}
+		update(capsule)
+		return k8sClient.Update(ctx, capsule)
+	}))
+}
+
 func TestIntegrationCapsuleReconcilerNginx(t *testing.T) {
 	if testing.Short() {
 		t.Skip()
@@ -129,7 +144,34 @@ func TestIntegrationCapsuleReconcilerNginx(t *testing.T) {
 			Namespace: nsName.Namespace,
 		},
 		Spec: v1alpha1.CapsuleSpec{
-			Image: "nginx:1.25.1",
+			Image:   "nginx:1.25.1",
+			Command: "nginx",
+			Args:    []string{"-g", "daemon off;"},
+			Resources: &v1.ResourceRequirements{
+				Requests: v1.ResourceList{
+					v1.ResourceCPU:    resource.MustParse("200m"),
+					v1.ResourceMemory: resource.MustParse("256Mi"),
+				},
+			},
+			ImagePullSecret: &v1.LocalObjectReference{
+				Name: "registry",
+			},
+			Files: []v1alpha1.File{
+				{
+					Path: "/etc/nginx/nginx.conf",
+					ConfigMap: &v1alpha1.FileContentRef{
+						Name: "nginx-conf",
+						Key:  "content",
+					},
+				},
+				{
+					Path: "/etc/ssl/key.pem",
+					Secret: &v1alpha1.FileContentRef{
+						Name: "nginx-key",
+						Key:  "content",
+					},
+				},
+			},
 		},
 	}
 
@@ -144,7 +186,42 @@ func TestIntegrationCapsuleReconcilerNginx(t *testing.T) {
 	}, waitFor, tick)
 
 	if assert.Len(t, deploy.Spec.Template.Spec.Containers, 1) {
-		assert.Equal(t, deploy.Spec.Template.Spec.Containers[0].Image, "nginx:1.25.1")
+		c := deploy.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, c.Image, "nginx:1.25.1")
+		assert.Equal(t, []string{"nginx"}, c.Command)
+		assert.Equal(t, capsule.Spec.Args, c.Args)
+		assert.Equal(t, resource.MustParse("200m"), c.Resources.Requests[v1.ResourceCPU])
+		assert.Equal(t, resource.MustParse("256Mi"), c.Resources.Requests[v1.ResourceMemory])
+		assert.Empty(t, c.Ports)
+		assert.Equal(t, []v1.VolumeMount{
+			{
+				Name:      "cfg-etc-nginx-nginx-conf",
+				MountPath: "/etc/nginx/nginx.conf",
+				SubPath:   "nginx.conf",
+				ReadOnly:  true,
+			},
+			{
+				Name:      "cfg-etc-ssl-key-pem",
+				MountPath: "/etc/ssl/key.pem",
+				SubPath:   "key.pem",
+				ReadOnly:  true,
+			},
+		}, c.VolumeMounts)
+	}
+	assert.Equal(t, []v1.LocalObjectReference{{Name: "registry"}}, deploy.Spec.Template.Spec.ImagePullSecrets)
+	if assert.Len(t, deploy.Spec.Template.Spec.Volumes, 2) {
+		cm := deploy.Spec.Template.Spec.Volumes[0]
+		assert.Equal(t, "cfg-etc-nginx-nginx-conf", cm.Name)
+		if assert.NotNil(t, cm.ConfigMap) {
+			assert.Equal(t, "nginx-conf", cm.ConfigMap.Name)
+			assert.Equal(t, []v1.KeyToPath{{Key: "content", Path: "nginx.conf"}}, cm.ConfigMap.Items)
+		}
+		secret := deploy.Spec.Template.Spec.Volumes[1]
+		assert.Equal(t, "cfg-etc-ssl-key-pem", secret.Name)
+		if assert.NotNil(t, secret.Secret) {
+			assert.Equal(t, "nginx-key", secret.Secret.SecretName)
+			assert.Equal(t, []v1.KeyToPath{{Key: "content", Path: "key.pem"}}, secret.Secret.Items)
+		}
 	}
 
 	capsuleOwnerRef := metav1.OwnerReference{
@@ -160,17 +237,36 @@ func TestIntegrationCapsuleReconcilerNginx(t *testing.T) {
 		assert.Equal(t, capsuleOwnerRef, deploy.OwnerReferences[0])
 	}
 
-	err := k8sClient.Get(ctx, nsName, &v1.Service{})
-	assert.True(t, kerrors.IsNotFound(err))
+	assert.Eventually(t, func() bool {
+		if err := k8sClient.Get(ctx, nsName, &capsule); err != nil {
+			return false
+		}
+		return capsule.Status.ObservedGeneration == capsule.Generation
+	}, waitFor, tick)
 
-	capsule.Spec.Interfaces = []v1alpha1.CapsuleInterface{
-		{
-			Name: "http",
-			Port: 80,
-		},
+	// There are no instances in the test environment, so the deployment never
+	// completes.
+	assert.Equal(t, capsule.Spec.Replicas, capsule.Status.Replicas)
+	assert.Equal(t, int32(0), capsule.Status.ReadyReplicas)
+	assert.Empty(t, capsule.Status.Image)
+	assert.True(t, meta.IsStatusConditionTrue(capsule.Status.Conditions, v1alpha1.CapsuleConditionProgressing))
+	assert.True(t, meta.IsStatusConditionFalse(capsule.Status.Conditions, v1alpha1.CapsuleConditionDegraded))
+	if c := meta.FindStatusCondition(capsule.Status.Conditions, v1alpha1.CapsuleConditionReady); assert.NotNil(t, c) {
+		assert.Equal(t, metav1.ConditionFalse, c.Status)
+		assert.Equal(t, capsule.Generation, c.ObservedGeneration)
 	}
 
-	assert.NoError(t, k8sClient.Update(ctx, &capsule))
+	err := k8sClient.Get(ctx, nsName, &v1.Service{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	updateCapsule(ctx, t, &capsule, func(c *v1alpha1.Capsule) {
+		c.Spec.Interfaces = []v1alpha1.CapsuleInterface{
+			{
+				Name: "http",
+				Port: 80,
+			},
+		}
+	})
 
 	var svc v1.Service
 	assert.Eventually(t, func() bool {
@@ -190,16 +286,65 @@ func TestIntegrationCapsuleReconcilerNginx(t *testing.T) {
 		assert.Equal(t, capsuleOwnerRef, svc.OwnerReferences[0])
 	}
 
-	err = k8sClient.Get(ctx, nsName, &netv1.Ingress{})
-	assert.True(t, kerrors.IsNotFound(err))
+	assert.Eventually(t, func() bool {
+		if err := k8sClient.Get(ctx, nsName, &deploy); err != nil {
+			return false
+		}
+		return len(deploy.Spec.Template.Spec.Containers) == 2
+	}, waitFor, tick)
 
-	capsule.Spec.Interfaces[0].Public = &v1alpha1.CapsulePublicInterface{
-		Ingress: &v1alpha1.CapsuleInterfaceIngress{
-			Host: "test.com",
-		},
+	if assert.Len(t, deploy.Spec.Template.Spec.Containers, 2) {
+		c := deploy.Spec.Template.Spec.Containers[0]
+		assert.Equal(t, []v1.ContainerPort{{
+			Name:          "port-80",
+			ContainerPort: 80,
+			Protocol:      v1.ProtocolTCP,
+		}}, c.Ports)
+
+		proxy := deploy.Spec.Template.Spec.Containers[1]
+		assert.Equal(t, "rig-proxy", proxy.Name)
+		assert.Equal(t, []string{"rig-proxy"}, proxy.Command)
+		if assert.Len(t, proxy.Ports, 1) {
+			assert.Equal(t, capsule.Spec.Interfaces[0].Name, proxy.Ports[0].Name)
+			assert.NotEqual(t, int32(80), proxy.Ports[0].ContainerPort)
+		}
+		if assert.Len(t, proxy.EnvFrom, 1) && assert.NotNil(t, proxy.EnvFrom[0].SecretRef) {
+			assert.Equal(t, "test-proxy", proxy.EnvFrom[0].SecretRef.Name)
+		}
+	}
+	assert.Contains(t, deploy.Spec.Template.Annotations, "rig.dev/proxy-config-sha")
+
+	var proxySecret v1.Secret
+	proxySecretName := types.NamespacedName{
+		Name:      "test-proxy",
+		Namespace: nsName.Namespace,
+	}
+	assert.Eventually(t, func() bool {
+		if err := k8sClient.Get(ctx, proxySecretName, &proxySecret); err != nil {
+			return false
+		}
+		return true
+	}, waitFor, tick)
+
+	assert.Contains(t, proxySecret.Data, "RIG_PROXY_CONFIG")
+	if assert.Len(t, proxySecret.OwnerReferences, 1) {
+		assert.Equal(t, capsuleOwnerRef, proxySecret.OwnerReferences[0])
 	}
 
-	assert.NoError(t, k8sClient.Update(ctx, &capsule))
+	err = k8sClient.Get(ctx, nsName, &netv1.Ingress{})
+	assert.True(t, kerrors.IsNotFound(err))
+
+	updateCapsule(ctx, t, &capsule, func(c *v1alpha1.Capsule) {
+		c.Spec.Interfaces[0].Public = &v1alpha1.CapsulePublicInterface{
+			Ingress: &v1alpha1.CapsuleInterfaceIngress{
+				Host:       "test.com",
+				PathPrefix: "/api",
+				TLS: &v1alpha1.CapsuleInterfaceIngressTLS{
+					SecretName: "test-cert",
+				},
+			},
+		}
+	})
 
 	var ing netv1.Ingress
 	assert.Eventually(t, func() bool {
@@ -216,19 +361,23 @@ func TestIntegrationCapsuleReconcilerNginx(t *testing.T) {
 			assert.Len(t, rule.IngressRuleValue.HTTP.Paths, 1) {
 			path := rule.IngressRuleValue.HTTP.Paths[0]
 			assert.Equal(t, ptr.New(netv1.PathTypePrefix), path.PathType)
-			assert.Equal(t, "/", path.Path)
+			assert.Equal(t, "/api", path.Path)
 			assert.Equal(t, capsule.Name, path.Backend.Service.Name)
 			assert.Equal(t, capsule.Spec.Interfaces[0].Name, path.Backend.Service.Port.Name)
 		}
 	}
-
-	capsule.Spec.Interfaces[0].Public = &v1alpha1.CapsulePublicInterface{
-		LoadBalancer: &v1alpha1.CapsuleInterfaceLoadBalancer{
-			Port: 1,
-		},
-	}
-
-	assert.NoError(t, k8sClient.Update(ctx, &capsule))
+	assert.Equal(t, []netv1.IngressTLS{{
+		Hosts:      []string{"test.com"},
+		SecretName: "test-cert",
+	}}, ing.Spec.TLS)
+
+	updateCapsule(ctx, t, &capsule, func(c *v1alpha1.Capsule) {
+		c.Spec.Interfaces[0].Public = &v1alpha1.CapsulePublicInterface{
+			LoadBalancer: &v1alpha1.CapsuleInterfaceLoadBalancer{
+				Port: 1,
+			},
+		}
+	})
 
 	assert.Eventually(t, func() bool {
 		if err := k8sClient.Get(ctx, nsName, &netv1.Ingress{}); err != nil {